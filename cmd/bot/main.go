@@ -1,39 +1,119 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"flag"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+	// TZ works without the time zone database of the image
+	_ "time/tzdata"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"golift.io/starr"
 	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
 
 	"github.com/woiza/telegram-bot-radarr/pkg/bot"
 	"github.com/woiza/telegram-bot-radarr/pkg/config"
+	"github.com/woiza/telegram-bot-radarr/pkg/logging"
+	"github.com/woiza/telegram-bot-radarr/pkg/metrics"
+	"github.com/woiza/telegram-bot-radarr/pkg/store"
 )
 
 func main() {
-	fmt.Println("Starting bot...")
+	healthcheck := flag.Bool("healthcheck", false, "check /healthz of the running bot and exit, for a Docker health check")
+	flag.Parse()
 
 	// get config from environment variables
-	config, err := config.LoadConfig()
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		// Handle error: configuration is incomplete or invalid
-		log.Fatal(err)
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
+	logging.Setup(cfg.LogLevel, cfg.LogFormat, cfg.Secrets()...)
+	if *healthcheck {
+		os.Exit(checkHealth(cfg.HTTPAddress))
+	}
+	slog.Info("Starting bot...")
 
-	b, err := tgbotapi.NewBotAPI(config.TelegramBotToken)
+	b, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
 	if err != nil {
-		log.Fatal("Error while starting bot: ", err)
+		slog.Error("Error while starting bot", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("Authorized on account", "username", b.Self.UserName)
+
+	var radarrServers []*bot.RadarrInstance
+	for _, radarrInstance := range cfg.Radarrs {
+		radarrConfig := starr.New(radarrInstance.APIKey, radarrInstance.URL(), 0)
+		bot.InstrumentClient(radarrConfig, radarrInstance.Name)
+		radarrServers = append(radarrServers, &bot.RadarrInstance{
+			Name:   radarrInstance.Name,
+			Server: radarr.New(radarrConfig),
+		})
+	}
+
+	var sonarrServer *sonarr.Sonarr
+	if cfg.Sonarr != nil {
+		sonarrConfig := starr.New(cfg.Sonarr.APIKey, cfg.Sonarr.URL(), 0)
+		bot.InstrumentClient(sonarrConfig, cfg.Sonarr.Name)
+		sonarrServer = sonarr.New(sonarrConfig)
 	}
 
-	fmt.Printf("Authorized on account %v\n", b.Self.UserName)
+	dataStore, err := store.Open(cfg.DataFile)
+	if err != nil {
+		slog.Error("Cannot open the data file", "error", err)
+		os.Exit(1)
+	}
 
-	radarrConfig := starr.New(config.RadarrAPIKey, fmt.Sprintf("%v://%v:%v%v", config.RadarrProtocol, config.RadarrHostname, config.RadarrPort, config.RadarrBaseUrl), 0)
-	radarrServer := radarr.New(radarrConfig)
+	botInstance := bot.New(&cfg, b, radarrServers, sonarrServer, dataStore)
 
-	botInstance := bot.New(&config, b, radarrServer)
+	// Serve the metrics, health checks and calendar feeds if the HTTP server is enabled
+	if cfg.HTTPAddress != "" {
+		botInstance.RegisterMetrics()
+		go botInstance.CollectMetrics(cfg.MetricsInterval)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle("/healthz", botInstance.HealthHandler(cfg.MaxPollAge))
+		mux.Handle("/readyz", botInstance.ReadyHandler())
+		mux.Handle(bot.CalendarPath, botInstance.CalendarHandler())
+		server := &http.Server{
+			Addr:              cfg.HTTPAddress,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+		}
+		go func() {
+			slog.Info("Starting HTTP server", "address", cfg.HTTPAddress)
+			if err := server.ListenAndServe(); err != nil {
+				slog.Error("HTTP server stopped", "error", err)
+			}
+		}()
+	}
+
+	// Check Radarr and Sonarr before handling any command
+	switch cfg.StartupCheck {
+	case config.StartupCheckFail:
+		if !botInstance.CheckConnections() {
+			slog.Error("Startup check failed, see the problems above")
+			os.Exit(1)
+		}
+	case config.StartupCheckRetry:
+		botInstance.WaitForConnections()
+	default:
+		if !botInstance.CheckConnections() {
+			slog.Warn("Startup check failed, starting anyway")
+		}
+	}
 
 	// Channel for receiving updates from the bot API
 	updates := make(chan tgbotapi.Update)
@@ -41,21 +121,22 @@ func main() {
 
 	// Start a goroutine to fetch updates from the bot API and send to the updates channel
 	go func() {
-		lastUpdateID := 0
+		updateConfig := tgbotapi.NewUpdate(0)
+		updateConfig.Timeout = 60
 		for {
-			updateConfig := tgbotapi.NewUpdate(lastUpdateID + 1)
-			updateConfig.Timeout = 60
-
-			updatesChannel := b.GetUpdatesChan(updateConfig)
+			received, err := b.GetUpdates(updateConfig)
 			if err != nil {
-				log.Println("Error getting updates channel:", err)
+				slog.Error("Error getting updates, retrying in 5 seconds", "error", err)
 				time.Sleep(5 * time.Second)
 				continue
 			}
+			botInstance.Polled()
 
-			for update := range updatesChannel {
-				updates <- update // Send updates to the updates channel
-				lastUpdateID = update.UpdateID
+			for _, update := range received {
+				if update.UpdateID >= updateConfig.Offset {
+					updateConfig.Offset = update.UpdateID + 1
+					updates <- update // Send updates to the updates channel
+				}
 			}
 		}
 	}()
@@ -63,6 +144,63 @@ func main() {
 	// Start a goroutine to handle updates concurrently
 	go botInstance.HandleUpdates(updates)
 
+	// Send the scheduled digests
+	go botInstance.RunDigests()
+
+	// Send the release reminders
+	go botInstance.RunReminders()
+
+	// Alert about low disk space if a threshold is set
+	go botInstance.RunDiskMonitor()
+
+	// Notify the admins about Radarr health issues
+	go botInstance.RunHealthMonitor()
+
+	// Reload the configuration on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			newConfig, err := config.LoadConfig()
+			if err != nil {
+				slog.Error("Configuration not reloaded", "error", err)
+				continue
+			}
+			botInstance.Reload(newConfig)
+		}
+	}()
+
 	// This can be a long-running process to handle incoming updates
 	select {}
 }
+
+// checkHealth requests /healthz of the bot running on the address and
+// returns the exit code, so the Docker image doesn't need curl.
+func checkHealth(address string) int {
+	if address == "" {
+		slog.Error("Health check failed, set RBOT_HTTP_ADDRESS to enable the HTTP server")
+		return 1
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		slog.Error("Invalid RBOT_HTTP_ADDRESS", "error", err)
+		return 1
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, port) + "/healthz")
+	if err != nil {
+		slog.Error("Health check failed", "error", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("Health check failed", "status", resp.StatusCode, "response", strings.TrimSpace(string(body)))
+		return 1
+	}
+	return 0
+}
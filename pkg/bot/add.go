@@ -27,15 +27,19 @@ const (
 	AddMovieUnMon            = "ADDMOVIE_UNMON"
 	AddMovieColSea           = "ADDMOVIE_COLSEA"
 	AddMovieColMon           = "ADDMOVIE_COLMON"
+	AddMovieViewCollection   = "ADDMOVIE_VIEW_COLLECTION"
+	AddMovieConfirmTargets   = "ADDMOVIE_CONFIRM_TARGETS"
 )
 
-func (b *Bot) processAddCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
+func (b *Bot) processAddCommand(update tgbotapi.Update, chatID int64, instances []*RadarrInstance) {
 	msg := tgbotapi.NewMessage(chatID, "Handling add movie command... please wait")
 	message, _ := b.sendMessage(msg)
 	command := userAddMovie{
+		instances: instances,
 		chatID:    message.Chat.ID,
 		messageID: message.MessageID,
 	}
+	r := instances[0].Server
 
 	criteria := update.Message.CommandArguments()
 	if len(criteria) < 1 {
@@ -44,8 +48,10 @@ func (b *Bot) processAddCommand(update tgbotapi.Update, chatID int64, r *radarr.
 	}
 	searchResults, err := r.Lookup(criteria)
 	if err != nil {
-		msg := tgbotapi.NewMessage(chatID, err.Error())
-		b.sendMessage(msg)
+		b.reportError(&command, err, func() bool {
+			b.processAddCommand(update, chatID, instances)
+			return false
+		})
 		return
 	}
 
@@ -72,7 +78,7 @@ func (b *Bot) processAddCommand(update tgbotapi.Update, chatID int64, r *radarr.
 func (b *Bot) addMovie(update tgbotapi.Update) bool {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot add movie: %v", err)
+		updateLogger(update).Warn("Cannot add movie", "error", err)
 		return false
 	}
 	command, exists := b.getAddMovieState(chatID)
@@ -103,7 +109,7 @@ func (b *Bot) addMovie(update tgbotapi.Update) bool {
 		return b.showAddMovieRootFolders(command)
 	case AddMovieAddOptionsGoBack:
 		// Check if there are no tags or tags should be ignored
-		if len(command.allTags) == 0 || b.Config.IgnoreTags {
+		if len(command.allTags) == 0 || b.config().IgnoreTags {
 			// Check if there is only one root folder and one profile
 			if len(command.allRootFolders) == 1 && len(command.allProfiles) == 1 {
 				return b.showAddMovieSearchResults(command)
@@ -137,6 +143,10 @@ func (b *Bot) addMovie(update tgbotapi.Update) bool {
 		return b.handleAddMovieColSea(update, command)
 	case AddMovieColMon:
 		return b.handleAddMovieColMon(update, command)
+	case AddMovieConfirmTargets:
+		return b.addMovieToLibrary(update, command)
+	case AddMovieViewCollection:
+		return b.showCollectionOfMovie(command.movie, AddMovieCommand, command.chatID, command.messageID)
 	default:
 		// Check if it starts with "PROFILE_"
 		if strings.HasPrefix(update.CallbackQuery.Data, "PROFILE_") {
@@ -159,6 +169,9 @@ func (b *Bot) addMovie(update tgbotapi.Update) bool {
 }
 
 func (b *Bot) showAddMovieSearchResults(command *userAddMovie) bool {
+	// start over with the first instance
+	command.targetIndex = 0
+	command.addMovieOptions = nil
 
 	// Extract movies from the map
 	movies := make([]*radarr.Movie, 0, len(command.searchResults))
@@ -213,14 +226,23 @@ func (b *Bot) showAddMovieSearchResults(command *userAddMovie) bool {
 func (b *Bot) addMovieDetails(update tgbotapi.Update, command *userAddMovie) bool {
 	movieIDStr := strings.TrimPrefix(update.CallbackQuery.Data, AddMovieTMDBID)
 	command.movie = command.searchResults[movieIDStr]
+	return b.showAddMovieDetails(command)
+}
 
+func (b *Bot) showAddMovieDetails(command *userAddMovie) bool {
 	var text strings.Builder
 	fmt.Fprintf(&text, "Is this the correct movie?\n\n")
 	fmt.Fprintf(&text, "[%v](https://www.imdb.com/title/%v) \\- _%v_\n\n", utils.Escape(command.movie.Title), command.movie.ImdbID, command.movie.Year)
 
+	buttonLabels := []string{"Yes, add this movie"}
+	buttonData := []string{AddMovieYes}
+	if command.movie.Collection != nil && command.movie.Collection.TmdbID != 0 {
+		buttonLabels = append(buttonLabels, "View collection")
+		buttonData = append(buttonData, AddMovieViewCollection)
+	}
 	keyboard := b.createKeyboard(
-		[]string{"Yes, add this movie", "\U0001F519"},
-		[]string{AddMovieYes, AddMovieGoBack},
+		append(buttonLabels, "\U0001F519"),
+		append(buttonData, AddMovieGoBack),
 	)
 
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
@@ -237,34 +259,65 @@ func (b *Bot) addMovieDetails(update tgbotapi.Update, command *userAddMovie) boo
 }
 
 func (b *Bot) handleAddMovieYes(update tgbotapi.Update, command *userAddMovie) bool {
+	// the search results are from the first instance, the others are checked separately
+	command.targets = nil
+	var existing []string
+	for i, instance := range command.instances {
+		inLibrary := command.movie.ID != 0
+		if i > 0 {
+			movies, err := instance.Server.GetMovie(command.movie.TmdbID)
+			if err != nil {
+				return b.reportError(command, err, func() bool { return b.handleAddMovieYes(update, command) })
+			}
+			inLibrary = len(movies) > 0
+		}
+		if inLibrary {
+			existing = append(existing, instance.Name)
+			continue
+		}
+		command.targets = append(command.targets, &addMovieTarget{instance: instance})
+	}
+
 	//movie already in library...
-	if command.movie.ID != 0 {
+	if len(command.targets) == 0 {
 		b.sendMessageWithEdit(command, "Movie already in library\nAll commands have been cleared")
+		b.clearState(update)
 		return false
 	}
+	if len(existing) > 0 && len(command.instances) > 1 {
+		msg := tgbotapi.NewMessage(command.chatID, fmt.Sprintf("Movie already in library of %s, skipping", strings.Join(existing, ", ")))
+		b.sendMessage(msg)
+	}
+
+	command.targetIndex = 0
+	return b.loadAddMovieTarget(update, command)
+}
+
+// loadAddMovieTarget fetches the quality profiles, root folders and tags of the
+// current target and starts choosing its settings.
+func (b *Bot) loadAddMovieTarget(update tgbotapi.Update, command *userAddMovie) bool {
+	r := command.targets[command.targetIndex].instance.Server
+	command.profileID = 0
+	command.rootFolder = nil
+	command.selectedTags = nil
 
-	profiles, err := b.RadarrServer.GetQualityProfiles()
+	profiles, err := r.GetQualityProfiles()
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.loadAddMovieTarget(update, command) })
 	}
 	if len(profiles) == 0 {
 		b.sendMessageWithEdit(command, "No quality profile(s) found on your radarr server.\nAll commands have been cleared.")
 		b.clearState(update)
+		return false
 	}
 	if len(profiles) == 1 {
 		command.profileID = profiles[0].ID
 	}
 	command.allProfiles = profiles
 
-	rootFolders, err := b.RadarrServer.GetRootFolders()
+	rootFolders, err := r.GetRootFolders()
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.loadAddMovieTarget(update, command) })
 	}
 	if len(rootFolders) == 1 {
 		command.rootFolder = rootFolders[0]
@@ -272,15 +325,13 @@ func (b *Bot) handleAddMovieYes(update tgbotapi.Update, command *userAddMovie) b
 	if len(rootFolders) == 0 {
 		b.sendMessageWithEdit(command, "No root folder(s) found on your radarr server.\nAll commands have been cleared.")
 		b.clearState(update)
+		return false
 	}
 	command.allRootFolders = rootFolders
 
-	tags, err := b.RadarrServer.GetTags()
+	tags, err := r.GetTags()
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.loadAddMovieTarget(update, command) })
 	}
 	command.allTags = tags
 
@@ -288,6 +339,14 @@ func (b *Bot) handleAddMovieYes(update tgbotapi.Update, command *userAddMovie) b
 	return b.showAddMovieProfiles(command)
 }
 
+// targetLabel names the instance whose settings are chosen, if there are several.
+func (command *userAddMovie) targetLabel() string {
+	if len(command.targets) < 2 {
+		return ""
+	}
+	return command.targets[command.targetIndex].instance.Name + ": "
+}
+
 func (b *Bot) showAddMovieProfiles(command *userAddMovie) bool {
 	// If there is only one profile, skip this step
 	if len(command.allProfiles) == 1 {
@@ -309,7 +368,7 @@ func (b *Bot) showAddMovieProfiles(command *userAddMovie) bool {
 	)
 	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, profileKeyboard...)
 	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
-	messageText.WriteString("Select quality profile:")
+	messageText.WriteString(command.targetLabel() + "Select quality profile:")
 	b.sendMessageWithEditAndKeyboard(
 		command,
 		keyboard,
@@ -324,7 +383,7 @@ func (b *Bot) handleAddMovieProfile(update tgbotapi.Update, command *userAddMovi
 	profileID, err := strconv.Atoi(profileIDStr)
 	if err != nil {
 		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
+		b.logger(command.chatID).Warn("Cannot convert quality profile ID to int", "error", err)
 		b.sendMessage(msg)
 		return false
 	}
@@ -354,7 +413,7 @@ func (b *Bot) showAddMovieRootFolders(command *userAddMovie) bool {
 	)
 	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, rootFolderKeyboard...)
 	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
-	messageText.WriteString("Select root folder:")
+	messageText.WriteString(command.targetLabel() + "Select root folder:")
 	b.sendMessageWithEditAndKeyboard(
 		command,
 		keyboard,
@@ -369,7 +428,7 @@ func (b *Bot) handleAddMovieRootFolder(update tgbotapi.Update, command *userAddM
 	id, err := strconv.Atoi(data)
 	if err != nil {
 		msg := tgbotapi.NewMessage(command.chatID, "Invalid root folder selection.")
-		fmt.Println(err)
+		b.logger(command.chatID).Warn("Cannot convert root folder ID to int", "error", err)
 		b.sendMessage(msg)
 		return false
 	}
@@ -383,7 +442,7 @@ func (b *Bot) handleAddMovieRootFolder(update tgbotapi.Update, command *userAddM
 
 	if command.rootFolder == nil {
 		msg := tgbotapi.NewMessage(command.chatID, "Root folder not found.")
-		fmt.Println(err)
+		b.logger(command.chatID).Warn("Root folder not found", "id", id)
 		b.sendMessage(msg)
 		return false
 	}
@@ -394,7 +453,7 @@ func (b *Bot) handleAddMovieRootFolder(update tgbotapi.Update, command *userAddM
 
 func (b *Bot) showAddMovieTags(command *userAddMovie) bool {
 	// If there are no tags or tags should be ignored, skip this step
-	if len(command.allTags) == 0 || b.Config.IgnoreTags {
+	if len(command.allTags) == 0 || b.config().IgnoreTags {
 		return b.showAddMovieAddOptions(command)
 	}
 	var tagsKeyboard [][]tgbotapi.InlineKeyboardButton
@@ -426,7 +485,7 @@ func (b *Bot) showAddMovieTags(command *userAddMovie) bool {
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
 		command.chatID,
 		command.messageID,
-		"Select tags:",
+		utils.Escape(command.targetLabel()+"Select tags:"),
 		keyboard,
 	)
 	editMsg.ParseMode = "MarkdownV2"
@@ -442,7 +501,7 @@ func (b *Bot) handleAddMovieEditSelectTag(update tgbotapi.Update, command *userA
 	// Parse the tag ID
 	tagID, err := strconv.Atoi(tagIDStr)
 	if err != nil {
-		fmt.Printf("Cannot convert tag string to int: %v", err)
+		b.logger(command.chatID).Warn("Cannot convert tag string to int", "error", err)
 		return false
 	}
 	// Check if the tag is already selected
@@ -460,6 +519,16 @@ func (b *Bot) handleAddMovieEditSelectTag(update tgbotapi.Update, command *userA
 }
 
 func (b *Bot) showAddMovieAddOptions(command *userAddMovie) bool {
+	// the options were chosen for the first instance already
+	if command.targetIndex > 0 {
+		keyboard := b.createKeyboard(
+			[]string{"Add to " + strings.Join(command.targetNames(), " and "), "Cancel, clear command", "\U0001F519"},
+			[]string{AddMovieConfirmTargets, AddMovieCancel, AddMovieAddOptionsGoBack},
+		)
+		b.setAddMovieState(command.chatID, command)
+		b.sendMessageWithEditAndKeyboard(command, keyboard, "The options chosen for "+command.targets[0].instance.Name+" are used for all instances.")
+		return false
+	}
 	keyboard := b.createKeyboard(
 		[]string{"Add movie monitored + search now", "Add movie monitored", "Add movie unmonitored", "Add collection monitored + search now", "Add collection monitored", "Cancel, clear command", "\U0001F519"},
 		[]string{AddMovieMonSea, AddMovieMon, AddMovieUnMon, AddMovieColSea, AddMovieColMon, AddMovieCancel, AddMovieAddOptionsGoBack},
@@ -527,44 +596,97 @@ func (b *Bot) handleAddMovieColMon(update tgbotapi.Update, command *userAddMovie
 	return b.addMovieToLibrary(update, command)
 }
 
+func (command *userAddMovie) targetNames() []string {
+	names := make([]string, 0, len(command.targets))
+	for _, target := range command.targets {
+		names = append(names, target.instance.Name)
+	}
+	return names
+}
+
 func (b *Bot) addMovieToLibrary(update tgbotapi.Update, command *userAddMovie) bool {
+	target := command.targets[command.targetIndex]
+	target.profileID = command.profileID
+	target.rootFolder = command.rootFolder
+	target.selectedTags = append([]int(nil), command.selectedTags...)
+
+	// choose the settings of the next instance first
+	if command.targetIndex+1 < len(command.targets) {
+		command.targetIndex++
+		return b.loadAddMovieTarget(update, command)
+	}
+
+	if len(command.targets) == 1 {
+		messageText, err := b.addMovieToInstance(command, target)
+		if err != nil {
+			return b.reportError(command, err, func() bool { return b.addMovieToLibrary(update, command) })
+		}
+		b.sendAddedMovie(command, target, messageText)
+		b.clearState(update)
+		return true
+	}
+
+	var messageText strings.Builder
+	for _, target := range command.targets {
+		text, err := b.addMovieToInstance(command, target)
+		if err != nil {
+			b.logError(command.chatID, err)
+			text = friendlyError(err) + "\n"
+		}
+		messageText.WriteString(target.instance.Name + ": " + text)
+	}
+	b.sendAddedMovie(command, command.targets[0], messageText.String())
+	b.clearState(update)
+	return true
+}
+
+// sendAddedMovie shows the result of adding the movie, with buttons to be
+// reminded of its upcoming releases.
+func (b *Bot) sendAddedMovie(command *userAddMovie, target *addMovieTarget, text string) {
+	rows := b.reminderButtons(command.chatID, target.instance, command.movie)
+	if len(rows) == 0 {
+		b.sendMessageWithEdit(command, text)
+		return
+	}
+	b.sendMessageWithEditAndKeyboard(command, tgbotapi.NewInlineKeyboardMarkup(rows...), text)
+}
+
+func (b *Bot) addMovieToInstance(command *userAddMovie, target *addMovieTarget) (string, error) {
 	var tagIDs []int
-	tagIDs = append(tagIDs, command.selectedTags...)
+	tagIDs = append(tagIDs, target.selectedTags...)
 
 	// does anyone ever user anything other than announced?
 	addMovieInput := radarr.AddMovieInput{
 		MinimumAvailability: "announced",
 		TmdbID:              command.movie.TmdbID,
 		Title:               command.movie.Title,
-		QualityProfileID:    command.profileID,
-		RootFolderPath:      command.rootFolder.Path,
+		QualityProfileID:    target.profileID,
+		RootFolderPath:      target.rootFolder.Path,
 		AddOptions:          command.addMovieOptions,
 		Tags:                tagIDs,
 		Monitored:           command.monitored,
 	}
 
-	var messageText string
-	var _, err = b.RadarrServer.AddMovie(&addMovieInput)
-	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
+	// adding again would fail with "already exists"
+	if !target.added {
+		var _, err = target.instance.Server.AddMovie(&addMovieInput)
+		if err != nil {
+			return "", err
+		}
+		target.added = true
+		b.recordRequest(command.chatID, command.movie.TmdbID)
 	}
-	movies, err := b.RadarrServer.GetMovie((command.movie.TmdbID))
+	movies, err := target.instance.Server.GetMovie((command.movie.TmdbID))
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
+		return "", err
+	}
+	title := command.movie.Title
+	if len(movies) > 0 {
+		title = movies[0].Title
 	}
 
 	if command.addMovieOptions.Monitor == "movieAndCollection" {
-		messageText = fmt.Sprintf("Collection '%v' added\n", movies[0].Title)
-	} else {
-		messageText = fmt.Sprintf("Movie '%v' added\n", movies[0].Title)
+		return fmt.Sprintf("Collection '%v' added\n", title), nil
 	}
-	b.sendMessageWithEdit(command, messageText)
-	b.clearState(update)
-	return true
+	return fmt.Sprintf("Movie '%v' added\n", title), nil
 }
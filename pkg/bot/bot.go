@@ -3,15 +3,21 @@ package bot
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"golift.io/starr"
 	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
 
 	"github.com/woiza/telegram-bot-radarr/pkg/config"
+	"github.com/woiza/telegram-bot-radarr/pkg/logging"
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/store"
 )
 
 const (
@@ -19,11 +25,33 @@ const (
 	DeleteMovieCommand      = "DELETEMOVIE"
 	LibraryMenuCommand      = "LIBRARYMENU"
 	LibraryFilteredCommand  = "LIBRARYFILTERED"
+	LibraryFiltersCommand   = "LIBRARYFILTERS"
 	LibraryMovieEditCommand = "LIBRARYMOVIEEDIT"
+	LibraryBulkEditCommand  = "LIBRARYBULKEDIT"
+	BulkAddCommand          = "BULKADD"
+	CollectionCommand       = "COLLECTION"
+	InstanceCommand         = "INSTANCE"
+	AddSeriesCommand        = "ADDSERIES"
+	SeriesLibraryCommand    = "SERIESLIBRARY"
+	DigestCommand           = "DIGEST"
+	CalendarCommand         = "CALENDAR"
 	CommandsClearedMessage  = "I am not sure what you mean.\nAll commands have been cleared"
+	SonarrNotConfigured     = "Sonarr is not configured, set the RBOT_SONARR_* variables to manage TV shows"
 )
 
+// addMovieTarget is a Radarr instance a movie is added to, with the settings chosen for it.
+type addMovieTarget struct {
+	instance     *RadarrInstance
+	profileID    int64
+	rootFolder   *radarr.RootFolder
+	selectedTags []int
+	added        bool // a retry only looks the movie up again
+}
+
 type userAddMovie struct {
+	instances       []*RadarrInstance // instances picked for the command
+	targets         []*addMovieTarget // instances the movie isn't in yet
+	targetIndex     int               // target whose settings are currently chosen
 	searchResults   map[string]*radarr.Movie
 	movie           *radarr.Movie
 	allProfiles     []*radarr.QualityProfile
@@ -38,10 +66,50 @@ type userAddMovie struct {
 	messageID       int
 }
 
+type bulkAddEntry struct {
+	query      string          // line as entered by the user
+	candidates []*radarr.Movie // lookup results, more than one if the match is ambiguous
+	movie      *radarr.Movie   // chosen candidate
+	ambiguous  bool
+	selected   bool
+	err        error
+}
+
+type userBulkAdd struct {
+	entries         []*bulkAddEntry
+	pickEntry       int // index of the entry whose candidates are shown in the picker
+	allProfiles     []*radarr.QualityProfile
+	profileID       int64
+	allRootFolders  []*radarr.RootFolder
+	rootFolder      *radarr.RootFolder
+	allTags         []*starr.Tag
+	selectedTags    []int
+	monitored       bool
+	addMovieOptions *radarr.AddMovieOptions
+	chatID          int64
+	messageID       int
+	page            int
+}
+
+type userCollection struct {
+	collections     []*radarrapi.Collection
+	collection      *radarrapi.Collection
+	library         map[int64]*radarr.Movie // keyed by TMDB ID
+	qualityProfiles []*radarr.QualityProfile
+	rootFolders     []*radarr.RootFolder
+	selectedMovies  []int64 // TMDB IDs of collection movies to add
+	returnCommand   string  // set if the collection was opened from another command
+	status          string
+	chatID          int64
+	messageID       int
+	page            int
+}
+
 type userDeleteMovie struct {
 	library            map[string]*radarr.Movie
 	moviesForSelection []*radarr.Movie // Movies to select from, either whole library or search results
 	selectedMovies     []*radarr.Movie
+	searchCriteria     string
 	chatID             int64
 	messageID          int
 	page               int
@@ -51,7 +119,9 @@ type userLibrary struct {
 	library                []*radarr.Movie
 	libraryFiltered        map[string]*radarr.Movie
 	searchResultsInLibrary []*radarr.Movie
+	searchCriteria         string
 	filter                 string
+	advancedFilter         libraryFilter
 	qualityProfiles        []*radarr.QualityProfile
 	selectedQualityProfile int64
 	allTags                []*starr.Tag
@@ -59,24 +129,99 @@ type userLibrary struct {
 	selectedMonitoring     bool
 	movie                  *radarr.Movie
 	lastSearch             time.Time
+	bulkSelectMode         bool
+	bulkSelectedMovies     []*radarr.Movie
+	bulkSelectedTags       []int
+	rootFolders            []*radarr.RootFolder
+	upcoming               *upcomingQuery
 	chatID                 int64
 	messageID              int
 	page                   int
 }
 
+type userAddSeries struct {
+	searchResults     map[string]*sonarr.Series // keyed by TVDB ID
+	series            *sonarr.Series
+	allProfiles       []*sonarr.QualityProfile
+	profileID         int64
+	languageProfileID int64
+	allRootFolders    []*sonarr.RootFolder
+	rootFolder        *sonarr.RootFolder
+	allTags           []*starr.Tag
+	selectedTags      []int
+	seriesType        string
+	monitor           string
+	chatID            int64
+	messageID         int
+}
+
+type userSeriesLibrary struct {
+	library            []*sonarr.Series
+	seriesForSelection []*sonarr.Series // whole library or search results
+	series             *sonarr.Series
+	qualityProfiles    []*sonarr.QualityProfile
+	searchCriteria     string
+	lastSearch         time.Time
+	chatID             int64
+	messageID          int
+	page               int
+}
+
 type Bot struct {
-	Config            *config.Config
 	Bot               *tgbotapi.BotAPI
-	RadarrServer      *radarr.Radarr
+	RadarrServers     []*RadarrInstance
+	SonarrServer      *sonarr.Sonarr // nil if Sonarr isn't configured
+	ActiveRadarr      map[int64]*RadarrInstance
+	PendingCommands   map[int64]tgbotapi.Update
 	ActiveCommand     map[int64]string
 	AddMovieStates    map[int64]*userAddMovie
 	DeleteMovieStates map[int64]*userDeleteMovie
 	LibraryStates     map[int64]*userLibrary
+	BulkAddStates     map[int64]*userBulkAdd
+	CollectionStates  map[int64]*userCollection
+	PendingDeletions  map[string]*pendingDeletion
+	AddSeriesStates   map[int64]*userAddSeries
+	SeriesStates      map[int64]*userSeriesLibrary
+	ErrorRetries      map[int64]*errorRetry
+	Loggers           map[int64]*slog.Logger // logger of the update handled last per chat
+	DigestStates      map[int64]*userDigest
+	Digests           map[int64]*digestSettings // digest subscriptions, saved in the store
+	digestsSending    map[int64]bool            // chats whose digest is being sent, guarded by muDigests
+	UpcomingQueries   map[int64]*upcomingQuery  // query of the last /up, its buttons outlive the command
+	CalendarStates    map[int64]*userCalendar
+	CalendarTokens    map[int64]string             // calendar feed tokens, saved in the store
+	Requests          map[int64][]int64            // TMDB IDs of the movies added per chat, saved in the store
+	Reminders         map[string]*reminder         // release reminders by chat, movie and release type, saved in the store
+	DiskAlerts        map[string]bool              // root folders with an active low disk space alert, saved in the store
+	HealthIssues      map[string]*radarrapi.Health // reported Radarr health issues, saved in the store
+	Store             *store.Store
+	reloads           chan config.Config
+	cfg               atomic.Pointer[config.Config] // replaced on reloads, read with config()
+	lastPoll          atomic.Int64                  // Unix time of the last successful Telegram poll
+	waiting           atomic.Bool                   // the startup check waits for Radarr and Sonarr, Telegram isn't polled yet
 	// Mutexes for synchronization
 	muActiveCommand     sync.Mutex
 	muAddMovieStates    sync.Mutex
 	muDeleteMovieStates sync.Mutex
 	muLibraryStates     sync.Mutex
+	muBulkAddStates     sync.Mutex
+	muCollectionStates  sync.Mutex
+	muPendingDeletions  sync.Mutex
+	muActiveRadarr      sync.Mutex
+	muPendingCommands   sync.Mutex
+	muAddSeriesStates   sync.Mutex
+	muSeriesStates      sync.Mutex
+	muErrorRetries      sync.Mutex
+	muLoggers           sync.Mutex
+	muDigestStates      sync.Mutex
+	muDigests           sync.Mutex
+	muUpcomingQueries   sync.Mutex
+	muCalendarStates    sync.Mutex
+	muCalendarTokens    sync.Mutex
+	muRequests          sync.Mutex
+	muReminders         sync.Mutex
+	muDiskAlerts        sync.Mutex
+	muHealthIssues      sync.Mutex
 }
 
 type Command interface {
@@ -111,38 +256,195 @@ func (c *userAddMovie) GetMessageID() int {
 	return c.messageID
 }
 
-func New(config *config.Config, botAPI *tgbotapi.BotAPI, radarrServer *radarr.Radarr) *Bot {
-	return &Bot{
-		Config:            config,
+// Implement the interface for userBulkAdd
+func (c *userBulkAdd) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userBulkAdd) GetMessageID() int {
+	return c.messageID
+}
+
+// Implement the interface for userCollection
+func (c *userCollection) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userCollection) GetMessageID() int {
+	return c.messageID
+}
+
+// Implement the interface for userAddSeries
+func (c *userAddSeries) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userAddSeries) GetMessageID() int {
+	return c.messageID
+}
+
+// Implement the interface for userSeriesLibrary
+func (c *userSeriesLibrary) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userSeriesLibrary) GetMessageID() int {
+	return c.messageID
+}
+
+func New(cfg *config.Config, botAPI *tgbotapi.BotAPI, radarrServers []*RadarrInstance, sonarrServer *sonarr.Sonarr, dataStore *store.Store) *Bot {
+	b := &Bot{
 		Bot:               botAPI,
-		RadarrServer:      radarrServer,
+		RadarrServers:     radarrServers,
+		SonarrServer:      sonarrServer,
+		ActiveRadarr:      make(map[int64]*RadarrInstance),
+		PendingCommands:   make(map[int64]tgbotapi.Update),
 		ActiveCommand:     make(map[int64]string),
 		AddMovieStates:    make(map[int64]*userAddMovie),
 		DeleteMovieStates: make(map[int64]*userDeleteMovie),
 		LibraryStates:     make(map[int64]*userLibrary),
+		BulkAddStates:     make(map[int64]*userBulkAdd),
+		CollectionStates:  make(map[int64]*userCollection),
+		PendingDeletions:  make(map[string]*pendingDeletion),
+		AddSeriesStates:   make(map[int64]*userAddSeries),
+		SeriesStates:      make(map[int64]*userSeriesLibrary),
+		ErrorRetries:      make(map[int64]*errorRetry),
+		Loggers:           make(map[int64]*slog.Logger),
+		DigestStates:      make(map[int64]*userDigest),
+		digestsSending:    make(map[int64]bool),
+		UpcomingQueries:   make(map[int64]*upcomingQuery),
+		CalendarStates:    make(map[int64]*userCalendar),
+		Store:             dataStore,
+		reloads:           make(chan config.Config),
 	}
+	b.cfg.Store(cfg)
+	// the bot counts as healthy until the first poll is overdue
+	b.Polled()
+	b.loadDigests()
+	b.loadCalendars()
+	b.loadReminders()
+	b.loadDiskAlerts()
+	b.loadHealthIssues()
+	return b
 }
 
 func (b *Bot) HandleUpdates(updates <-chan tgbotapi.Update) {
-	for update := range updates {
-		b.HandleUpdate(update)
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			b.HandleUpdate(update)
+		case newConfig := <-b.reloads:
+			b.applyConfig(newConfig)
+		}
+	}
+}
+
+// Reload applies the settings which don't need a new connection, like the
+// allowed users and max items. It's applied between two updates, so no
+// command sees a half changed configuration, and swapped atomically for the
+// background loops and the HTTP handlers.
+func (b *Bot) Reload(newConfig config.Config) {
+	b.reloads <- newConfig
+}
+
+// config returns the current configuration. A reload replaces it as a
+// whole, callers which read several settings should keep the returned one.
+func (b *Bot) config() *config.Config {
+	return b.cfg.Load()
+}
+
+// isAllowed reports whether the chat may use the bot. Work on behalf of a
+// chat checks it again before it runs, a reload may have removed the chat.
+func (b *Bot) isAllowed(chatID int64) bool {
+	return b.config().AllowedChatIDs[chatID]
+}
+
+func (b *Bot) applyConfig(newConfig config.Config) {
+	current := b.config()
+	if newConfig.RestartRequired(*current) {
+		slog.Warn("Connection settings or log format changed, restart the bot to apply them")
 	}
+	updated := *current
+	updated.AllowedChatIDs = newConfig.AllowedChatIDs
+	updated.AdminChatIDs = newConfig.AdminChatIDs
+	updated.MaxItems = newConfig.MaxItems
+	updated.IgnoreTags = newConfig.IgnoreTags
+	updated.DeleteGracePeriod = newConfig.DeleteGracePeriod
+	updated.LogLevel = newConfig.LogLevel
+	updated.PublicURL = newConfig.PublicURL
+	updated.DiskMinFree = newConfig.DiskMinFree
+	updated.DiskMinFreePct = newConfig.DiskMinFreePct
+	updated.DiskAlertChatIDs = newConfig.DiskAlertChatIDs
+	updated.DiskInterval = newConfig.DiskInterval
+	updated.HealthInterval = newConfig.HealthInterval
+	b.cfg.Store(&updated)
+	logging.SetLevel(updated.LogLevel)
+	slog.Info("Configuration reloaded")
 }
 
 func (b *Bot) HandleUpdate(update tgbotapi.Update) {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot handle update: %v", err)
+		updateLogger(update).Warn("Cannot handle update", "error", err)
 		return
 	}
 
-	if update.Message != nil && !b.Config.AllowedChatIDs[chatID] {
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Access denied. You are not authorized.")
+	// buttons are checked too, a reload may have removed the chat after they were sent
+	if !b.isAllowed(chatID) {
+		updateLogger(update).Warn("Access denied")
+		msg := tgbotapi.NewMessage(chatID, "Access denied. You are not authorized.")
 		b.sendMessage(msg)
 		return
 	}
 
 	activeCommand, _ := b.getActiveCommand(chatID)
+	logger := updateLogger(update).With("command", activeCommand)
+	b.setLogger(chatID, logger)
+	logger.Debug("Handling update")
+
+	if update.CallbackQuery != nil {
+		updatesTotal.Inc("callback")
+		callbacksTotal.Inc(callbackAction(update.CallbackQuery.Data))
+	} else {
+		updatesTotal.Inc("message")
+	}
+
+	// undo buttons of queued deletions outlive the command that created them
+	if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, DeleteMovieUndo) {
+		b.handleDeleteMovieUndo(update)
+		return
+	}
+
+	// movie buttons of /up open the library, whatever the active command
+	if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, UpcomingMovie) {
+		b.handleUpcomingMovie(update)
+		return
+	}
+
+	// reminder buttons work the same after adding a movie, in the library and in /reminders
+	if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, ReminderToggle) {
+		b.handleReminderToggle(update)
+		return
+	}
+	if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, ReminderCancel) {
+		b.handleReminderCancel(update)
+		return
+	}
+
+	// the Raw button of /system stays usable after other commands
+	if update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, SystemRaw) {
+		b.handleSystemRaw(update)
+		return
+	}
+
+	// Retry and Cancel buttons of errors work the same in all commands
+	if update.CallbackQuery != nil && (update.CallbackQuery.Data == ErrorRetry || update.CallbackQuery.Data == ErrorCancel) {
+		b.handleErrorCallback(update)
+		return
+	}
 
 	if update.CallbackQuery != nil {
 		switch activeCommand {
@@ -162,10 +464,46 @@ func (b *Bot) HandleUpdate(update tgbotapi.Update) {
 			if !b.libraryFiltered(update) {
 				return
 			}
+		case LibraryFiltersCommand:
+			if !b.libraryFilters(update) {
+				return
+			}
 		case LibraryMovieEditCommand:
 			if !b.libraryMovieEdit(update) {
 				return
 			}
+		case LibraryBulkEditCommand:
+			if !b.libraryBulkEdit(update) {
+				return
+			}
+		case BulkAddCommand:
+			if !b.bulkAdd(update) {
+				return
+			}
+		case CollectionCommand:
+			if !b.collections(update) {
+				return
+			}
+		case InstanceCommand:
+			if !b.selectInstance(update) {
+				return
+			}
+		case AddSeriesCommand:
+			if !b.addSeries(update) {
+				return
+			}
+		case SeriesLibraryCommand:
+			if !b.seriesLibrary(update) {
+				return
+			}
+		case DigestCommand:
+			if !b.digest(update) {
+				return
+			}
+		case CalendarCommand:
+			if !b.calendar(update) {
+				return
+			}
 		default:
 			b.clearState(update)
 			msg := tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, CommandsClearedMessage)
@@ -183,17 +521,25 @@ func (b *Bot) HandleUpdate(update tgbotapi.Update) {
 			Type:   "bot_command",
 			Length: 2, // length of the command `/q`
 		}}
+		// plain text searches the instance the chat used last, only commands ask
+		b.handleCommand(update, []*RadarrInstance{b.getRadarrInstance(chatID)})
+		return
 	}
 
 	if update.Message.IsCommand() {
-		b.handleCommand(update, b.RadarrServer)
+		// let the user pick the Radarr instance first if there are several
+		if b.needsInstancePick(update) {
+			b.showInstancePicker(update)
+			return
+		}
+		b.handleCommand(update, b.RadarrServers[:1])
 	}
 }
 
 func (b *Bot) clearState(update tgbotapi.Update) {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot clear state: %v", err)
+		updateLogger(update).Warn("Cannot clear state", "error", err)
 		return
 	}
 
@@ -217,6 +563,46 @@ func (b *Bot) clearState(update tgbotapi.Update) {
 	defer b.muLibraryStates.Unlock()
 
 	delete(b.LibraryStates, chatID)
+
+	b.muBulkAddStates.Lock()
+	defer b.muBulkAddStates.Unlock()
+
+	delete(b.BulkAddStates, chatID)
+
+	b.muCollectionStates.Lock()
+	defer b.muCollectionStates.Unlock()
+
+	delete(b.CollectionStates, chatID)
+
+	b.muPendingCommands.Lock()
+	defer b.muPendingCommands.Unlock()
+
+	delete(b.PendingCommands, chatID)
+
+	b.muAddSeriesStates.Lock()
+	defer b.muAddSeriesStates.Unlock()
+
+	delete(b.AddSeriesStates, chatID)
+
+	b.muSeriesStates.Lock()
+	defer b.muSeriesStates.Unlock()
+
+	delete(b.SeriesStates, chatID)
+
+	b.muErrorRetries.Lock()
+	defer b.muErrorRetries.Unlock()
+
+	delete(b.ErrorRetries, chatID)
+
+	b.muDigestStates.Lock()
+	defer b.muDigestStates.Unlock()
+
+	delete(b.DigestStates, chatID)
+
+	b.muCalendarStates.Lock()
+	defer b.muCalendarStates.Unlock()
+
+	delete(b.CalendarStates, chatID)
 }
 
 func (b *Bot) getChatID(update tgbotapi.Update) (int64, error) {
@@ -233,6 +619,52 @@ func (b *Bot) getChatID(update tgbotapi.Update) (int64, error) {
 	return chatID, nil
 }
 
+// updateLogger returns a logger with the chat, user, update and callback
+// data of the update.
+func updateLogger(update tgbotapi.Update) *slog.Logger {
+	logger := slog.With("update_id", update.UpdateID)
+	switch {
+	case update.Message != nil:
+		logger = logger.With("chat_id", update.Message.Chat.ID)
+		if update.Message.From != nil {
+			logger = logger.With("user_id", update.Message.From.ID)
+		}
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			logger = logger.With("chat_id", update.CallbackQuery.Message.Chat.ID)
+		}
+		if update.CallbackQuery.From != nil {
+			logger = logger.With("user_id", update.CallbackQuery.From.ID)
+		}
+		logger = logger.With("callback_data", update.CallbackQuery.Data)
+	}
+	return logger
+}
+
+// logger returns the logger of the update handled last in the chat, so log
+// lines of the handlers can be correlated with the update.
+func (b *Bot) logger(chatID int64) *slog.Logger {
+	b.muLoggers.Lock()
+	defer b.muLoggers.Unlock()
+	if logger, exists := b.Loggers[chatID]; exists {
+		return logger
+	}
+	return chatLogger(chatID)
+}
+
+// chatLogger returns a logger for work which isn't part of handling an
+// update, like timers, background loops and HTTP requests. The logger of
+// the last update of the chat would attach unrelated update details.
+func chatLogger(chatID int64) *slog.Logger {
+	return slog.With("chat_id", chatID)
+}
+
+func (b *Bot) setLogger(chatID int64, logger *slog.Logger) {
+	b.muLoggers.Lock()
+	defer b.muLoggers.Unlock()
+	b.Loggers[chatID] = logger
+}
+
 func (b *Bot) getActiveCommand(chatID int64) (string, bool) {
 	b.muActiveCommand.Lock()
 	defer b.muActiveCommand.Unlock()
@@ -285,10 +717,89 @@ func (b *Bot) setLibraryState(chatID int64, state *userLibrary) {
 	b.LibraryStates[chatID] = state
 }
 
+func (b *Bot) getBulkAddState(chatID int64) (*userBulkAdd, bool) {
+	b.muBulkAddStates.Lock()
+	defer b.muBulkAddStates.Unlock()
+	state, exists := b.BulkAddStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setBulkAddState(chatID int64, state *userBulkAdd) {
+	b.muBulkAddStates.Lock()
+	defer b.muBulkAddStates.Unlock()
+	b.BulkAddStates[chatID] = state
+}
+
+func (b *Bot) getCollectionState(chatID int64) (*userCollection, bool) {
+	b.muCollectionStates.Lock()
+	defer b.muCollectionStates.Unlock()
+	state, exists := b.CollectionStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setCollectionState(chatID int64, state *userCollection) {
+	b.muCollectionStates.Lock()
+	defer b.muCollectionStates.Unlock()
+	b.CollectionStates[chatID] = state
+}
+
+func (b *Bot) getAddSeriesState(chatID int64) (*userAddSeries, bool) {
+	b.muAddSeriesStates.Lock()
+	defer b.muAddSeriesStates.Unlock()
+	state, exists := b.AddSeriesStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setAddSeriesState(chatID int64, state *userAddSeries) {
+	b.muAddSeriesStates.Lock()
+	defer b.muAddSeriesStates.Unlock()
+	b.AddSeriesStates[chatID] = state
+}
+
+func (b *Bot) getSeriesState(chatID int64) (*userSeriesLibrary, bool) {
+	b.muSeriesStates.Lock()
+	defer b.muSeriesStates.Unlock()
+	state, exists := b.SeriesStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setSeriesState(chatID int64, state *userSeriesLibrary) {
+	b.muSeriesStates.Lock()
+	defer b.muSeriesStates.Unlock()
+	b.SeriesStates[chatID] = state
+}
+
+func (b *Bot) getDigestState(chatID int64) (*userDigest, bool) {
+	b.muDigestStates.Lock()
+	defer b.muDigestStates.Unlock()
+	state, exists := b.DigestStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setDigestState(chatID int64, state *userDigest) {
+	b.muDigestStates.Lock()
+	defer b.muDigestStates.Unlock()
+	b.DigestStates[chatID] = state
+}
+
+func (b *Bot) getCalendarState(chatID int64) (*userCalendar, bool) {
+	b.muCalendarStates.Lock()
+	defer b.muCalendarStates.Unlock()
+	state, exists := b.CalendarStates[chatID]
+	return state, exists
+}
+
+func (b *Bot) setCalendarState(chatID int64, state *userCalendar) {
+	b.muCalendarStates.Lock()
+	defer b.muCalendarStates.Unlock()
+	b.CalendarStates[chatID] = state
+}
+
 func (b *Bot) sendMessage(msg tgbotapi.Chattable) (tgbotapi.Message, error) {
 	message, err := b.Bot.Send(msg)
 	if err != nil {
-		log.Println("Error sending message:", err)
+		sendErrorsTotal.Inc()
+		slog.Error("Cannot send message", "error", err)
 	}
 	return message, err
 }
@@ -301,7 +812,7 @@ func (b *Bot) sendMessageWithEdit(command Command, text string) {
 	)
 	_, err := b.sendMessage(editMsg)
 	if err != nil {
-		log.Printf("Error editing message: %v", err)
+		b.logger(command.GetChatID()).Error("Cannot edit message", "error", err)
 	}
 }
 
@@ -314,7 +825,7 @@ func (b *Bot) sendMessageWithEditAndKeyboard(command Command, keyboard tgbotapi.
 	)
 	_, err := b.sendMessage(editMsg)
 	if err != nil {
-		log.Printf("Error editing message with keyboard: %v", err)
+		b.logger(command.GetChatID()).Error("Cannot edit message with keyboard", "error", err)
 	}
 }
 
@@ -0,0 +1,660 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const (
+	BulkAddToggle           = "BULKADD_TOGGLE_"
+	BulkAddPick             = "BULKADD_PICK_"
+	BulkAddPickCandidate    = "BULKADD_CANDIDATE_"
+	BulkAddPickGoBack       = "BULKADD_PICK_GOBACK"
+	BulkAddNoop             = "BULKADD_NOOP"
+	BulkAddContinue         = "BULKADD_CONTINUE"
+	BulkAddCancel           = "BULKADD_CANCEL"
+	BulkAddProfile          = "BULKADD_PROFILE_"
+	BulkAddProfileGoBack    = "BULKADD_PROFILE_GOBACK"
+	BulkAddRootFolder       = "BULKADD_ROOTFOLDER_"
+	BulkAddRootFolderGoBack = "BULKADD_ROOTFOLDER_GOBACK"
+	BulkAddTag              = "BULKADD_TAG_"
+	BulkAddTagsDone         = "BULKADD_TAGS_DONE"
+	BulkAddTagsGoBack       = "BULKADD_TAGS_GOBACK"
+	BulkAddOptionsGoBack    = "BULKADD_OPTIONS_GOBACK"
+	BulkAddMonSea           = "BULKADD_MONSEA"
+	BulkAddMon              = "BULKADD_MON"
+	BulkAddUnMon            = "BULKADD_UNMON"
+	BulkAddFirstPage        = "BULKADD_FIRST_PAGE"
+	BulkAddPreviousPage     = "BULKADD_PREV_PAGE"
+	BulkAddNextPage         = "BULKADD_NEXT_PAGE"
+	BulkAddLastPage         = "BULKADD_LAST_PAGE"
+)
+
+const (
+	bulkAddMaxEntries    = 50
+	bulkAddMaxCandidates = 5
+)
+
+var (
+	bulkAddIMDBPattern  = regexp.MustCompile(`^(?i)(?:imdb:)?(tt\d+)$`)
+	bulkAddTMDBPattern  = regexp.MustCompile(`^(?i)tmdb:(\d+)$`)
+	bulkAddTitlePattern = regexp.MustCompile(`^(.+?)[\s(\[]+((?:18|19|20)\d{2})[)\]]?$`)
+)
+
+func (b *Bot) processBulkAddCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
+	msg := tgbotapi.NewMessage(chatID, "Handling bulk add command... please wait")
+	message, _ := b.sendMessage(msg)
+	command := userBulkAdd{
+		chatID:    message.Chat.ID,
+		messageID: message.MessageID,
+	}
+
+	var lines []string
+	for _, line := range strings.Split(update.Message.CommandArguments(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		b.sendMessageWithEdit(&command, "Please provide one movie per line:\n/bulkadd\nThe Matrix 1999\ntt0133093\ntmdb:603")
+		return
+	}
+	if len(lines) > bulkAddMaxEntries {
+		b.sendMessageWithEdit(&command, fmt.Sprintf("Too many movies, please send at most %d lines at once", bulkAddMaxEntries))
+		return
+	}
+
+	b.sendMessageWithEdit(&command, fmt.Sprintf("Looking up %d movie(s)... please wait", len(lines)))
+	seen := make(map[int64]bool)
+	for _, line := range lines {
+		entry := resolveBulkAddEntry(r, line)
+		// a movie listed twice, e.g. by title and by ID, is only selected once
+		if entry.movie != nil && seen[entry.movie.TmdbID] {
+			entry.selected = false
+		} else if entry.movie != nil {
+			seen[entry.movie.TmdbID] = true
+		}
+		command.entries = append(command.entries, entry)
+	}
+
+	b.setBulkAddState(command.chatID, &command)
+	b.setActiveCommand(command.chatID, BulkAddCommand)
+	b.showBulkAddReview(&command)
+}
+
+// resolveBulkAddEntry looks up a single line of the bulk add message. Lines
+// can be IMDb IDs, TMDB IDs with the tmdb: prefix or titles with an optional
+// year. Bare numbers are titles, like "1917" or "300".
+func resolveBulkAddEntry(r *radarr.Radarr, line string) *bulkAddEntry {
+	entry := &bulkAddEntry{query: line}
+
+	var term string
+	var year int
+	var title string
+	switch {
+	case bulkAddIMDBPattern.MatchString(line):
+		term = "imdb:" + bulkAddIMDBPattern.FindStringSubmatch(line)[1]
+	case bulkAddTMDBPattern.MatchString(line):
+		term = "tmdb:" + bulkAddTMDBPattern.FindStringSubmatch(line)[1]
+	case bulkAddTitlePattern.MatchString(line):
+		matches := bulkAddTitlePattern.FindStringSubmatch(line)
+		title = matches[1]
+		year, _ = strconv.Atoi(matches[2])
+		term = title
+	default:
+		title = line
+		term = line
+	}
+
+	results, err := r.Lookup(term)
+	if err != nil {
+		entry.err = err
+		return entry
+	}
+
+	if year != 0 {
+		byYear := filterMovies(results, func(movie *radarr.Movie) bool {
+			return movie.Year == year
+		})
+		// the year might be part of the title, e.g. "Blade Runner 2049"
+		if len(byYear) == 0 {
+			title = line
+			results, err = r.Lookup(line)
+			if err != nil {
+				entry.err = err
+				return entry
+			}
+		} else {
+			results = byYear
+		}
+	}
+
+	if len(results) == 0 {
+		return entry
+	}
+
+	// prefer exact title matches if there are several results
+	if len(results) > 1 && title != "" {
+		exact := filterMovies(results, func(movie *radarr.Movie) bool {
+			return strings.EqualFold(utils.IgnoreArticles(movie.Title), utils.IgnoreArticles(title))
+		})
+		if len(exact) > 0 {
+			results = exact
+		}
+	}
+
+	if len(results) > bulkAddMaxCandidates {
+		results = results[:bulkAddMaxCandidates]
+	}
+
+	entry.candidates = results
+	entry.movie = results[0]
+	entry.ambiguous = len(results) > 1
+	// ambiguous matches and movies already in the library are not selected by default
+	entry.selected = !entry.ambiguous && entry.movie.ID == 0
+	return entry
+}
+
+func (b *Bot) bulkAdd(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot bulk add movies", "error", err)
+		return false
+	}
+	command, exists := b.getBulkAddState(chatID)
+	if !exists {
+		return false
+	}
+
+	data := update.CallbackQuery.Data
+	switch data {
+	// ignore click on page number
+	case "current_page", BulkAddNoop:
+		return false
+	case BulkAddFirstPage:
+		command.page = 0
+		return b.showBulkAddReview(command)
+	case BulkAddPreviousPage:
+		if command.page > 0 {
+			command.page--
+		}
+		return b.showBulkAddReview(command)
+	case BulkAddNextPage:
+		command.page++
+		return b.showBulkAddReview(command)
+	case BulkAddLastPage:
+		totalPages := (len(command.entries) + b.config().MaxItems - 1) / b.config().MaxItems
+		command.page = totalPages - 1
+		return b.showBulkAddReview(command)
+	case BulkAddPickGoBack:
+		return b.showBulkAddReview(command)
+	case BulkAddContinue:
+		return b.handleBulkAddContinue(update, command)
+	case BulkAddProfileGoBack:
+		return b.showBulkAddReview(command)
+	case BulkAddRootFolderGoBack:
+		if len(command.allProfiles) == 1 {
+			return b.showBulkAddReview(command)
+		}
+		return b.showBulkAddProfiles(command)
+	case BulkAddTagsGoBack:
+		if len(command.allRootFolders) == 1 && len(command.allProfiles) == 1 {
+			return b.showBulkAddReview(command)
+		}
+		if len(command.allRootFolders) == 1 {
+			return b.showBulkAddProfiles(command)
+		}
+		return b.showBulkAddRootFolders(command)
+	case BulkAddOptionsGoBack:
+		if len(command.allTags) == 0 || b.config().IgnoreTags {
+			if len(command.allRootFolders) == 1 && len(command.allProfiles) == 1 {
+				return b.showBulkAddReview(command)
+			}
+			if len(command.allRootFolders) == 1 {
+				return b.showBulkAddProfiles(command)
+			}
+			return b.showBulkAddRootFolders(command)
+		}
+		return b.showBulkAddTags(command)
+	case BulkAddTagsDone:
+		return b.showBulkAddOptions(command)
+	case BulkAddMonSea:
+		command.monitored = *starr.True()
+		command.addMovieOptions = &radarr.AddMovieOptions{
+			SearchForMovie: *starr.True(),
+			Monitor:        "movieOnly",
+		}
+		return b.addBulkMoviesToLibrary(update, command)
+	case BulkAddMon:
+		command.monitored = *starr.True()
+		command.addMovieOptions = &radarr.AddMovieOptions{
+			SearchForMovie: *starr.False(),
+			Monitor:        "movieOnly",
+		}
+		return b.addBulkMoviesToLibrary(update, command)
+	case BulkAddUnMon:
+		command.monitored = *starr.False()
+		command.addMovieOptions = &radarr.AddMovieOptions{
+			SearchForMovie: *starr.False(),
+			Monitor:        "none",
+		}
+		return b.addBulkMoviesToLibrary(update, command)
+	case BulkAddCancel:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(data, BulkAddToggle):
+		return b.handleBulkAddToggle(data, command)
+	case strings.HasPrefix(data, BulkAddPickCandidate):
+		return b.handleBulkAddPickCandidate(data, command)
+	case strings.HasPrefix(data, BulkAddPick):
+		return b.handleBulkAddPick(data, command)
+	case strings.HasPrefix(data, BulkAddProfile):
+		return b.handleBulkAddProfile(data, command)
+	case strings.HasPrefix(data, BulkAddRootFolder):
+		return b.handleBulkAddRootFolder(data, command)
+	case strings.HasPrefix(data, BulkAddTag):
+		return b.handleBulkAddSelectTag(data, command)
+	}
+	return b.showBulkAddReview(command)
+}
+
+func (b *Bot) showBulkAddReview(command *userBulkAdd) bool {
+	entries := command.entries
+
+	// Pagination parameters
+	page := command.page
+	pageSize := b.config().MaxItems
+	totalPages := (len(entries) + pageSize - 1) / pageSize
+
+	// Calculate start and end index for the current page
+	startIndex := page * pageSize
+	endIndex := (page + 1) * pageSize
+	if endIndex > len(entries) {
+		endIndex = len(entries)
+	}
+
+	var found, ambiguous, notFound, inLibrary, selected int
+	for _, entry := range entries {
+		switch {
+		case entry.movie == nil:
+			notFound++
+		case entry.movie.ID != 0:
+			inLibrary++
+		case entry.ambiguous:
+			ambiguous++
+		default:
+			found++
+		}
+		if entry.selected {
+			selected++
+		}
+	}
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for i := startIndex; i < endIndex; i++ {
+		entry := entries[i]
+		index := strconv.Itoa(i)
+		var row []tgbotapi.InlineKeyboardButton
+		switch {
+		case entry.movie == nil:
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("❌ "+entry.query+" - not found", BulkAddNoop))
+		case entry.movie.ID != 0:
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("\U0001F4DA %v - %v - in library", entry.movie.Title, entry.movie.Year), BulkAddNoop))
+		default:
+			checkbox := "⬜"
+			if entry.selected {
+				checkbox = "✅"
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %v - %v", checkbox, entry.movie.Title, entry.movie.Year), BulkAddToggle+index))
+		}
+		if len(entry.candidates) > 1 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("❓ Pick", BulkAddPick+index))
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+	}
+
+	if len(entries) > pageSize {
+		paginationButtons := b.createPaginationButtons(page, totalPages, BulkAddFirstPage, BulkAddPreviousPage, BulkAddNextPage, BulkAddLastPage)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, paginationButtons)
+	}
+
+	var keyboardContinueCancel tgbotapi.InlineKeyboardMarkup
+	if selected > 0 {
+		keyboardContinueCancel = b.createKeyboard(
+			[]string{fmt.Sprintf("Continue - add %d movie(s)", selected), "Cancel - clear command"},
+			[]string{BulkAddContinue, BulkAddCancel},
+		)
+	} else {
+		keyboardContinueCancel = b.createKeyboard(
+			[]string{"Cancel - clear command"},
+			[]string{BulkAddCancel},
+		)
+	}
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardContinueCancel.InlineKeyboard...)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Found: %d\n", found)
+	if ambiguous > 0 {
+		fmt.Fprintf(&text, "Ambiguous: %d (❓ pick the right movie)\n", ambiguous)
+	}
+	if inLibrary > 0 {
+		fmt.Fprintf(&text, "Already in library: %d\n", inLibrary)
+	}
+	if notFound > 0 {
+		fmt.Fprintf(&text, "Not found: %d\n", notFound)
+	}
+	for _, entry := range entries {
+		if entry.err != nil {
+			fmt.Fprintf(&text, "Lookup of '%s' failed: %v\n", entry.query, entry.err)
+		}
+	}
+	fmt.Fprintf(&text, "\nSelect the movie(s) you want to add - page %d/%d", page+1, totalPages)
+
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text.String())
+	return false
+}
+
+func (b *Bot) handleBulkAddToggle(data string, command *userBulkAdd) bool {
+	index, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddToggle))
+	if err != nil || index < 0 || index >= len(command.entries) {
+		b.logger(command.chatID).Warn("Invalid bulk add selection", "data", data)
+		return false
+	}
+	entry := command.entries[index]
+	if entry.movie != nil && entry.movie.ID == 0 {
+		entry.selected = !entry.selected
+	}
+	return b.showBulkAddReview(command)
+}
+
+func (b *Bot) handleBulkAddPick(data string, command *userBulkAdd) bool {
+	index, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddPick))
+	if err != nil || index < 0 || index >= len(command.entries) {
+		b.logger(command.chatID).Warn("Invalid bulk add selection", "data", data)
+		return false
+	}
+	command.pickEntry = index
+	entry := command.entries[index]
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for i, movie := range entry.candidates {
+		buttonText := fmt.Sprintf("%v - %v", movie.Title, movie.Year)
+		if movie.ID != 0 {
+			buttonText += " - in library"
+		}
+		if movie == entry.movie {
+			buttonText += " ✅"
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, BulkAddPickCandidate+strconv.Itoa(i)),
+		))
+	}
+	keyboardGoBack := b.createKeyboard(
+		[]string{"\U0001F519"},
+		[]string{BulkAddPickGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Which movie did you mean by *%s*?\n\n", utils.Escape(entry.query))
+	for _, movie := range entry.candidates {
+		fmt.Fprintf(&text, "[%v](https://www.imdb.com/title/%v) \\- _%v_\n", utils.Escape(movie.Title), movie.ImdbID, movie.Year)
+	}
+
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
+		command.chatID,
+		command.messageID,
+		text.String(),
+		keyboard,
+	)
+	editMsg.ParseMode = "MarkdownV2"
+	editMsg.DisableWebPagePreview = true
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessage(editMsg)
+	return false
+}
+
+func (b *Bot) handleBulkAddPickCandidate(data string, command *userBulkAdd) bool {
+	candidate, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddPickCandidate))
+	if err != nil || command.pickEntry >= len(command.entries) {
+		b.logger(command.chatID).Warn("Invalid bulk add candidate", "data", data)
+		return false
+	}
+	entry := command.entries[command.pickEntry]
+	if candidate < 0 || candidate >= len(entry.candidates) {
+		b.logger(command.chatID).Warn("Invalid bulk add candidate", "data", data)
+		return false
+	}
+	entry.movie = entry.candidates[candidate]
+	entry.ambiguous = false
+	entry.selected = entry.movie.ID == 0
+	return b.showBulkAddReview(command)
+}
+
+func (b *Bot) handleBulkAddContinue(update tgbotapi.Update, command *userBulkAdd) bool {
+	profiles, err := b.getRadarrServer(command.chatID).GetQualityProfiles()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleBulkAddContinue(update, command) })
+	}
+	if len(profiles) == 0 {
+		b.sendMessageWithEdit(command, "No quality profile(s) found on your radarr server.\nAll commands have been cleared.")
+		b.clearState(update)
+		return false
+	}
+	if len(profiles) == 1 {
+		command.profileID = profiles[0].ID
+	}
+	command.allProfiles = profiles
+
+	rootFolders, err := b.getRadarrServer(command.chatID).GetRootFolders()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleBulkAddContinue(update, command) })
+	}
+	if len(rootFolders) == 0 {
+		b.sendMessageWithEdit(command, "No root folder(s) found on your radarr server.\nAll commands have been cleared.")
+		b.clearState(update)
+		return false
+	}
+	if len(rootFolders) == 1 {
+		command.rootFolder = rootFolders[0]
+	}
+	command.allRootFolders = rootFolders
+
+	tags, err := b.getRadarrServer(command.chatID).GetTags()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleBulkAddContinue(update, command) })
+	}
+	command.allTags = tags
+
+	b.setBulkAddState(command.chatID, command)
+	return b.showBulkAddProfiles(command)
+}
+
+func (b *Bot) showBulkAddProfiles(command *userBulkAdd) bool {
+	// If there is only one profile, skip this step
+	if len(command.allProfiles) == 1 {
+		return b.showBulkAddRootFolders(command)
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, profile := range command.allProfiles {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(profile.Name, BulkAddProfile+strconv.Itoa(int(profile.ID))),
+		))
+	}
+	keyboardGoBack := b.createKeyboard(
+		[]string{"\U0001F519"},
+		[]string{BulkAddProfileGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select quality profile for all movies:")
+	return false
+}
+
+func (b *Bot) handleBulkAddProfile(data string, command *userBulkAdd) bool {
+	profileID, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddProfile))
+	if err != nil {
+		msg := tgbotapi.NewMessage(command.chatID, err.Error())
+		b.logger(command.chatID).Warn("Cannot convert quality profile ID to int", "error", err)
+		b.sendMessage(msg)
+		return false
+	}
+	command.profileID = int64(profileID)
+	b.setBulkAddState(command.chatID, command)
+	return b.showBulkAddRootFolders(command)
+}
+
+func (b *Bot) showBulkAddRootFolders(command *userBulkAdd) bool {
+	// If there is only one root folder, skip this step
+	if len(command.allRootFolders) == 1 {
+		return b.showBulkAddTags(command)
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, rootFolder := range command.allRootFolders {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(rootFolder.Path, BulkAddRootFolder+strconv.Itoa(int(rootFolder.ID))),
+		))
+	}
+	keyboardGoBack := b.createKeyboard(
+		[]string{"\U0001F519"},
+		[]string{BulkAddRootFolderGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select root folder for all movies:")
+	return false
+}
+
+func (b *Bot) handleBulkAddRootFolder(data string, command *userBulkAdd) bool {
+	id, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddRootFolder))
+	if err != nil {
+		msg := tgbotapi.NewMessage(command.chatID, "Invalid root folder selection.")
+		b.logger(command.chatID).Warn("Cannot convert root folder ID to int", "error", err)
+		b.sendMessage(msg)
+		return false
+	}
+
+	command.rootFolder = nil
+	for _, rootFolder := range command.allRootFolders {
+		if rootFolder.ID == int64(id) {
+			command.rootFolder = rootFolder
+			break
+		}
+	}
+	if command.rootFolder == nil {
+		msg := tgbotapi.NewMessage(command.chatID, "Root folder not found.")
+		b.sendMessage(msg)
+		return false
+	}
+
+	b.setBulkAddState(command.chatID, command)
+	return b.showBulkAddTags(command)
+}
+
+func (b *Bot) showBulkAddTags(command *userBulkAdd) bool {
+	// If there are no tags or tags should be ignored, skip this step
+	if len(command.allTags) == 0 || b.config().IgnoreTags {
+		return b.showBulkAddOptions(command)
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, tag := range command.allTags {
+		buttonText := tag.Label
+		if isSelectedTag(command.selectedTags, tag.ID) {
+			buttonText += " ✅"
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, BulkAddTag+strconv.Itoa(tag.ID)),
+		))
+	}
+	keyboardDoneGoBack := b.createKeyboard(
+		[]string{"Done - Continue", "\U0001F519"},
+		[]string{BulkAddTagsDone, BulkAddTagsGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardDoneGoBack.InlineKeyboard...)
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select tags for all movies:")
+	return false
+}
+
+func (b *Bot) handleBulkAddSelectTag(data string, command *userBulkAdd) bool {
+	tagID, err := strconv.Atoi(strings.TrimPrefix(data, BulkAddTag))
+	if err != nil {
+		b.logger(command.chatID).Warn("Cannot convert tag string to int", "error", err)
+		return false
+	}
+	if isSelectedTag(command.selectedTags, tagID) {
+		command.selectedTags = removeTag(command.selectedTags, tagID)
+	} else {
+		command.selectedTags = append(command.selectedTags, tagID)
+	}
+	b.setBulkAddState(command.chatID, command)
+	return b.showBulkAddTags(command)
+}
+
+func (b *Bot) showBulkAddOptions(command *userBulkAdd) bool {
+	keyboard := b.createKeyboard(
+		[]string{"Add movies monitored + search now", "Add movies monitored", "Add movies unmonitored", "Cancel, clear command", "\U0001F519"},
+		[]string{BulkAddMonSea, BulkAddMon, BulkAddUnMon, BulkAddCancel, BulkAddOptionsGoBack},
+	)
+	b.setBulkAddState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "How would you like to add the movies?")
+	return false
+}
+
+func (b *Bot) addBulkMoviesToLibrary(update tgbotapi.Update, command *userBulkAdd) bool {
+	b.sendMessageWithEdit(command, "Adding movies... please wait")
+
+	var added, failed []string
+	adding := make(map[int64]bool)
+	for _, entry := range command.entries {
+		// the same movie may have been selected on two lines
+		if !entry.selected || entry.movie == nil || adding[entry.movie.TmdbID] {
+			continue
+		}
+		adding[entry.movie.TmdbID] = true
+		addMovieInput := radarr.AddMovieInput{
+			MinimumAvailability: "announced",
+			TmdbID:              entry.movie.TmdbID,
+			Title:               entry.movie.Title,
+			QualityProfileID:    command.profileID,
+			RootFolderPath:      command.rootFolder.Path,
+			AddOptions:          command.addMovieOptions,
+			Tags:                command.selectedTags,
+			Monitored:           command.monitored,
+		}
+		label := fmt.Sprintf("%v (%v)", entry.movie.Title, entry.movie.Year)
+		if _, err := b.getRadarrServer(command.chatID).AddMovie(&addMovieInput); err != nil {
+			b.logError(command.chatID, err)
+			failed = append(failed, fmt.Sprintf("❌ %s: %s", label, friendlyError(err)))
+			continue
+		}
+		b.recordRequest(command.chatID, entry.movie.TmdbID)
+		added = append(added, "✅ "+label)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Added %d of %d movie(s)\n\n", len(added), len(added)+len(failed))
+	for _, line := range added {
+		fmt.Fprintln(&text, line)
+	}
+	for _, line := range failed {
+		fmt.Fprintln(&text, line)
+	}
+
+	b.sendMessageWithEdit(command, text.String())
+	b.clearState(update)
+	return true
+}
@@ -0,0 +1,325 @@
+package bot
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/ics"
+)
+
+const (
+	CalendarNewToken   = "CALENDAR_NEW_TOKEN"
+	CalendarDisable    = "CALENDAR_DISABLE"
+	CalendarDone       = "CALENDAR_DONE"
+	CalendarPath       = "/calendar/"
+	calendarStoreKey   = "calendars"
+	requestsStoreKey   = "requests"
+	calendarPastDays   = 30
+	calendarFutureDays = 365
+)
+
+type userCalendar struct {
+	chatID    int64
+	messageID int
+}
+
+func (c *userCalendar) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userCalendar) GetMessageID() int {
+	return c.messageID
+}
+
+func (b *Bot) loadCalendars() {
+	if err := b.Store.Load(calendarStoreKey, &b.CalendarTokens); err != nil {
+		slog.Error("Cannot load calendar feeds", "error", err)
+	}
+	if b.CalendarTokens == nil {
+		b.CalendarTokens = make(map[int64]string)
+	}
+	if err := b.Store.Load(requestsStoreKey, &b.Requests); err != nil {
+		slog.Error("Cannot load requested movies", "error", err)
+	}
+	if b.Requests == nil {
+		b.Requests = make(map[int64][]int64)
+	}
+}
+
+// recordRequest remembers that the chat added the movie, for the "only my
+// requests" calendar feed.
+func (b *Bot) recordRequest(chatID int64, tmdbID int64) {
+	b.muRequests.Lock()
+	defer b.muRequests.Unlock()
+	for _, requested := range b.Requests[chatID] {
+		if requested == tmdbID {
+			return
+		}
+	}
+	b.Requests[chatID] = append(b.Requests[chatID], tmdbID)
+	if err := b.Store.Save(requestsStoreKey, b.Requests); err != nil {
+		b.logger(chatID).Error("Cannot save requested movies", "error", err)
+	}
+}
+
+func (b *Bot) requestedMovies(chatID int64) map[int64]bool {
+	b.muRequests.Lock()
+	defer b.muRequests.Unlock()
+	requested := make(map[int64]bool, len(b.Requests[chatID]))
+	for _, tmdbID := range b.Requests[chatID] {
+		requested[tmdbID] = true
+	}
+	return requested
+}
+
+// calendarToken returns the feed token of the chat, empty if the feed is
+// disabled.
+func (b *Bot) calendarToken(chatID int64) string {
+	b.muCalendarTokens.Lock()
+	defer b.muCalendarTokens.Unlock()
+	return b.CalendarTokens[chatID]
+}
+
+// setCalendarToken replaces the feed token of the chat, an empty token
+// disables the feed.
+func (b *Bot) setCalendarToken(chatID int64, token string) {
+	b.muCalendarTokens.Lock()
+	defer b.muCalendarTokens.Unlock()
+	if token == "" {
+		delete(b.CalendarTokens, chatID)
+	} else {
+		b.CalendarTokens[chatID] = token
+	}
+	if err := b.Store.Save(calendarStoreKey, b.CalendarTokens); err != nil {
+		b.logger(chatID).Error("Cannot save calendar feeds", "error", err)
+	}
+}
+
+// calendarChat returns the chat the feed token belongs to.
+func (b *Bot) calendarChat(token string) (int64, bool) {
+	b.muCalendarTokens.Lock()
+	defer b.muCalendarTokens.Unlock()
+	for chatID, candidate := range b.CalendarTokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			return chatID, true
+		}
+	}
+	return 0, false
+}
+
+func newCalendarToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func (b *Bot) processCalendarCommand(chatID int64) {
+	if b.config().HTTPAddress == "" {
+		msg := tgbotapi.NewMessage(chatID, "The calendar feed is served by the HTTP server, set RBOT_HTTP_ADDRESS to enable it")
+		b.sendMessage(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Handling calendar command... please wait")
+	message, _ := b.sendMessage(msg)
+	command := userCalendar{
+		chatID:    message.Chat.ID,
+		messageID: message.MessageID,
+	}
+	if b.calendarToken(chatID) == "" {
+		token, err := newCalendarToken()
+		if err != nil {
+			b.sendError(chatID, "", err)
+			return
+		}
+		b.setCalendarToken(chatID, token)
+	}
+	b.setCalendarState(command.chatID, &command)
+	b.showCalendar(&command)
+}
+
+func (b *Bot) calendar(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot manage calendar", "error", err)
+		return false
+	}
+	command, exists := b.getCalendarState(chatID)
+	if !exists {
+		return false
+	}
+
+	switch update.CallbackQuery.Data {
+	case CalendarNewToken:
+		token, err := newCalendarToken()
+		if err != nil {
+			return b.reportError(command, err, func() bool { return b.calendar(update) })
+		}
+		b.setCalendarToken(chatID, token)
+		return b.showCalendar(command)
+	case CalendarDisable:
+		b.setCalendarToken(chatID, "")
+		b.clearState(update)
+		b.sendMessageWithEdit(command, "Calendar feed disabled, /calendar creates a new link")
+		return false
+	case CalendarDone:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, "Calendar feed: "+b.calendarURL(chatID))
+		return false
+	}
+	return false
+}
+
+func (b *Bot) showCalendar(command *userCalendar) bool {
+	feed := b.calendarURL(command.chatID)
+
+	var text strings.Builder
+	text.WriteString("Subscribe to the calendar feed in your calendar app, keep the links private:\n\n")
+	fmt.Fprintf(&text, "All releases:\n%s\n\n", feed)
+	fmt.Fprintf(&text, "Monitored movies only:\n%s?monitored=1\n\n", feed)
+	fmt.Fprintf(&text, "Movies you requested only:\n%s?requested=1\n\n", feed)
+	fmt.Fprintf(&text, "Releases of the past %d and next %d days are included, change it with &past=7 or &days=90.", calendarPastDays, calendarFutureDays)
+	if b.config().PublicURL == "" {
+		text.WriteString("\n\nSet RBOT_PUBLIC_URL to the address your calendar app reaches the bot at.")
+	}
+
+	keyboard := b.createKeyboard(
+		[]string{"New link - the old one stops working", "Disable feed", "Done"},
+		[]string{CalendarNewToken, CalendarDisable, CalendarDone},
+	)
+
+	b.setActiveCommand(command.chatID, CalendarCommand)
+	b.setCalendarState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text.String())
+	return false
+}
+
+// calendarURL returns the feed URL of the chat, just the path if the public
+// URL isn't configured.
+func (b *Bot) calendarURL(chatID int64) string {
+	return b.config().PublicURL + CalendarPath + b.calendarToken(chatID) + ".ics"
+}
+
+// CalendarHandler serves the calendar feeds, /calendar/<token>.ics. The
+// query parameters monitored=1 and requested=1 filter the movies, past and
+// days change the time range.
+func (b *Bot) CalendarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, CalendarPath), ".ics")
+		chatID, exists := b.calendarChat(token)
+		// the feed of a chat removed by a reload looks like an unknown one
+		if token == "" || !exists || !b.isAllowed(chatID) {
+			http.NotFound(w, req)
+			return
+		}
+
+		query := req.URL.Query()
+		past, err := calendarDays(query.Get("past"), calendarPastDays)
+		if err != nil {
+			http.Error(w, "past: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		days, err := calendarDays(query.Get("days"), calendarFutureDays)
+		if err != nil {
+			http.Error(w, "days: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var requested map[int64]bool
+		if isSet(query.Get("requested")) {
+			requested = b.requestedMovies(chatID)
+		}
+
+		calendar, err := b.releaseCalendar(today().AddDate(0, 0, -past), today().AddDate(0, 0, days+1), isSet(query.Get("monitored")), requested)
+		if err != nil {
+			chatLogger(chatID).Error("Cannot serve calendar feed", "error", err)
+			http.Error(w, friendlyError(err), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		if err := calendar.Write(w); err != nil {
+			chatLogger(chatID).Warn("Cannot send calendar feed", "error", err)
+		}
+	})
+}
+
+// releaseCalendar returns an event per cinema, digital and physical release
+// of all instances. Only requested movies are included if requested isn't nil.
+func (b *Bot) releaseCalendar(start, end time.Time, monitoredOnly bool, requested map[int64]bool) (*ics.Calendar, error) {
+	calendar := &ics.Calendar{Name: "Radarr releases"}
+	added := make(map[string]bool)
+	for _, instance := range b.RadarrServers {
+		movies, err := instance.Server.GetCalendar(radarr.Calendar{
+			Start:       start,
+			End:         end,
+			Unmonitored: !monitoredOnly,
+		})
+		if err != nil {
+			// an incomplete feed would delete the events of the instance in the calendar app
+			return nil, fmt.Errorf("%s: %w", instance.Name, err)
+		}
+		for _, movie := range movies {
+			if (monitoredOnly && !movie.Monitored) || (requested != nil && !requested[movie.TmdbID]) {
+				continue
+			}
+			for _, kind := range releaseKinds {
+				date := releaseDate(movie, kind)
+				uid := fmt.Sprintf("%d-%s@telegram-bot-radarr", movie.TmdbID, kind)
+				// the same movie on several instances is a single event
+				if date.IsZero() || date.Before(start) || !date.Before(end) || added[uid] {
+					continue
+				}
+				added[uid] = true
+				calendar.Events = append(calendar.Events, ics.Event{
+					UID:         uid,
+					Date:        date,
+					Summary:     fmt.Sprintf("%s (%s release)", movie.Title, kind),
+					Description: releaseDescription(movie),
+					URL:         fmt.Sprintf("https://www.themoviedb.org/movie/%d", movie.TmdbID),
+				})
+			}
+		}
+	}
+	return calendar, nil
+}
+
+func releaseDescription(movie *radarr.Movie) string {
+	lines := []string{fmt.Sprintf("%v (%v)", movie.Title, movie.Year)}
+	if movie.Overview != "" {
+		lines = append(lines, movie.Overview)
+	}
+	lines = append(lines, fmt.Sprintf("TMDB: https://www.themoviedb.org/movie/%d", movie.TmdbID))
+	if movie.ImdbID != "" {
+		lines = append(lines, "IMDb: https://www.imdb.com/title/"+movie.ImdbID)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// calendarDays parses a number of days of the feed URL.
+func calendarDays(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 || days > 3*calendarFutureDays {
+		return 0, fmt.Errorf("%q is not a number of days between 0 and %d", value, 3*calendarFutureDays)
+	}
+	return days, nil
+}
+
+// isSet reports whether a flag of the feed URL is enabled, e.g. monitored=1.
+func isSet(value string) bool {
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
@@ -0,0 +1,544 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const (
+	CollectionID               = "COLLECTION_ID_"
+	CollectionMovie            = "COLLECTION_MOVIE_"
+	CollectionAdd              = "COLLECTION_ADD"
+	CollectionAddSearch        = "COLLECTION_ADD_SEARCH"
+	CollectionToggleMonitor    = "COLLECTION_TOGGLE_MONITOR"
+	CollectionToggleProfile    = "COLLECTION_TOGGLE_PROFILE"
+	CollectionToggleRootFolder = "COLLECTION_TOGGLE_ROOTFOLDER"
+	CollectionGoBack           = "COLLECTION_GOBACK"
+	CollectionCancel           = "COLLECTION_CANCEL"
+	CollectionFirstPage        = "COLLECTION_FIRST_PAGE"
+	CollectionPreviousPage     = "COLLECTION_PREV_PAGE"
+	CollectionNextPage         = "COLLECTION_NEXT_PAGE"
+	CollectionLastPage         = "COLLECTION_LAST_PAGE"
+)
+
+const (
+	InLibraryIcon = "\U0001F4DA" // Books
+	OnDiskIcon    = "\U0001F4BE" // Floppy disk
+	MissingIcon   = "⏳"          // Hourglass
+	ExcludedIcon  = "\U0001F6AB" // No entry
+)
+
+func (b *Bot) processCollectionsCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
+	msg := tgbotapi.NewMessage(chatID, "Handling collections command... please wait")
+	message, _ := b.sendMessage(msg)
+	command := userCollection{
+		chatID:    message.Chat.ID,
+		messageID: message.MessageID,
+	}
+
+	collections, err := radarrapi.GetCollections(r, 0)
+	if err != nil {
+		b.reportError(&command, err, func() bool {
+			b.processCollectionsCommand(update, chatID, r)
+			return false
+		})
+		return
+	}
+
+	criteria := strings.ToLower(update.Message.CommandArguments())
+	if criteria != "" {
+		var matches []*radarrapi.Collection
+		for _, collection := range collections {
+			if strings.Contains(strings.ToLower(collection.Title), criteria) {
+				matches = append(matches, collection)
+			}
+		}
+		collections = matches
+	}
+	if len(collections) == 0 {
+		b.sendMessageWithEdit(&command, "No collections found")
+		return
+	}
+
+	sort.SliceStable(collections, func(i, j int) bool {
+		return utils.IgnoreArticles(strings.ToLower(collections[i].Title)) < utils.IgnoreArticles(strings.ToLower(collections[j].Title))
+	})
+	command.collections = collections
+
+	if err := b.loadCollectionSettings(&command); err != nil {
+		b.reportError(&command, err, func() bool {
+			b.processCollectionsCommand(update, chatID, r)
+			return false
+		})
+		return
+	}
+
+	b.setActiveCommand(command.chatID, CollectionCommand)
+	if len(collections) == 1 {
+		command.collection = collections[0]
+		b.setCollectionState(command.chatID, &command)
+		b.showCollectionDetail(&command)
+		return
+	}
+	b.setCollectionState(command.chatID, &command)
+	b.showCollectionList(&command)
+}
+
+// showCollectionOfMovie opens the collection view for a movie from another
+// flow. Going back returns to returnCommand.
+func (b *Bot) showCollectionOfMovie(movie *radarr.Movie, returnCommand string, chatID int64, messageID int) bool {
+	command := userCollection{
+		returnCommand: returnCommand,
+		chatID:        chatID,
+		messageID:     messageID,
+	}
+	if movie.Collection == nil || movie.Collection.TmdbID == 0 {
+		return false
+	}
+
+	collections, err := radarrapi.GetCollections(b.getRadarrServer(command.chatID), movie.Collection.TmdbID)
+	if err != nil {
+		return b.reportError(&command, err, func() bool {
+			return b.showCollectionOfMovie(movie, returnCommand, chatID, messageID)
+		})
+	}
+	if err := b.loadCollectionSettings(&command); err != nil {
+		return b.reportError(&command, err, func() bool {
+			return b.showCollectionOfMovie(movie, returnCommand, chatID, messageID)
+		})
+	}
+
+	b.setActiveCommand(chatID, CollectionCommand)
+	if len(collections) == 0 {
+		keyboard := b.createKeyboard(
+			[]string{"\U0001F519"},
+			[]string{CollectionGoBack},
+		)
+		b.setCollectionState(chatID, &command)
+		b.sendMessageWithEditAndKeyboard(&command, keyboard, "This collection is not known to Radarr yet. Add a movie of the collection first.")
+		return false
+	}
+	command.collection = collections[0]
+	b.setCollectionState(chatID, &command)
+	return b.showCollectionDetail(&command)
+}
+
+// loadCollectionSettings fetches everything needed to render and edit collections.
+func (b *Bot) loadCollectionSettings(command *userCollection) error {
+	movies, err := b.getRadarrServer(command.chatID).GetMovie(0)
+	if err != nil {
+		return err
+	}
+	command.library = make(map[int64]*radarr.Movie, len(movies))
+	for _, movie := range movies {
+		command.library[movie.TmdbID] = movie
+	}
+
+	command.qualityProfiles, err = b.getRadarrServer(command.chatID).GetQualityProfiles()
+	if err != nil {
+		return err
+	}
+	command.rootFolders, err = b.getRadarrServer(command.chatID).GetRootFolders()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (b *Bot) collections(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot manage collections", "error", err)
+		return false
+	}
+	command, exists := b.getCollectionState(chatID)
+	if !exists {
+		return false
+	}
+
+	switch update.CallbackQuery.Data {
+	// ignore click on page number
+	case "current_page":
+		return false
+	case CollectionFirstPage:
+		command.page = 0
+		return b.showCollectionList(command)
+	case CollectionPreviousPage:
+		if command.page > 0 {
+			command.page--
+		}
+		return b.showCollectionList(command)
+	case CollectionNextPage:
+		command.page++
+		return b.showCollectionList(command)
+	case CollectionLastPage:
+		totalPages := (len(command.collections) + b.config().MaxItems - 1) / b.config().MaxItems
+		command.page = totalPages - 1
+		return b.showCollectionList(command)
+	case CollectionAdd:
+		return b.handleCollectionAdd(command, false)
+	case CollectionAddSearch:
+		return b.handleCollectionAdd(command, true)
+	case CollectionToggleMonitor:
+		return b.handleCollectionToggleMonitor(command)
+	case CollectionToggleProfile:
+		return b.handleCollectionToggleProfile(command)
+	case CollectionToggleRootFolder:
+		return b.handleCollectionToggleRootFolder(command)
+	case CollectionGoBack:
+		return b.handleCollectionGoBack(update, command)
+	case CollectionCancel:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return false
+	default:
+		if strings.HasPrefix(update.CallbackQuery.Data, CollectionID) {
+			return b.handleCollectionSelection(update, command)
+		}
+		if strings.HasPrefix(update.CallbackQuery.Data, CollectionMovie) {
+			return b.handleCollectionMovieSelection(update, command)
+		}
+		return false
+	}
+}
+
+func (b *Bot) showCollectionList(command *userCollection) bool {
+	collections := command.collections
+
+	// Pagination parameters
+	page := command.page
+	pageSize := b.config().MaxItems
+	totalPages := (len(collections) + pageSize - 1) / pageSize
+
+	// Calculate start and end index for the current page
+	startIndex := page * pageSize
+	endIndex := (page + 1) * pageSize
+	if endIndex > len(collections) {
+		endIndex = len(collections)
+	}
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, collection := range collections[startIndex:endIndex] {
+		inLibrary := 0
+		for _, movie := range collection.Movies {
+			if _, exists := command.library[movie.TmdbID]; exists {
+				inLibrary++
+			}
+		}
+		buttonText := fmt.Sprintf("%v (%d/%d)", collection.Title, inLibrary, len(collection.Movies))
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, CollectionID+strconv.Itoa(int(collection.ID))),
+		))
+	}
+
+	if len(collections) > pageSize {
+		paginationButtons := b.createPaginationButtons(page, totalPages, CollectionFirstPage, CollectionPreviousPage, CollectionNextPage, CollectionLastPage)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, paginationButtons)
+	}
+
+	keyboardCancel := b.createKeyboard(
+		[]string{"Cancel - clear command"},
+		[]string{CollectionCancel},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardCancel.InlineKeyboard...)
+
+	b.setCollectionState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, fmt.Sprintf("Collections - page %d/%d", page+1, totalPages))
+	return false
+}
+
+func (b *Bot) handleCollectionSelection(update tgbotapi.Update, command *userCollection) bool {
+	collectionID, err := strconv.ParseInt(strings.TrimPrefix(update.CallbackQuery.Data, CollectionID), 10, 64)
+	if err != nil {
+		b.logger(command.chatID).Warn("Cannot convert collection ID to int", "error", err)
+		return false
+	}
+	for _, collection := range command.collections {
+		if collection.ID == collectionID {
+			command.collection = collection
+			break
+		}
+	}
+	if command.collection == nil {
+		return false
+	}
+	command.selectedMovies = nil
+	command.status = ""
+	return b.showCollectionDetail(command)
+}
+
+func (b *Bot) showCollectionDetail(command *userCollection) bool {
+	collection := command.collection
+
+	var collectionMonitorIcon string
+	if collection.Monitored {
+		collectionMonitorIcon = MonitorIcon
+	} else {
+		collectionMonitorIcon = UnmonitorIcon
+	}
+	profileName := ""
+	if profile := findQualityProfileByID(command.qualityProfiles, collection.QualityProfileID); profile != nil {
+		profileName = profile.Name
+	}
+
+	var text strings.Builder
+	if command.status != "" {
+		fmt.Fprintf(&text, "%s\n\n", utils.Escape(command.status))
+	}
+	fmt.Fprintf(&text, "*%s*\n\n", utils.Escape(collection.Title))
+	fmt.Fprintf(&text, "Collection Monitored: %s\n", collectionMonitorIcon)
+	fmt.Fprintf(&text, "Quality Profile: %s\n", utils.Escape(profileName))
+	fmt.Fprintf(&text, "Root Folder: %s\n", utils.Escape(collection.RootFolderPath))
+	fmt.Fprintf(&text, "Minimum Availability: %s\n\n", utils.Escape(string(collection.MinimumAvailability)))
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, member := range collection.Movies {
+		fmt.Fprintf(&text, "%s [%v](https://www.themoviedb.org/movie/%v) \\- _%v_\n", command.collectionMovieMarkers(member), utils.Escape(member.Title), member.TmdbID, member.Year)
+
+		if _, exists := command.library[member.TmdbID]; exists || member.IsExcluded {
+			continue
+		}
+		checkbox := "⬜"
+		if isSelectedTmdbID(command.selectedMovies, member.TmdbID) {
+			checkbox = "✅"
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %v - %v", checkbox, member.Title, member.Year), CollectionMovie+strconv.Itoa(int(member.TmdbID))),
+		))
+	}
+	fmt.Fprintf(&text, "\n%s in library %s monitored %s on disk %s missing %s excluded", InLibraryIcon, MonitorIcon, OnDiskIcon, MissingIcon, ExcludedIcon)
+
+	if len(command.selectedMovies) > 0 {
+		keyboardAdd := b.createKeyboard(
+			[]string{fmt.Sprintf("Add %d movie(s) monitored + search now", len(command.selectedMovies)), fmt.Sprintf("Add %d movie(s) monitored", len(command.selectedMovies))},
+			[]string{CollectionAddSearch, CollectionAdd},
+		)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardAdd.InlineKeyboard...)
+	}
+
+	settingsLabels := []string{"Collection Monitored: " + collectionMonitorIcon}
+	settingsData := []string{CollectionToggleMonitor}
+	if len(command.qualityProfiles) > 1 {
+		settingsLabels = append(settingsLabels, "Quality Profile: "+profileName)
+		settingsData = append(settingsData, CollectionToggleProfile)
+	}
+	if len(command.rootFolders) > 1 {
+		settingsLabels = append(settingsLabels, "Root Folder: "+collection.RootFolderPath)
+		settingsData = append(settingsData, CollectionToggleRootFolder)
+	}
+	settingsLabels = append(settingsLabels, "Cancel - clear command", "\U0001F519")
+	settingsData = append(settingsData, CollectionCancel, CollectionGoBack)
+	keyboardSettings := b.createKeyboard(settingsLabels, settingsData)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardSettings.InlineKeyboard...)
+
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
+		command.chatID,
+		command.messageID,
+		text.String(),
+		keyboard,
+	)
+	editMsg.ParseMode = "MarkdownV2"
+	editMsg.DisableWebPagePreview = true
+	b.setCollectionState(command.chatID, command)
+	b.sendMessage(editMsg)
+	return false
+}
+
+// collectionMovieMarkers returns the status icons of a collection member.
+func (c *userCollection) collectionMovieMarkers(member *radarrapi.CollectionMovie) string {
+	movie, exists := c.library[member.TmdbID]
+	if !exists {
+		if member.IsExcluded {
+			return ExcludedIcon
+		}
+		return "➖" // Minus, not in library
+	}
+	markers := InLibraryIcon
+	if movie.Monitored {
+		markers += MonitorIcon
+	} else {
+		markers += UnmonitorIcon
+	}
+	if movie.HasFile {
+		markers += OnDiskIcon
+	} else {
+		markers += MissingIcon
+	}
+	return markers
+}
+
+func (b *Bot) handleCollectionMovieSelection(update tgbotapi.Update, command *userCollection) bool {
+	tmdbID, err := strconv.ParseInt(strings.TrimPrefix(update.CallbackQuery.Data, CollectionMovie), 10, 64)
+	if err != nil {
+		b.logger(command.chatID).Warn("Cannot convert TMDB ID to int", "error", err)
+		return false
+	}
+	if isSelectedTmdbID(command.selectedMovies, tmdbID) {
+		var updated []int64
+		for _, id := range command.selectedMovies {
+			if id != tmdbID {
+				updated = append(updated, id)
+			}
+		}
+		command.selectedMovies = updated
+	} else {
+		command.selectedMovies = append(command.selectedMovies, tmdbID)
+	}
+	command.status = ""
+	return b.showCollectionDetail(command)
+}
+
+func (b *Bot) handleCollectionAdd(command *userCollection, search bool) bool {
+	collection := command.collection
+
+	// use the collection defaults, fall back to the first profile and root folder
+	profileID := collection.QualityProfileID
+	if findQualityProfileByID(command.qualityProfiles, profileID) == nil && len(command.qualityProfiles) > 0 {
+		profileID = command.qualityProfiles[0].ID
+	}
+	rootFolderPath := collection.RootFolderPath
+	if rootFolderPath == "" && len(command.rootFolders) > 0 {
+		rootFolderPath = command.rootFolders[0].Path
+	}
+	minimumAvailability := collection.MinimumAvailability
+	if minimumAvailability == "" {
+		minimumAvailability = radarr.AvailabilityAnnounced
+	}
+
+	var added, failed []string
+	for _, member := range collection.Movies {
+		if !isSelectedTmdbID(command.selectedMovies, member.TmdbID) {
+			continue
+		}
+		addMovieInput := radarr.AddMovieInput{
+			MinimumAvailability: minimumAvailability,
+			TmdbID:              member.TmdbID,
+			Title:               member.Title,
+			QualityProfileID:    profileID,
+			RootFolderPath:      rootFolderPath,
+			AddOptions: &radarr.AddMovieOptions{
+				SearchForMovie: search,
+				Monitor:        "movieOnly",
+			},
+			Tags:      collection.Tags,
+			Monitored: *starr.True(),
+		}
+		movie, err := b.getRadarrServer(command.chatID).AddMovie(&addMovieInput)
+		if err != nil {
+			b.logError(command.chatID, err)
+			failed = append(failed, fmt.Sprintf("%v: %s", member.Title, friendlyError(err)))
+			continue
+		}
+		b.recordRequest(command.chatID, movie.TmdbID)
+		command.library[movie.TmdbID] = movie
+		added = append(added, member.Title)
+	}
+
+	var status strings.Builder
+	if len(added) > 0 {
+		fmt.Fprintf(&status, "Added: %s", strings.Join(added, ", "))
+	}
+	if len(failed) > 0 {
+		if status.Len() > 0 {
+			status.WriteString("\n")
+		}
+		fmt.Fprintf(&status, "Failed: %s", strings.Join(failed, "; "))
+	}
+	command.status = status.String()
+	command.selectedMovies = nil
+	return b.showCollectionDetail(command)
+}
+
+func (b *Bot) handleCollectionToggleMonitor(command *userCollection) bool {
+	monitored := !command.collection.Monitored
+	return b.updateCollection(command, &radarrapi.CollectionUpdate{Monitored: &monitored})
+}
+
+func (b *Bot) handleCollectionToggleProfile(command *userCollection) bool {
+	if len(command.qualityProfiles) == 0 {
+		return false
+	}
+	nextProfileIndex := (getQualityProfileIndexByID(command.qualityProfiles, command.collection.QualityProfileID) + 1) % len(command.qualityProfiles)
+	profileID := command.qualityProfiles[nextProfileIndex].ID
+	return b.updateCollection(command, &radarrapi.CollectionUpdate{QualityProfileID: &profileID})
+}
+
+func (b *Bot) handleCollectionToggleRootFolder(command *userCollection) bool {
+	if len(command.rootFolders) == 0 {
+		return false
+	}
+	currentIndex := -1
+	for i, rootFolder := range command.rootFolders {
+		if rootFolder.Path == command.collection.RootFolderPath {
+			currentIndex = i
+			break
+		}
+	}
+	rootFolderPath := command.rootFolders[(currentIndex+1)%len(command.rootFolders)].Path
+	return b.updateCollection(command, &radarrapi.CollectionUpdate{RootFolderPath: &rootFolderPath})
+}
+
+func (b *Bot) updateCollection(command *userCollection, update *radarrapi.CollectionUpdate) bool {
+	update.CollectionIDs = []int64{command.collection.ID}
+	if _, err := radarrapi.UpdateCollections(b.getRadarrServer(command.chatID), update); err != nil {
+		return b.reportError(command, err, func() bool { return b.updateCollection(command, update) })
+	}
+	// the bulk editor does not return the movies of a collection, so reload it
+	collection, err := radarrapi.GetCollection(b.getRadarrServer(command.chatID), command.collection.ID)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.updateCollection(command, update) })
+	}
+	for i := range command.collections {
+		if command.collections[i].ID == collection.ID {
+			command.collections[i] = collection
+		}
+	}
+	command.collection = collection
+	command.status = ""
+	return b.showCollectionDetail(command)
+}
+
+func (b *Bot) handleCollectionGoBack(update tgbotapi.Update, command *userCollection) bool {
+	switch command.returnCommand {
+	case AddMovieCommand:
+		addCommand, exists := b.getAddMovieState(command.chatID)
+		if !exists {
+			return false
+		}
+		b.setActiveCommand(command.chatID, AddMovieCommand)
+		return b.showAddMovieDetails(addCommand)
+	case LibraryFilteredCommand:
+		libraryCommand, exists := b.getLibraryState(command.chatID)
+		if !exists {
+			return false
+		}
+		b.setActiveCommand(command.chatID, LibraryFilteredCommand)
+		return b.showLibraryMovieDetail(update, libraryCommand)
+	}
+	if command.collection != nil && len(command.collections) > 1 {
+		command.collection = nil
+		command.selectedMovies = nil
+		command.status = ""
+		return b.showCollectionList(command)
+	}
+	b.clearState(update)
+	b.sendMessageWithEdit(command, CommandsCleared)
+	return false
+}
+
+func isSelectedTmdbID(selected []int64, tmdbID int64) bool {
+	for _, id := range selected {
+		if id == tmdbID {
+			return true
+		}
+	}
+	return false
+}
@@ -2,157 +2,241 @@ package bot
 
 import (
 	"fmt"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/woiza/telegram-bot-radarr/pkg/utils"
-	"golift.io/starr"
 	"golift.io/starr/radarr"
 )
 
-func (b *Bot) handleCommand(update tgbotapi.Update, r *radarr.Radarr) {
+// commandNames maps the commands and their aliases to the name handleCommand
+// switches on and the metrics count. Unknown commands show the help.
+var commandNames = map[string]string{
+	"q": "q", "query": "q", "add": "q", "Q": "q", "Query": "q", "Add": "q",
+	"bulkadd": "bulkadd", "ba": "bulkadd",
+	"collections": "collections", "collection": "collections", "c": "collections",
+	"movies": "movies", "library": "movies", "l": "movies",
+	"find": "find", "f": "find",
+	"upgrades": "upgrades", "cutoff": "upgrades",
+	"delete": "delete", "remove": "delete", "Delete": "delete", "Remove": "delete", "d": "delete",
+	"tv": "tv", "series": "tv", "addseries": "tv",
+	"shows": "shows", "tvlibrary": "shows",
+	"clear": "clear", "cancel": "clear", "stop": "clear",
+	"diskspace": "diskspace", "disk": "diskspace", "free": "diskspace", "rootfolder": "diskspace", "rootfolders": "diskspace",
+	"stats": "stats", "statistics": "stats",
+	"up": "up", "upcoming": "up",
+	"rss": "rss", "RSS": "rss", "searchmonitored": "searchmonitored",
+	"updateAll": "updateall", "updateall": "updateall",
+	"system": "system", "System": "system", "systemstatus": "system", "Systemstatus": "system",
+	"health": "health", "queue": "queue",
+	"diag": "diag", "diagnostics": "diag",
+	"calendar": "calendar", "ics": "calendar",
+	"reminders": "reminders", "reminder": "reminders",
+	"digest": "digest", "digests": "digest",
+	"getid": "getid", "id": "getid",
+}
+
+func commandName(command string) string {
+	if name, exists := commandNames[command]; exists {
+		return name
+	}
+	return "help"
+}
+
+// handleCommand runs the command on the first instance, or on all instances
+// for commands which support it.
+func (b *Bot) handleCommand(update tgbotapi.Update, instances []*RadarrInstance) {
 
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot handle command: %v", err)
+		updateLogger(update).Warn("Cannot handle command", "error", err)
 		return
 	}
 
+	b.setActiveRadarr(chatID, instances[0])
+	r := instances[0].Server
 	msg := tgbotapi.NewMessage(chatID, "")
 
-	switch update.Message.Command() {
+	command := commandName(update.Message.Command())
+	commandsTotal.Inc(command)
+	switch command {
 
-	case "q", "query", "add", "Q", "Query", "Add":
+	case "q":
 		b.setActiveCommand(chatID, AddMovieCommand)
-		b.processAddCommand(update, chatID, r)
+		b.processAddCommand(update, chatID, instances)
 
-	case "movies", "library", "l":
+	case "bulkadd":
+		b.setActiveCommand(chatID, BulkAddCommand)
+		b.processBulkAddCommand(update, chatID, r)
+
+	case "collections":
+		b.setActiveCommand(chatID, CollectionCommand)
+		b.processCollectionsCommand(update, chatID, r)
+
+	case "movies":
 		b.setActiveCommand(chatID, LibraryMenuCommand)
 		b.processLibraryCommand(update, chatID, r)
 
-	case "delete", "remove", "Delete", "Remove", "d":
+	case "find":
+		b.setActiveCommand(chatID, LibraryFilteredCommand)
+		b.processFindCommand(update, chatID, r)
+
+	case "upgrades":
+		b.setActiveCommand(chatID, LibraryFilteredCommand)
+		b.processUpgradesCommand(update, chatID, r)
+
+	case "delete":
 		b.setActiveCommand(chatID, DeleteMovieCommand)
 		b.processDeleteCommand(update, chatID, r)
 
-	case "clear", "cancel", "stop":
-		b.clearState(update)
-		msg.Text = "All commands have been cleared"
-		b.sendMessage(msg)
-
-	case "diskspace", "disk", "free", "rootfolder", "rootfolders":
-		rootFolders, err := r.GetRootFolders()
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
+	case "tv":
+		if b.SonarrServer == nil {
+			msg.Text = SonarrNotConfigured
 			b.sendMessage(msg)
 			break
 		}
-		msg.Text = utils.PrepareRootFolders(rootFolders)
-		msg.ParseMode = "MarkdownV2"
-		msg.DisableWebPagePreview = true
-		b.sendMessage(msg)
+		b.setActiveCommand(chatID, AddSeriesCommand)
+		b.processAddSeriesCommand(update, chatID, b.SonarrServer)
 
-	case "up", "upcoming":
-		calendar := radarr.Calendar{
-			Start:       time.Now(),
-			End:         time.Now().AddDate(0, 0, 30), // 30 days
-			Unmonitored: *starr.True(),
-		}
-		upcoming, err := r.GetCalendar(calendar)
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
+	case "shows":
+		if b.SonarrServer == nil {
+			msg.Text = SonarrNotConfigured
 			b.sendMessage(msg)
 			break
 		}
-		if len(upcoming) == 0 {
-			msg.Text = "no upcoming releases in the next 30 days"
-			b.sendMessage(msg)
-			break
-		}
-		b.sendUpcoming(upcoming, &msg)
+		b.setActiveCommand(chatID, SeriesLibraryCommand)
+		b.processSeriesLibraryCommand(update, chatID, b.SonarrServer)
 
-	case "rss", "RSS":
-		command := radarr.CommandRequest{
-			Name:     "RssSync",
-			MovieIDs: []int64{},
-		}
-		_, err := r.SendCommand(&command)
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
-			b.sendMessage(msg)
-			break
-		}
-		msg.Text = "RSS sync started"
+	case "clear":
+		b.clearState(update)
+		msg.Text = "All commands have been cleared"
 		b.sendMessage(msg)
 
-	case "searchmonitored":
-		movies, err := r.GetMovie(0)
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
+	case "diskspace":
+		for _, instance := range instances {
+			rootFolders, err := instance.Server.GetRootFolders()
+			if err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+				continue
+			}
+			msg.Text = utils.PrepareRootFolders(rootFolders)
+			if len(b.RadarrServers) > 1 {
+				msg.Text = fmt.Sprintf("*%s*\n%s", utils.Escape(instance.Name), msg.Text)
+			}
+			msg.ParseMode = "MarkdownV2"
+			msg.DisableWebPagePreview = true
 			b.sendMessage(msg)
-			break
 		}
-		var monitoredMoviesIDs []int64
-		for _, movie := range movies {
-			if movie.Monitored {
-				monitoredMoviesIDs = append(monitoredMoviesIDs, movie.ID)
+		if b.SonarrServer != nil {
+			msg := tgbotapi.NewMessage(chatID, "")
+			rootFolders, err := b.SonarrServer.GetRootFolders()
+			if err != nil {
+				b.sendError(chatID, "Sonarr: ", err)
+				break
 			}
-		}
-		command := radarr.CommandRequest{
-			Name:     "MoviesSearch",
-			MovieIDs: monitoredMoviesIDs,
-		}
-		_, err = r.SendCommand(&command)
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
+			msg.Text = "*Sonarr*\n" + utils.PrepareSonarrRootFolders(rootFolders)
+			msg.ParseMode = "MarkdownV2"
+			msg.DisableWebPagePreview = true
 			b.sendMessage(msg)
-			break
 		}
-		msg.Text = "Search for monitored movies started"
-		b.sendMessage(msg)
 
-	case "updateAll", "updateall":
+	case "stats":
 		movies, err := r.GetMovie(0)
 		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
-			b.sendMessage(msg)
+			b.sendError(chatID, "", err)
 			break
 		}
-		var allMoviesIDs []int64
-		for _, movie := range movies {
-			allMoviesIDs = append(allMoviesIDs, movie.ID)
-		}
-		command := radarr.CommandRequest{
-			Name:     "RefreshMovie",
-			MovieIDs: allMoviesIDs,
-		}
-		_, err = r.SendCommand(&command)
+		rootFolders, err := r.GetRootFolders()
 		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
-			b.sendMessage(msg)
+			b.sendError(chatID, "", err)
 			break
 		}
-		msg.Text = "Update All started"
-		b.sendMessage(msg)
+		b.sendStats(utils.PrepareLibraryStats(movies, rootFolders), &msg)
 
-	case "system", "System", "systemstatus", "Systemstatus":
-		status, err := r.GetSystemStatus()
-		if err != nil {
-			msg.Text = err.Error()
-			fmt.Println(err)
+	case "up":
+		b.processUpcomingCommand(update, chatID, instances)
+
+	case "rss":
+		for _, instance := range instances {
+			command := radarr.CommandRequest{
+				Name:     "RssSync",
+				MovieIDs: []int64{},
+			}
+			if err := b.sendTrackedCommand(chatID, instance, &command, "RSS sync"); err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+			}
+		}
+
+	case "searchmonitored":
+		for _, instance := range instances {
+			movies, err := instance.Server.GetMovie(0)
+			if err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+				continue
+			}
+			var monitoredMoviesIDs []int64
+			for _, movie := range movies {
+				if movie.Monitored {
+					monitoredMoviesIDs = append(monitoredMoviesIDs, movie.ID)
+				}
+			}
+			command := radarr.CommandRequest{
+				Name:     "MoviesSearch",
+				MovieIDs: monitoredMoviesIDs,
+			}
+			if err := b.sendTrackedCommand(chatID, instance, &command, "Search for monitored movies"); err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+			}
+		}
+
+	case "updateall":
+		for _, instance := range instances {
+			movies, err := instance.Server.GetMovie(0)
+			if err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+				continue
+			}
+			var allMoviesIDs []int64
+			for _, movie := range movies {
+				allMoviesIDs = append(allMoviesIDs, movie.ID)
+			}
+			command := radarr.CommandRequest{
+				Name:     "RefreshMovie",
+				MovieIDs: allMoviesIDs,
+			}
+			if err := b.sendTrackedCommand(chatID, instance, &command, "Update All"); err != nil {
+				b.sendError(chatID, b.instanceLabel(instance), err)
+			}
+		}
+
+	case "system":
+		b.sendSystemStatus(chatID, instances)
+
+	case "health":
+		b.sendHealth(chatID, instances)
+
+	case "queue":
+		b.sendQueue(chatID, instances)
+
+	case "diag":
+		if !b.config().IsAdmin(chatID) {
+			msg.Text = "Only admins can run diagnostics"
 			b.sendMessage(msg)
 			break
 		}
-		msg.Text = prettyPrint(status)
-		b.sendMessage(msg)
+		b.sendDiagnostics(chatID)
 
-	case "getid", "id":
+	case "calendar":
+		b.setActiveCommand(chatID, CalendarCommand)
+		b.processCalendarCommand(chatID)
+
+	case "reminders":
+		b.processRemindersCommand(chatID)
+
+	case "digest":
+		b.setActiveCommand(chatID, DigestCommand)
+		b.processDigestCommand(chatID)
+
+	case "getid":
 		msg.Text = fmt.Sprintf("Your user ID: %d", chatID)
 		b.sendMessage(msg)
 
@@ -160,15 +244,30 @@ func (b *Bot) handleCommand(update tgbotapi.Update, r *radarr.Radarr) {
 		msg.Text = fmt.Sprintf("Hello %v!\n", update.Message.From)
 		msg.Text += "Here's a list of commands at your disposal:\n\n"
 		msg.Text += "/q [movie] - searches a movie \n"
+		msg.Text += "/bulkadd [movies] - adds several movies, one per line\n"
 		msg.Text += "/library [movie] - manage movie(s)\n"
+		msg.Text += "/find <query> - filters the library, e.g. /find year<2000 -monitored\n"
+		msg.Text += "/upgrades - lists movies which haven't met the cutoff\n"
+		msg.Text += "/collections [name] - manage movie collections\n"
 		msg.Text += "/delete [movie] - deletes a movie\n"
+		if b.SonarrServer != nil {
+			msg.Text += "/tv [series] - searches a series\n"
+			msg.Text += "/shows [series] - manage series, seasons and deletion\n"
+		}
 		msg.Text += "/clear - deletes all sent commands\n"
 		msg.Text += "/free  - lists free disk space \n"
-		msg.Text += "/up\t\t\t\t - lists upcoming movies in the next 30 days\n"
+		msg.Text += "/stats - shows library statistics\n"
+		msg.Text += "/up\t\t\t\t - lists upcoming movies in the next 30 days and episodes in the next 7 days, /up 90 or /up past 14 for other ranges\n"
 		msg.Text += "/rss \t\t - performs a RSS sync\n"
 		msg.Text += "/searchmonitored - searches all monitored movies\n"
 		msg.Text += "/updateall - updates metadata and rescans files/folders\n"
-		msg.Text += "/system - shows your Radarr configuration\n"
+		msg.Text += "/system - shows the version, uptime, updates, queue and disks of Radarr\n"
+		msg.Text += "/diag - checks the connection to Radarr and Sonarr\n"
+		msg.Text += "/digest - schedules a daily or weekly summary\n"
+		msg.Text += "/health - lists Radarr's health issues\n"
+		msg.Text += "/queue - lists the downloads and their progress\n"
+		msg.Text += "/calendar - link of a calendar feed of the releases\n"
+		msg.Text += "/reminders - lists and cancels release reminders\n"
 		msg.Text += "/id - shows your Telegram user ID"
 		b.sendMessage(msg)
 	}
@@ -0,0 +1,30 @@
+package bot
+
+import "testing"
+
+func TestInstanceCommandsHaveNames(t *testing.T) {
+	for command := range instanceCommands {
+		if _, exists := commandNames[command]; !exists {
+			t.Errorf("instance command %q is missing in commandNames", command)
+		}
+	}
+}
+
+func TestCallbackAction(t *testing.T) {
+	tests := []struct {
+		data, want string
+	}{
+		{data: ErrorRetry, want: "error_retry"},
+		{data: "REMIND_ME_603_digital_HD", want: "remind_me"},
+		{data: DeleteMovieUndo + "12", want: "delete_movie_undo"},
+		{data: DigestSection + "added", want: "digest_section"},
+		{data: "LIBRARY_FILTER_GENRE_Drama", want: "library_filter_genre"},
+		{data: "603", want: "none"},
+		{data: "", want: "none"},
+	}
+	for _, test := range tests {
+		if got := callbackAction(test.data); got != test.want {
+			t.Errorf("callbackAction(%q) = %q, want %q", test.data, got, test.want)
+		}
+	}
+}
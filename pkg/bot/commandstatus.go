@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+)
+
+const (
+	// commands are polled every commandPollFast in their first minute, then
+	// every commandPollSlow until commandPollTimeout.
+	commandPollFast    = 2 * time.Second
+	commandPollSlow    = 10 * time.Second
+	commandPollTimeout = time.Hour
+	// commandPollErrors failed polls in a row stop the tracking.
+	commandPollErrors = 5
+)
+
+// sendTrackedCommand sends the command to Radarr and a status message, which
+// is edited as the command is queued, started and finished. name describes
+// the command, e.g. "RSS sync".
+func (b *Bot) sendTrackedCommand(chatID int64, instance *RadarrInstance, request *radarr.CommandRequest, name string) error {
+	response, err := instance.Server.SendCommand(request)
+	if err != nil {
+		return err
+	}
+	text := commandStatusText(b.instanceLabel(instance)+name, response)
+	message, err := b.sendMessage(tgbotapi.NewMessage(chatID, text))
+	if err != nil {
+		// the command runs anyway, there's just nothing to update
+		return nil
+	}
+	go b.trackCommand(instance, message, name, response, text)
+	return nil
+}
+
+// trackCommand polls the command until it's done and edits the status
+// message whenever its text changes.
+func (b *Bot) trackCommand(instance *RadarrInstance, message tgbotapi.Message, name string, response *radarr.CommandResponse, text string) {
+	logger := chatLogger(message.Chat.ID).With("instance", instance.Name, "command", response.Name, "command_id", response.ID)
+	start := time.Now()
+	failures := 0
+	for !commandDone(response.Status) {
+		if time.Since(start) > commandPollTimeout {
+			logger.Warn("Stopped tracking command, it's still running")
+			b.editCommandStatus(message, fmt.Sprintf("%s%s is still running after %s, check Radarr for the result", b.instanceLabel(instance), name, commandPollTimeout))
+			return
+		}
+		if time.Since(start) < time.Minute {
+			time.Sleep(commandPollFast)
+		} else {
+			time.Sleep(commandPollSlow)
+		}
+
+		current, err := radarrapi.GetCommand(instance.Server, response.ID)
+		if err != nil {
+			failures++
+			logger.Debug("Cannot poll command", "error", err)
+			if failures >= commandPollErrors {
+				logger.Warn("Stopped tracking command", "error", err)
+				b.editCommandStatus(message, fmt.Sprintf("%s%s: status unknown, %s", b.instanceLabel(instance), name, friendlyError(err)))
+				return
+			}
+			continue
+		}
+		failures = 0
+		response = current
+
+		// Telegram rejects edits which don't change the message
+		if newText := commandStatusText(b.instanceLabel(instance)+name, response); newText != text {
+			text = newText
+			b.editCommandStatus(message, text)
+		}
+	}
+	logger.Info("Command finished", "status", response.Status, "duration", commandDuration(response))
+}
+
+func (b *Bot) editCommandStatus(message tgbotapi.Message, text string) {
+	if _, err := b.sendMessage(tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, text)); err != nil {
+		chatLogger(message.Chat.ID).Warn("Cannot update command status", "error", err)
+	}
+}
+
+// commandDone reports whether the command won't change anymore. Radarr also
+// knows aborted, cancelled and orphaned commands.
+func commandDone(status string) bool {
+	return status != "queued" && status != "started"
+}
+
+func commandStatusText(name string, response *radarr.CommandResponse) string {
+	var text string
+	switch response.Status {
+	case "queued":
+		text = name + " queued"
+	case "started":
+		// no running time, it would edit the message on every poll
+		text = name + " started"
+	case "completed":
+		text = fmt.Sprintf("%s finished in %s", name, commandDuration(response))
+	case "failed":
+		text = fmt.Sprintf("%s failed after %s", name, commandDuration(response))
+	default:
+		text = fmt.Sprintf("%s %s", name, response.Status)
+	}
+	if response.Message != "" && response.Status != "queued" {
+		text += ": " + response.Message
+	}
+	return text
+}
+
+// commandDuration returns how long the command ran, up to now if it's
+// still running.
+func commandDuration(response *radarr.CommandResponse) time.Duration {
+	if response.Started.IsZero() {
+		return 0
+	}
+	end := response.Ended
+	if end.IsZero() || end.Before(response.Started) {
+		end = time.Now()
+	}
+	return end.Sub(response.Started).Round(time.Second)
+}
+
+// movieSearchName describes the search of a single movie.
+func movieSearchName(movie *radarr.Movie) string {
+	return fmt.Sprintf("Search for %v (%v)", movie.Title, movie.Year)
+}
@@ -8,7 +8,6 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/woiza/telegram-bot-radarr/pkg/utils"
-	"golift.io/starr"
 	"golift.io/starr/radarr"
 )
 
@@ -16,12 +15,19 @@ const (
 	DeleteMovieConfirm      = "DELETE_MOVIE_SUBMIT"
 	DeleteMovieCancel       = "DELETE_MOVIE_CANCEL"
 	DeleteMovieGoBack       = "DELETE_MOVIE_GOBACK"
-	DeleteMovieYes          = "DELETE_MOVIE_YES"
+	DeleteMovieKeepFiles    = "DELETE_MOVIE_KEEP_FILES"
+	DeleteMovieWithFiles    = "DELETE_MOVIE_WITH_FILES"
+	DeleteMovieExclude      = "DELETE_MOVIE_EXCLUDE"
+	DeleteMovieExcludeKeep  = "DELETE_MOVIE_EXCLUDE_KEEP_FILES"
+	DeleteMovieUndo         = "DELETE_MOVIE_UNDO_"
+	DeleteMovieSearchLookup = "DELETE_MOVIE_SEARCH_LOOKUP"
 	DeleteMovieTMDBID       = "DELETE_MOVIE_TMDBID_"
 	DeleteMovieFirstPage    = "DELETE_MOVIE_FIRST_PAGE"
 	DeleteMoviePreviousPage = "DELETE_MOVIE_PREV_PAGE"
 	DeleteMovieNextPage     = "DELETE_MOVIE_NEXT_PAGE"
 	DeleteMovieLastPage     = "DELETE_MOVIE_LAST_PAGE"
+	// deleteMaxResults is the most search results offered for deletion.
+	deleteMaxResults = 25
 )
 
 func (b *Bot) processDeleteCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
@@ -30,8 +36,10 @@ func (b *Bot) processDeleteCommand(update tgbotapi.Update, chatID int64, r *rada
 
 	movies, err := r.GetMovie(0)
 	if err != nil {
-		msg := tgbotapi.NewMessage(chatID, err.Error())
-		b.sendMessage(msg)
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.processDeleteCommand(update, chatID, r)
+			return false
+		})
 		return
 	}
 	command := userDeleteMovie{
@@ -58,21 +66,29 @@ func (b *Bot) processDeleteCommand(update tgbotapi.Update, chatID int64, r *rada
 		return
 	}
 
-	searchResults, err := r.Lookup(criteria)
-	if err != nil {
-		msg := tgbotapi.NewMessage(chatID, err.Error())
-		b.sendMessage(msg)
+	// search the fetched library, a Radarr lookup is only done on request
+	command.searchCriteria = criteria
+	searchResults := utils.SearchMovies(movies, criteria)
+	if len(searchResults) > deleteMaxResults {
+		b.sendMessageWithEdit(&command, "Result size too large, please narrow down your search criteria")
+		return
+	}
+	if len(searchResults) == 0 {
+		keyboard := b.createKeyboard(
+			[]string{"Search via Radarr lookup", "Cancel - clear command"},
+			[]string{DeleteMovieSearchLookup, DeleteMovieCancel},
+		)
+		b.setDeleteMovieState(chatID, &command)
+		b.sendMessageWithEditAndKeyboard(&command, keyboard, fmt.Sprintf("No movies found in your library matching '%s'", criteria))
 		return
 	}
 
-	b.setDeleteMovieState(chatID, &command)
-	b.handleDeleteSearchResults(searchResults, &command)
-
+	b.showDeleteSearchResults(searchResults, &command)
 }
 func (b *Bot) deleteMovie(update tgbotapi.Update) bool {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot delete movie: %v", err)
+		updateLogger(update).Warn("Cannot delete movie", "error", err)
 		return false
 	}
 
@@ -97,15 +113,29 @@ func (b *Bot) deleteMovie(update tgbotapi.Update) bool {
 		command.page++
 		return b.showDeleteMovieSelection(command)
 	case DeleteMovieLastPage:
-		totalPages := (len(command.moviesForSelection) + b.Config.MaxItems - 1) / b.Config.MaxItems
+		totalPages := (len(command.moviesForSelection) + b.config().MaxItems - 1) / b.config().MaxItems
 		command.page = totalPages - 1
 		return b.showDeleteMovieSelection(command)
 	case DeleteMovieConfirm:
 		return b.processMovieSelectionForDelete(command)
-	case DeleteMovieYes:
-		return b.handleDeleteMovieYes(update, command)
+	case DeleteMovieKeepFiles:
+		return b.handleDeleteMovieYes(update, command, false, false)
+	case DeleteMovieWithFiles:
+		return b.handleDeleteMovieYes(update, command, true, false)
+	case DeleteMovieExcludeKeep:
+		return b.handleDeleteMovieYes(update, command, false, true)
+	case DeleteMovieExclude:
+		return b.handleDeleteMovieYes(update, command, true, true)
 	case DeleteMovieGoBack:
 		return b.showDeleteMovieSelection(command)
+	case DeleteMovieSearchLookup:
+		b.sendMessageWithEdit(command, "Searching via Radarr lookup... please wait")
+		searchResults, err := b.getRadarrServer(command.chatID).Lookup(command.searchCriteria)
+		if err != nil {
+			return b.reportError(command, err, func() bool { return b.deleteMovie(update) })
+		}
+		b.handleDeleteSearchResults(searchResults, command)
+		return false
 	case DeleteMovieCancel:
 		b.clearState(update)
 		b.sendMessageWithEdit(command, CommandsCleared)
@@ -126,7 +156,7 @@ func (b *Bot) showDeleteMovieSelection(command *userDeleteMovie) bool {
 
 	// Pagination parameters
 	page := command.page
-	pageSize := b.Config.MaxItems
+	pageSize := b.config().MaxItems
 	totalPages := (len(movies) + pageSize - 1) / pageSize
 
 	// Calculate start and end index for the current page
@@ -157,21 +187,7 @@ func (b *Bot) showDeleteMovieSelection(command *userDeleteMovie) bool {
 
 	// Create pagination buttons
 	if len(movies) > pageSize {
-		paginationButtons := []tgbotapi.InlineKeyboardButton{}
-		if page > 0 {
-			paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("◀️", DeleteMoviePreviousPage))
-		}
-		paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, totalPages), "current_page"))
-		if page+1 < totalPages {
-			paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("▶️", DeleteMovieNextPage))
-		}
-		if page != 0 {
-			paginationButtons = append([]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData("⏮️", DeleteMovieFirstPage)}, paginationButtons...)
-		}
-		if page+1 != totalPages {
-			paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("⏭️", DeleteMovieLastPage))
-		}
-
+		paginationButtons := b.createPaginationButtons(page, totalPages, DeleteMovieFirstPage, DeleteMoviePreviousPage, DeleteMovieNextPage, DeleteMovieLastPage)
 		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, paginationButtons)
 	}
 
@@ -209,7 +225,7 @@ func (b *Bot) handleDeleteSearchResults(searchResults []*radarr.Movie, command *
 		b.sendMessageWithEdit(command, "No movies found matching your search criteria")
 		return
 	}
-	if len(searchResults) > 25 {
+	if len(searchResults) > deleteMaxResults {
 		b.sendMessageWithEdit(command, "Result size too large, please narrow down your search criteria")
 		return
 	}
@@ -217,8 +233,8 @@ func (b *Bot) handleDeleteSearchResults(searchResults []*radarr.Movie, command *
 	// if movie has a radarr ID, it's in the library
 	var moviesInLibrary []*radarr.Movie
 	for _, movie := range searchResults {
-		if movie.ID != 0 {
-			moviesInLibrary = append(moviesInLibrary, movie)
+		if libraryMovie, exists := command.library[strconv.Itoa(int(movie.TmdbID))]; movie.ID != 0 && exists {
+			moviesInLibrary = append(moviesInLibrary, libraryMovie)
 		}
 	}
 	if len(moviesInLibrary) == 0 {
@@ -226,6 +242,11 @@ func (b *Bot) handleDeleteSearchResults(searchResults []*radarr.Movie, command *
 		return
 	}
 
+	b.showDeleteSearchResults(moviesInLibrary, command)
+}
+
+func (b *Bot) showDeleteSearchResults(moviesInLibrary []*radarr.Movie, command *userDeleteMovie) {
+
 	if len(moviesInLibrary) == 1 {
 		command.selectedMovies = make([]*radarr.Movie, len(moviesInLibrary))
 		command.selectedMovies[0] = moviesInLibrary[0]
@@ -238,38 +259,40 @@ func (b *Bot) handleDeleteSearchResults(searchResults []*radarr.Movie, command *
 	}
 }
 func (b *Bot) processMovieSelectionForDelete(command *userDeleteMovie) bool {
-	var keyboard tgbotapi.InlineKeyboardMarkup
 	var messageText strings.Builder
 	var disablePreview bool
 	switch len(command.selectedMovies) {
 	case 1:
-		keyboard = b.createKeyboard(
-			[]string{"Yes, delete this movie", "Cancel, clear command", "\U0001F519"},
-			[]string{DeleteMovieYes, DeleteMovieCancel, DeleteMovieGoBack},
-		)
-		fmt.Fprintf(&messageText, "Do you want to delete the following movie including all files?\n\n")
+		fmt.Fprintf(&messageText, "Do you want to delete the following movie?\n\n")
 		fmt.Fprintf(&messageText, "[%v](https://www.imdb.com/title/%v) \\- _%v_\n",
 			utils.Escape(command.selectedMovies[0].Title), command.selectedMovies[0].ImdbID, command.selectedMovies[0].Year)
 		disablePreview = false
 	case 0:
 		return b.showDeleteMovieSelection(command)
 	default:
-		keyboard = b.createKeyboard(
-			[]string{"Yes, delete these movies", "Cancel, clear command", "\U0001F519"},
-			[]string{DeleteMovieYes, DeleteMovieCancel, DeleteMovieGoBack},
-		)
 		// Sort the movies alphabetically based on their titles
 		sort.SliceStable(command.selectedMovies, func(i, j int) bool {
 			return utils.IgnoreArticles(strings.ToLower(command.selectedMovies[i].Title)) < utils.IgnoreArticles(strings.ToLower(command.selectedMovies[j].Title))
 		})
 
-		fmt.Fprintf(&messageText, "Do you want to delete the following movies including all files?\n\n")
+		fmt.Fprintf(&messageText, "Do you want to delete the following movies?\n\n")
 		for _, movie := range command.selectedMovies {
 			fmt.Fprintf(&messageText, "[%v](https://www.imdb.com/title/%v) \\- _%v_\n",
 				utils.Escape(movie.Title), movie.ImdbID, movie.Year)
 		}
 		disablePreview = true
 	}
+	fmt.Fprintf(&messageText, "\nSize on disk: %s", utils.Escape(utils.ByteCountSI(totalSizeOnDisk(command.selectedMovies))))
+
+	keyboard := b.createDeleteOptionsKeyboard(
+		command.selectedMovies,
+		[]string{DeleteMovieKeepFiles, DeleteMovieWithFiles, DeleteMovieExcludeKeep, DeleteMovieExclude},
+	)
+	keyboardCancelGoBack := b.createKeyboard(
+		[]string{"Cancel, clear command", "\U0001F519"},
+		[]string{DeleteMovieCancel, DeleteMovieGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardCancelGoBack.InlineKeyboard...)
 
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
 		command.chatID,
@@ -287,36 +310,23 @@ func (b *Bot) processMovieSelectionForDelete(command *userDeleteMovie) bool {
 	return false
 }
 
-func (b *Bot) handleDeleteMovieYes(update tgbotapi.Update, command *userDeleteMovie) bool {
-	var movieIDs []int64
-	var deletedMovies []string
-	for _, movie := range command.selectedMovies {
-		movieIDs = append(movieIDs, movie.ID)
-		deletedMovies = append(deletedMovies, movie.Title)
-	}
-	bulkEdit := radarr.BulkEdit{
-		MovieIDs:           movieIDs,
-		DeleteFiles:        starr.True(),
-		AddImportExclusion: starr.False(),
-	}
-
-	err := b.RadarrServer.DeleteMovies(&bulkEdit)
-	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		fmt.Println(err)
-		b.sendMessage(msg)
-		return false
-	}
-
-	messageText := fmt.Sprintf("Deleted movies:\n- %v", strings.Join(deletedMovies, "\n- "))
-	editMsg := tgbotapi.NewEditMessageText(
-		command.chatID,
-		command.messageID,
-		messageText,
+// createDeleteOptionsKeyboard returns the buttons to delete movies from Radarr
+// only or with their files, each without or with an import list exclusion.
+func (b *Bot) createDeleteOptionsKeyboard(movies []*radarr.Movie, buttonData []string) tgbotapi.InlineKeyboardMarkup {
+	return b.createKeyboard(
+		[]string{
+			"Delete from Radarr only, keep files",
+			fmt.Sprintf("Delete with files, frees %s", utils.ByteCountSI(totalSizeOnDisk(movies))),
+			"Delete from Radarr only + exclude from import lists",
+			"Delete with files + exclude from import lists",
+		},
+		buttonData,
 	)
+}
 
+func (b *Bot) handleDeleteMovieYes(update tgbotapi.Update, command *userDeleteMovie, deleteFiles, addImportExclusion bool) bool {
 	b.clearState(update)
-	b.sendMessage(editMsg)
+	b.deleteMovies(command.chatID, command.messageID, command.selectedMovies, deleteFiles, addImportExclusion)
 	return true
 }
 
@@ -337,6 +347,14 @@ func (b *Bot) handleDeleteMovieSelection(update tgbotapi.Update, command *userDe
 	return b.showDeleteMovieSelection(command)
 }
 
+func totalSizeOnDisk(movies []*radarr.Movie) int64 {
+	var size int64
+	for _, movie := range movies {
+		size += movie.SizeOnDisk
+	}
+	return size
+}
+
 func isSelectedMovie(selectedMovies []*radarr.Movie, MovieID int64) bool {
 	for _, selectedMovie := range selectedMovies {
 		if selectedMovie.ID == MovieID {
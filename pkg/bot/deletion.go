@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+)
+
+// pendingDeletion is a deletion waiting for the grace period to pass, it can
+// be undone until then.
+type pendingDeletion struct {
+	timer              *time.Timer
+	radarrServer       *radarr.Radarr
+	movies             []*radarr.Movie
+	deleteFiles        bool
+	addImportExclusion bool
+	chatID             int64
+	messageID          int
+}
+
+// deleteMovies deletes the movies right away, or queues the deletion if a grace
+// period is configured. The message is edited to show the result.
+func (b *Bot) deleteMovies(chatID int64, messageID int, movies []*radarr.Movie, deleteFiles, addImportExclusion bool) {
+	deletion := &pendingDeletion{
+		radarrServer:       b.getRadarrServer(chatID),
+		movies:             movies,
+		deleteFiles:        deleteFiles,
+		addImportExclusion: addImportExclusion,
+		chatID:             chatID,
+		messageID:          messageID,
+	}
+
+	gracePeriod := b.config().DeleteGracePeriod
+	if gracePeriod <= 0 {
+		b.executeMovieDeletion(deletion, b.logger(chatID))
+		return
+	}
+
+	key := pendingDeletionKey(chatID, messageID)
+	b.muPendingDeletions.Lock()
+	deletion.timer = time.AfterFunc(gracePeriod, func() {
+		b.muPendingDeletions.Lock()
+		delete(b.PendingDeletions, key)
+		b.muPendingDeletions.Unlock()
+		b.executeMovieDeletion(deletion, chatLogger(deletion.chatID))
+	})
+	b.PendingDeletions[key] = deletion
+	b.muPendingDeletions.Unlock()
+
+	text := fmt.Sprintf("%s in %v:\n- %v", deletionDescription(deletion), gracePeriod, strings.Join(movieTitles(movies), "\n- "))
+	keyboard := b.createKeyboard(
+		[]string{"Undo"},
+		[]string{DeleteMovieUndo + strconv.Itoa(messageID)},
+	)
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	b.sendMessage(editMsg)
+}
+
+func (b *Bot) executeMovieDeletion(deletion *pendingDeletion, logger *slog.Logger) {
+	var movieIDs []int64
+	for _, movie := range deletion.movies {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+	bulkEdit := radarr.BulkEdit{
+		MovieIDs:           movieIDs,
+		DeleteFiles:        &deletion.deleteFiles,
+		AddImportExclusion: &deletion.addImportExclusion,
+	}
+
+	err := deletion.radarrServer.DeleteMovies(&bulkEdit)
+	if err != nil {
+		logRequestError(logger, err)
+		b.sendMessage(tgbotapi.NewMessage(deletion.chatID, friendlyError(err)))
+		return
+	}
+
+	var messageText string
+	switch {
+	case deletion.addImportExclusion && deletion.deleteFiles:
+		messageText = "Deleted and excluded movies including files:\n- "
+	case deletion.addImportExclusion:
+		messageText = "Deleted and excluded movies from Radarr, files were kept:\n- "
+	case deletion.deleteFiles:
+		messageText = "Deleted movies including files:\n- "
+	default:
+		messageText = "Deleted movies from Radarr, files were kept:\n- "
+	}
+	messageText += strings.Join(movieTitles(deletion.movies), "\n- ")
+	editMsg := tgbotapi.NewEditMessageText(
+		deletion.chatID,
+		deletion.messageID,
+		messageText,
+	)
+	b.sendMessage(editMsg)
+}
+
+func (b *Bot) handleDeleteMovieUndo(update tgbotapi.Update) {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot undo deletion", "error", err)
+		return
+	}
+	messageID, err := strconv.Atoi(strings.TrimPrefix(update.CallbackQuery.Data, DeleteMovieUndo))
+	if err != nil {
+		b.logger(chatID).Warn("Cannot undo deletion", "error", err)
+		return
+	}
+
+	key := pendingDeletionKey(chatID, messageID)
+	b.muPendingDeletions.Lock()
+	deletion, exists := b.PendingDeletions[key]
+	if exists && deletion.timer.Stop() {
+		delete(b.PendingDeletions, key)
+	} else {
+		exists = false
+	}
+	b.muPendingDeletions.Unlock()
+
+	if !exists {
+		// too late, the deletion is already running
+		return
+	}
+
+	text := fmt.Sprintf("Deletion cancelled, nothing was deleted:\n- %v", strings.Join(movieTitles(deletion.movies), "\n- "))
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	b.sendMessage(editMsg)
+}
+
+func deletionDescription(deletion *pendingDeletion) string {
+	switch {
+	case deletion.addImportExclusion && deletion.deleteFiles:
+		return "Deleting and excluding movies including files"
+	case deletion.addImportExclusion:
+		return "Deleting and excluding movies from Radarr, keeping files,"
+	case deletion.deleteFiles:
+		return "Deleting movies including files"
+	default:
+		return "Deleting movies from Radarr, keeping files,"
+	}
+}
+
+func pendingDeletionKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d_%d", chatID, messageID)
+}
+
+func movieTitles(movies []*radarr.Movie) []string {
+	titles := make([]string, 0, len(movies))
+	for _, movie := range movies {
+		titles = append(titles, movie.Title)
+	}
+	return titles
+}
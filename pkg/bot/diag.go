@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
+)
+
+// diagnostic is the result of a single connectivity check.
+type diagnostic struct {
+	check   string
+	ok      bool
+	message string
+}
+
+// diagnoseRadarr checks that Radarr is reachable, the API key works, the
+// version is supported and the server is set up to add movies.
+func diagnoseRadarr(r *radarr.Radarr) []diagnostic {
+	status, err := r.GetSystemStatus()
+	if err != nil {
+		return []diagnostic{connectionDiagnostic(err)}
+	}
+	diagnostics := []diagnostic{
+		{check: "Connection", ok: true, message: "reachable, API key accepted"},
+		versionDiagnostic(status.Version, 3),
+	}
+
+	profiles, err := r.GetQualityProfiles()
+	diagnostics = append(diagnostics, countDiagnostic("Quality profiles", len(profiles), err,
+		"create a quality profile in Radarr under Settings > Profiles"))
+
+	rootFolders, err := r.GetRootFolders()
+	diagnostics = append(diagnostics, countDiagnostic("Root folders", len(rootFolders), err,
+		"add a root folder in Radarr under Settings > Media Management"))
+	for _, rootFolder := range rootFolders {
+		if !rootFolder.Accessible {
+			diagnostics = append(diagnostics, diagnostic{
+				check:   "Root folder",
+				message: rootFolder.Path + " is not accessible by Radarr, check the mount and permissions",
+			})
+		}
+	}
+	return diagnostics
+}
+
+// diagnoseSonarr runs the same checks as diagnoseRadarr for Sonarr.
+func diagnoseSonarr(s *sonarr.Sonarr) []diagnostic {
+	status, err := s.GetSystemStatus()
+	if err != nil {
+		return []diagnostic{connectionDiagnostic(err)}
+	}
+	diagnostics := []diagnostic{
+		{check: "Connection", ok: true, message: "reachable, API key accepted"},
+		versionDiagnostic(status.Version, 3),
+	}
+
+	profiles, err := s.GetQualityProfiles()
+	diagnostics = append(diagnostics, countDiagnostic("Quality profiles", len(profiles), err,
+		"create a quality profile in Sonarr under Settings > Profiles"))
+
+	rootFolders, err := s.GetRootFolders()
+	diagnostics = append(diagnostics, countDiagnostic("Root folders", len(rootFolders), err,
+		"add a root folder in Sonarr under Settings > Media Management"))
+	return diagnostics
+}
+
+// connectionDiagnostic explains why the system status couldn't be fetched.
+func connectionDiagnostic(err error) diagnostic {
+	return diagnostic{check: "Connection", message: friendlyError(err)}
+}
+
+// versionDiagnostic checks the major version is at least minMajor.
+func versionDiagnostic(version string, minMajor int) diagnostic {
+	result := diagnostic{check: "Version", ok: true, message: version}
+	major, err := strconv.Atoi(strings.Split(version, ".")[0])
+	if err == nil && major < minMajor {
+		result.ok = false
+		result.message = fmt.Sprintf("%s is not supported, version %d or newer is required", version, minMajor)
+	}
+	return result
+}
+
+func countDiagnostic(check string, count int, err error, hint string) diagnostic {
+	switch {
+	case err != nil:
+		return diagnostic{check: check, message: friendlyError(err)}
+	case count == 0:
+		return diagnostic{check: check, message: "none found, " + hint}
+	}
+	return diagnostic{check: check, ok: true, message: strconv.Itoa(count) + " found"}
+}
+
+func diagnosticsOK(diagnostics []diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if !diagnostic.ok {
+			return false
+		}
+	}
+	return true
+}
+
+func formatDiagnostics(name string, diagnostics []diagnostic) string {
+	var text strings.Builder
+	text.WriteString(name + ":")
+	for _, diagnostic := range diagnostics {
+		icon := MonitorIcon
+		if !diagnostic.ok {
+			icon = UnmonitorIcon
+		}
+		fmt.Fprintf(&text, "\n%s %s: %s", icon, diagnostic.check, diagnostic.message)
+	}
+	return text.String()
+}
+
+// diagnoseAll checks all configured servers, the results are keyed by server name.
+func (b *Bot) diagnoseAll() ([]string, map[string][]diagnostic) {
+	var names []string
+	results := make(map[string][]diagnostic)
+	for _, instance := range b.RadarrServers {
+		names = append(names, instance.Name)
+		results[instance.Name] = diagnoseRadarr(instance.Server)
+	}
+	if b.SonarrServer != nil {
+		names = append(names, "Sonarr")
+		results["Sonarr"] = diagnoseSonarr(b.SonarrServer)
+	}
+	return names, results
+}
+
+// runDiagnostics checks all configured servers and returns a report per server.
+func (b *Bot) runDiagnostics() ([]string, bool) {
+	var reports []string
+	ok := true
+	names, results := b.diagnoseAll()
+	for _, name := range names {
+		ok = ok && diagnosticsOK(results[name])
+		reports = append(reports, formatDiagnostics(name, results[name]))
+	}
+	return reports, ok
+}
+
+// CheckConnections runs the diagnostics on startup and logs the results.
+// It reports whether all checks passed.
+func (b *Bot) CheckConnections() bool {
+	ok := true
+	names, results := b.diagnoseAll()
+	for _, name := range names {
+		for _, diagnostic := range results[name] {
+			level := slog.LevelInfo
+			if !diagnostic.ok {
+				level = slog.LevelWarn
+				ok = false
+			}
+			slog.Log(context.Background(), level, "Startup check", "server", name, "check", diagnostic.check, "ok", diagnostic.ok, "result", diagnostic.message)
+		}
+	}
+	return ok
+}
+
+// WaitForConnections repeats the startup check with an increasing delay until
+// all checks pass.
+func (b *Bot) WaitForConnections() {
+	b.waiting.Store(true)
+	defer func() {
+		// the poll age counts from the end of the wait
+		b.Polled()
+		b.waiting.Store(false)
+	}()
+	delay := 5 * time.Second
+	for !b.CheckConnections() {
+		slog.Warn("Startup check failed, retrying", "delay", delay)
+		time.Sleep(delay)
+		delay = min(delay*2, 5*time.Minute)
+	}
+}
+
+func (b *Bot) sendDiagnostics(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "Running diagnostics... please wait")
+	message, _ := b.sendMessage(msg)
+
+	reports, _ := b.runDiagnostics()
+	editMsg := tgbotapi.NewEditMessageText(chatID, message.MessageID, strings.Join(reports, "\n\n"))
+	b.sendMessage(editMsg)
+}
@@ -0,0 +1,498 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+const (
+	DigestSchedule   = "DIGEST_SCHEDULE"
+	DigestHour       = "DIGEST_HOUR"
+	DigestHourDown   = "DIGEST_HOUR_DOWN"
+	DigestHourUp     = "DIGEST_HOUR_UP"
+	DigestWeekday    = "DIGEST_WEEKDAY"
+	DigestSection    = "DIGEST_SECTION_"
+	DigestSendNow    = "DIGEST_SEND_NOW"
+	DigestDone       = "DIGEST_DONE"
+	digestStoreKey   = "digests"
+	digestMaxEntries = 15 // per list, so a digest after a long break stays readable
+)
+
+// Digest schedules.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Digest sections.
+const (
+	DigestAdded     = "added"
+	DigestUpcoming  = "upcoming"
+	DigestFailed    = "failed"
+	DigestDiskSpace = "disk"
+	DigestHealth    = "health"
+)
+
+var digestSections = []string{DigestAdded, DigestUpcoming, DigestFailed, DigestDiskSpace, DigestHealth}
+
+var digestSectionNames = map[string]string{
+	DigestAdded:     "Added and imported",
+	DigestUpcoming:  "Upcoming releases",
+	DigestFailed:    "Failed downloads",
+	DigestDiskSpace: "Disk space",
+	DigestHealth:    "Health warnings",
+}
+
+// digestSettings are the digest subscription of a chat, they are saved in
+// the data file.
+type digestSettings struct {
+	Schedule string          `json:"schedule"`
+	Hour     int             `json:"hour"`
+	Weekday  time.Weekday    `json:"weekday"`
+	Sections map[string]bool `json:"sections"`
+	LastSent time.Time       `json:"lastSent"`
+	// LastTried is when the last digest was sent, even an incomplete one.
+	// Only complete digests move LastSent, the next one covers the rest.
+	LastTried time.Time `json:"lastTried,omitempty"`
+}
+
+type userDigest struct {
+	chatID    int64
+	messageID int
+}
+
+func (c *userDigest) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *userDigest) GetMessageID() int {
+	return c.messageID
+}
+
+func defaultDigestSettings() *digestSettings {
+	settings := &digestSettings{Schedule: DigestOff, Hour: 8, Weekday: time.Monday, Sections: make(map[string]bool)}
+	for _, section := range digestSections {
+		settings.Sections[section] = true
+	}
+	return settings
+}
+
+// next returns when the digest after the last one is due.
+func (s *digestSettings) next() time.Time {
+	last := s.LastSent.Local()
+	if s.LastTried.After(s.LastSent) {
+		last = s.LastTried.Local()
+	}
+	next := time.Date(last.Year(), last.Month(), last.Day(), s.Hour, 0, 0, 0, time.Local)
+	days := 1
+	if s.Schedule == DigestWeekly {
+		days = 7
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(last) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next
+}
+
+func (s *digestSettings) describe() string {
+	switch s.Schedule {
+	case DigestDaily:
+		return fmt.Sprintf("daily at %02d:00", s.Hour)
+	case DigestWeekly:
+		return fmt.Sprintf("every %v at %02d:00", s.Weekday, s.Hour)
+	}
+	return "off"
+}
+
+func (b *Bot) loadDigests() {
+	if err := b.Store.Load(digestStoreKey, &b.Digests); err != nil {
+		slog.Error("Cannot load digests", "error", err)
+	}
+	if b.Digests == nil {
+		b.Digests = make(map[int64]*digestSettings)
+	}
+}
+
+// getDigestSettings returns a copy of the settings of the chat.
+func (b *Bot) getDigestSettings(chatID int64) digestSettings {
+	b.muDigests.Lock()
+	defer b.muDigests.Unlock()
+	return b.digestSettingsCopy(chatID)
+}
+
+// digestSettingsCopy returns a copy of the settings, the caller holds
+// muDigests.
+func (b *Bot) digestSettingsCopy(chatID int64) digestSettings {
+	settings, exists := b.Digests[chatID]
+	if !exists {
+		settings = defaultDigestSettings()
+	}
+	copied := *settings
+	copied.Sections = make(map[string]bool, len(settings.Sections))
+	for section, enabled := range settings.Sections {
+		copied.Sections[section] = enabled
+	}
+	return copied
+}
+
+// startDigest marks the digest of the chat as being sent and returns its
+// settings, false if it's already being sent. finishDigest ends it.
+func (b *Bot) startDigest(chatID int64) (digestSettings, bool) {
+	b.muDigests.Lock()
+	defer b.muDigests.Unlock()
+	if b.digestsSending[chatID] {
+		return digestSettings{}, false
+	}
+	b.digestsSending[chatID] = true
+	return b.digestSettingsCopy(chatID), true
+}
+
+// finishDigest saves when the digest was sent. Only the send times are
+// written, the settings may have been changed in /digest meanwhile.
+func (b *Bot) finishDigest(chatID int64, sent time.Time, complete bool) {
+	b.muDigests.Lock()
+	defer b.muDigests.Unlock()
+	delete(b.digestsSending, chatID)
+	settings, exists := b.Digests[chatID]
+	if !exists {
+		settings = defaultDigestSettings()
+		b.Digests[chatID] = settings
+	}
+	settings.LastTried = sent
+	if complete {
+		settings.LastSent = sent
+	}
+	if err := b.Store.Save(digestStoreKey, b.Digests); err != nil {
+		chatLogger(chatID).Error("Cannot save digests", "error", err)
+	}
+}
+
+// setDigestSettings saves the settings of the chat. The send times of a
+// digest sent meanwhile are kept.
+func (b *Bot) setDigestSettings(chatID int64, settings digestSettings) {
+	b.muDigests.Lock()
+	defer b.muDigests.Unlock()
+	current, exists := b.Digests[chatID]
+	switch {
+	case settings.Schedule != DigestOff && (!exists || current.Schedule == DigestOff):
+		// the first digest covers the time since it was turned on, not
+		// the time it was off
+		settings.LastSent = time.Now()
+		settings.LastTried = time.Time{}
+	case exists:
+		settings.LastSent = current.LastSent
+		settings.LastTried = current.LastTried
+	}
+	b.Digests[chatID] = &settings
+	if err := b.Store.Save(digestStoreKey, b.Digests); err != nil {
+		b.logger(chatID).Error("Cannot save digests", "error", err)
+	}
+}
+
+func (b *Bot) processDigestCommand(chatID int64) {
+	msg := tgbotapi.NewMessage(chatID, "Handling digest command... please wait")
+	message, _ := b.sendMessage(msg)
+	command := userDigest{
+		chatID:    message.Chat.ID,
+		messageID: message.MessageID,
+	}
+	b.setDigestState(command.chatID, &command)
+	b.showDigestSettings(&command)
+}
+
+func (b *Bot) digest(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot manage digest", "error", err)
+		return false
+	}
+	command, exists := b.getDigestState(chatID)
+	if !exists {
+		return false
+	}
+	settings := b.getDigestSettings(chatID)
+
+	data := update.CallbackQuery.Data
+	switch {
+	case data == DigestSchedule:
+		switch settings.Schedule {
+		case DigestOff:
+			settings.Schedule = DigestDaily
+		case DigestDaily:
+			settings.Schedule = DigestWeekly
+		default:
+			settings.Schedule = DigestOff
+		}
+	case data == DigestHourDown:
+		settings.Hour = (settings.Hour + 23) % 24
+	case data == DigestHourUp:
+		settings.Hour = (settings.Hour + 1) % 24
+	case data == DigestWeekday:
+		settings.Weekday = (settings.Weekday + 1) % 7
+	case strings.HasPrefix(data, DigestSection):
+		section := strings.TrimPrefix(data, DigestSection)
+		settings.Sections[section] = !settings.Sections[section]
+	case data == DigestSendNow:
+		settings, started := b.startDigest(chatID)
+		if !started {
+			b.clearState(update)
+			b.sendMessageWithEdit(command, "The digest is being sent right now")
+			return false
+		}
+		b.sendMessageWithEdit(command, "Sending digest... please wait")
+		sent, complete := b.sendDigest(b.logger(chatID), chatID, settings)
+		b.finishDigest(chatID, sent, complete)
+		b.clearState(update)
+		b.sendMessageWithEdit(command, "Digest sent, next one "+settings.describe())
+		return false
+	case data == DigestDone:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, "Digest "+settings.describe())
+		return false
+	}
+	b.setDigestSettings(chatID, settings)
+	return b.showDigestSettings(command)
+}
+
+func (b *Bot) showDigestSettings(command *userDigest) bool {
+	settings := b.getDigestSettings(command.chatID)
+
+	text := "Digest: " + settings.describe()
+	if settings.Schedule != DigestOff {
+		text += "\nNext digest: " + settings.next().Format("Mon 02 Jan 2006 15:04")
+	}
+	zone, _ := time.Now().Zone()
+	text += "\nTimes are in the bot's time zone, " + zone
+
+	var buttonLabels, buttonData []string
+	buttonLabels = append(buttonLabels, "Schedule: "+settings.Schedule)
+	buttonData = append(buttonData, DigestSchedule)
+	if settings.Schedule == DigestWeekly {
+		buttonLabels = append(buttonLabels, "Day: "+settings.Weekday.String())
+		buttonData = append(buttonData, DigestWeekday)
+	}
+	for _, section := range digestSections {
+		icon := UnmonitorIcon
+		if settings.Sections[section] {
+			icon = MonitorIcon
+		}
+		buttonLabels = append(buttonLabels, icon+" "+digestSectionNames[section])
+		buttonData = append(buttonData, DigestSection+section)
+	}
+	buttonLabels = append(buttonLabels, "Send now", "Done")
+	buttonData = append(buttonData, DigestSendNow, DigestDone)
+
+	keyboard := b.createKeyboard(buttonLabels, buttonData)
+	hourRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("-1h", DigestHourDown),
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%02d:00", settings.Hour), DigestHour),
+		tgbotapi.NewInlineKeyboardButtonData("+1h", DigestHourUp),
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard[:1], append([][]tgbotapi.InlineKeyboardButton{hourRow}, keyboard.InlineKeyboard[1:]...)...)
+
+	b.setActiveCommand(command.chatID, DigestCommand)
+	b.setDigestState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text)
+	return false
+}
+
+// RunDigests sends the digests when they are due.
+func (b *Bot) RunDigests() {
+	for {
+		b.sendDueDigests()
+		time.Sleep(time.Minute)
+	}
+}
+
+func (b *Bot) sendDueDigests() {
+	b.muDigests.Lock()
+	var due []int64
+	for chatID, settings := range b.Digests {
+		if settings.Schedule != DigestOff && !time.Now().Before(settings.next()) {
+			due = append(due, chatID)
+		}
+	}
+	b.muDigests.Unlock()
+
+	for _, chatID := range due {
+		// a reload may have removed the chat, its settings stay until it's allowed again
+		if !b.isAllowed(chatID) {
+			continue
+		}
+		settings, started := b.startDigest(chatID)
+		if !started {
+			// sent with "Send now" right now
+			continue
+		}
+		sent, complete := b.sendDigest(chatLogger(chatID), chatID, settings)
+		b.finishDigest(chatID, sent, complete)
+	}
+}
+
+// sendDigest sends the sections of the digest since the last one. It
+// returns when the digest started and whether the library and history of
+// all instances could be read, otherwise their entries are sent next time.
+func (b *Bot) sendDigest(logger *slog.Logger, chatID int64, settings digestSettings) (time.Time, bool) {
+	started := time.Now()
+	since := settings.LastSent
+	if since.IsZero() {
+		since = started.AddDate(0, 0, -1)
+	}
+	sections := []string{fmt.Sprintf("*Digest since %s*", utils.Escape(since.Local().Format("Mon 02 Jan 15:04")))}
+	complete := true
+	for _, instance := range b.RadarrServers {
+		if len(b.RadarrServers) > 1 {
+			sections = append(sections, "*"+utils.Escape(instance.Name)+"*")
+		}
+		instanceSections, ok := instanceDigestSections(logger, instance.Server, settings, since)
+		sections = append(sections, instanceSections...)
+		complete = complete && ok
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "")
+	b.sendStats(sections, &msg)
+	return started, complete
+}
+
+// instanceDigestSections returns the sections of the instance, false if the
+// library or history couldn't be read.
+func instanceDigestSections(logger *slog.Logger, r *radarr.Radarr, settings digestSettings, since time.Time) ([]string, bool) {
+	var sections []string
+
+	if settings.Sections[DigestAdded] || settings.Sections[DigestFailed] {
+		movies, err := r.GetMovie(0)
+		if err != nil {
+			return append(sections, digestError(logger, "Library", err)), false
+		}
+		history, err := radarrapi.GetHistorySince(r, since)
+		if err != nil {
+			return append(sections, digestError(logger, "History", err)), false
+		}
+		titles := make(map[int64]string, len(movies))
+		var added []string
+		for _, movie := range movies {
+			titles[movie.ID] = fmt.Sprintf("%v (%v)", movie.Title, movie.Year)
+			if movie.Added.After(since) {
+				added = append(added, titles[movie.ID])
+			}
+		}
+		var imported, failed []string
+		for _, record := range history {
+			switch record.EventType {
+			case "downloadFolderImported":
+				imported = append(imported, titles[record.MovieID])
+			case "downloadFailed":
+				failed = append(failed, fmt.Sprintf("%s: %s", record.SourceTitle, record.Data.Message))
+			}
+		}
+		if settings.Sections[DigestAdded] {
+			sections = append(sections, digestList("Added", added), digestList("Imported", imported))
+		}
+		if settings.Sections[DigestFailed] {
+			sections = append(sections, digestList("Failed downloads", failed))
+		}
+	}
+
+	if settings.Sections[DigestUpcoming] {
+		upcoming, err := r.GetCalendar(radarr.Calendar{
+			Start:       time.Now(),
+			End:         time.Now().AddDate(0, 0, 7),
+			Unmonitored: *starr.True(),
+		})
+		if err != nil {
+			sections = append(sections, digestError(logger, "Upcoming", err))
+		} else {
+			sections = append(sections, digestList("Upcoming in the next 7 days", upcomingReleases(upcoming, time.Now(), time.Now().AddDate(0, 0, 7))))
+		}
+	}
+
+	if settings.Sections[DigestDiskSpace] {
+		rootFolders, err := r.GetRootFolders()
+		if err != nil {
+			sections = append(sections, digestError(logger, "Disk space", err))
+		} else {
+			sections = append(sections, "*Disk space*\n"+utils.PrepareRootFolders(rootFolders))
+		}
+	}
+
+	if settings.Sections[DigestHealth] {
+		health, err := radarrapi.GetHealth(r)
+		if err != nil {
+			sections = append(sections, digestError(logger, "Health", err))
+		} else {
+			var issues []string
+			for _, issue := range healthIssues(health) {
+				issues = append(issues, fmt.Sprintf("%s: %s", issue.Type, issue.Message))
+			}
+			sections = append(sections, digestList("Health warnings", issues))
+		}
+	}
+	return sections, true
+}
+
+// upcomingReleases lists the cinema, digital and physical releases within
+// the time range, by date.
+func upcomingReleases(movies []*radarr.Movie, start, end time.Time) []string {
+	type release struct {
+		date time.Time
+		text string
+	}
+	var releases []release
+	for _, movie := range movies {
+		for kind, date := range map[string]time.Time{
+			"cinema":   movie.InCinemas,
+			"digital":  movie.DigitalRelease,
+			"physical": movie.PhysicalRelease,
+		} {
+			if !date.Before(start) && date.Before(end) {
+				releases = append(releases, release{date, fmt.Sprintf("%s %v %s", date.Local().Format("Mon 02 Jan"), movie.Title, kind)})
+			}
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		if releases[i].date.Equal(releases[j].date) {
+			return releases[i].text < releases[j].text
+		}
+		return releases[i].date.Before(releases[j].date)
+	})
+	lines := make([]string, len(releases))
+	for i, release := range releases {
+		lines[i] = release.text
+	}
+	return lines
+}
+
+// digestList formats a section with a title and the escaped entries.
+func digestList(title string, entries []string) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*", utils.Escape(title))
+	if len(entries) == 0 {
+		text.WriteString("\nnone")
+		return text.String()
+	}
+	for i, entry := range entries {
+		if i == digestMaxEntries {
+			fmt.Fprintf(&text, "\n\\.\\.\\. and %d more", len(entries)-i)
+			break
+		}
+		text.WriteString("\n\\- " + utils.Escape(entry))
+	}
+	return text.String()
+}
+
+func digestError(logger *slog.Logger, title string, err error) string {
+	logRequestError(logger, err)
+	return fmt.Sprintf("*%s*\n%s", utils.Escape(title), utils.Escape(friendlyError(err)))
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+const (
+	diskAlertsStoreKey = "disk_alerts"
+	// diskHysteresis is how far above the threshold the free space has to
+	// rise before an alert is cleared, so it doesn't flap.
+	diskHysteresis = 0.1
+	// the alert lists the largest movies added within diskRecentDays
+	diskRecentDays    = 30
+	diskLargestMovies = 5
+)
+
+func (b *Bot) loadDiskAlerts() {
+	if err := b.Store.Load(diskAlertsStoreKey, &b.DiskAlerts); err != nil {
+		slog.Error("Cannot load disk alerts", "error", err)
+	}
+	if b.DiskAlerts == nil {
+		b.DiskAlerts = make(map[string]bool)
+	}
+}
+
+// RunDiskMonitor checks the free space of the root folders every
+// RBOT_DISK_CHECK_INTERVAL.
+func (b *Bot) RunDiskMonitor() {
+	for {
+		cfg := b.config()
+		if cfg.DiskMinFree > 0 || cfg.DiskMinFreePct > 0 {
+			for _, instance := range b.RadarrServers {
+				b.checkDiskSpace(instance)
+			}
+		}
+		time.Sleep(cfg.DiskInterval)
+	}
+}
+
+// checkDiskSpace alerts when a root folder drops below the threshold and
+// when it recovered. Failed requests keep the previous state.
+func (b *Bot) checkDiskSpace(instance *RadarrInstance) {
+	logger := slog.With("instance", instance.Name)
+	rootFolders, err := instance.Server.GetRootFolders()
+	if err != nil {
+		logger.Warn("Cannot check disk space", "error", err)
+		return
+	}
+	disks, err := radarrapi.GetDiskSpace(instance.Server)
+	if err != nil {
+		// without the disk sizes, only absolute thresholds work
+		logger.Warn("Cannot get disk sizes", "error", err)
+	}
+
+	for _, rootFolder := range rootFolders {
+		threshold, total := b.diskThreshold(rootFolder.Path, disks)
+		if threshold == 0 {
+			continue
+		}
+		key := instance.Name + ":" + rootFolder.Path
+		b.muDiskAlerts.Lock()
+		alerted := b.DiskAlerts[key]
+		low := rootFolder.FreeSpace < threshold
+		recovered := float64(rootFolder.FreeSpace) >= float64(threshold)*(1+diskHysteresis)
+		changed := (!alerted && low) || (alerted && recovered)
+		if changed {
+			b.DiskAlerts[key] = low
+			if err := b.Store.Save(diskAlertsStoreKey, b.DiskAlerts); err != nil {
+				logger.Error("Cannot save disk alerts", "error", err)
+			}
+		}
+		b.muDiskAlerts.Unlock()
+		if !changed {
+			continue
+		}
+
+		logger.Info("Disk space changed", "path", rootFolder.Path, "free", rootFolder.FreeSpace, "threshold", threshold, "low", low)
+		var text string
+		if low {
+			text = b.lowDiskSpaceAlert(instance, rootFolder, threshold, total)
+		} else {
+			text = fmt.Sprintf("%sDisk space recovered on %s: %s free", b.instanceLabel(instance), rootFolder.Path, utils.ByteCountSI(rootFolder.FreeSpace))
+		}
+		for chatID := range b.config().DiskAlertChats() {
+			b.sendMessage(tgbotapi.NewMessage(chatID, text))
+		}
+	}
+}
+
+// diskThreshold returns the threshold of the root folder in bytes and the
+// size of its disk, 0 if unknown. A percentage needs the disk size.
+func (b *Bot) diskThreshold(path string, disks []*radarrapi.DiskSpace) (int64, int64) {
+	// the disk of the root folder is the one with the longest matching path
+	var disk *radarrapi.DiskSpace
+	for _, candidate := range disks {
+		if isSubPath(path, candidate.Path) && (disk == nil || len(candidate.Path) > len(disk.Path)) {
+			disk = candidate
+		}
+	}
+	var total int64
+	if disk != nil {
+		total = disk.TotalSpace
+	}
+	cfg := b.config()
+	if cfg.DiskMinFreePct > 0 {
+		return int64(float64(total) * cfg.DiskMinFreePct / 100), total
+	}
+	return cfg.DiskMinFree, total
+}
+
+func (b *Bot) lowDiskSpaceAlert(instance *RadarrInstance, rootFolder *radarr.RootFolder, threshold, total int64) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "⚠️ %sLow disk space on %s\n", b.instanceLabel(instance), rootFolder.Path)
+	fmt.Fprintf(&text, "%s free", utils.ByteCountSI(rootFolder.FreeSpace))
+	if total > 0 {
+		fmt.Fprintf(&text, " of %s (%.1f%%)", utils.ByteCountSI(total), float64(rootFolder.FreeSpace)*100/float64(total))
+	}
+	fmt.Fprintf(&text, ", alert below %s\n", utils.ByteCountSI(threshold))
+
+	movies, err := instance.Server.GetMovie(0)
+	if err != nil {
+		slog.Warn("Cannot list the largest additions", "instance", instance.Name, "error", err)
+		return text.String()
+	}
+	largest := largestRecentMovies(movies, rootFolder.Path, time.Now().AddDate(0, 0, -diskRecentDays))
+	if len(largest) == 0 {
+		fmt.Fprintf(&text, "\nNo movies were added to it in the last %d days.", diskRecentDays)
+		return text.String()
+	}
+	fmt.Fprintf(&text, "\nLargest additions of the last %d days:", diskRecentDays)
+	for _, movie := range largest {
+		fmt.Fprintf(&text, "\n- %v (%v): %s, added %s", movie.Title, movie.Year, utils.ByteCountSI(movie.SizeOnDisk), movie.Added.Local().Format("02 Jan"))
+	}
+	return text.String()
+}
+
+// largestRecentMovies returns the largest movies of the root folder which
+// were added since the date.
+func largestRecentMovies(movies []*radarr.Movie, rootFolder string, since time.Time) []*radarr.Movie {
+	var recent []*radarr.Movie
+	for _, movie := range movies {
+		if isInRootFolder(movie, rootFolder) && movie.SizeOnDisk > 0 && movie.Added.After(since) {
+			recent = append(recent, movie)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].SizeOnDisk > recent[j].SizeOnDisk
+	})
+	if len(recent) > diskLargestMovies {
+		recent = recent[:diskLargestMovies]
+	}
+	return recent
+}
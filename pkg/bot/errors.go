@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr"
+)
+
+const (
+	ErrorRetry  = "ERROR_RETRY"
+	ErrorCancel = "ERROR_CANCEL"
+)
+
+// errorRetry repeats the action which failed in the menu message.
+type errorRetry struct {
+	messageID int
+	retry     func() bool
+}
+
+// statusMessage is a message which isn't part of a command state yet, e.g.
+// the "please wait" message of a command.
+type statusMessage struct {
+	chatID    int64
+	messageID int
+}
+
+func (c *statusMessage) GetChatID() int64 {
+	return c.chatID
+}
+
+func (c *statusMessage) GetMessageID() int {
+	return c.messageID
+}
+
+// friendlyError explains an error of Radarr or Sonarr, so the user knows
+// what went wrong and what to do about it. Other errors are logged and get
+// a generic message.
+func friendlyError(err error) string {
+	var reqErr *starr.ReqError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &reqErr) && (reqErr.Code == http.StatusUnauthorized || reqErr.Code == http.StatusForbidden):
+		return "The API key was rejected. Check the API key in Settings > General."
+	case errors.As(err, &reqErr) && reqErr.Code == http.StatusNotFound:
+		return "Not found. It may have been deleted in the meantime, or the base URL is wrong."
+	case errors.As(err, &reqErr) && reqErr.Code >= http.StatusInternalServerError:
+		return fmt.Sprintf("The server had an internal error (HTTP %d). Check its logs.", reqErr.Code)
+	case errors.As(err, &reqErr):
+		if messages := validationMessages(reqErr); len(messages) > 0 {
+			return "The request was refused: " + strings.Join(messages, ", ")
+		}
+		return fmt.Sprintf("The request was refused (HTTP %d).", reqErr.Code)
+	case errors.As(err, &dnsErr):
+		return "The hostname " + dnsErr.Name + " can't be resolved."
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "The server didn't answer in time. It may be busy or unreachable."
+	case errors.As(err, &syntaxErr):
+		return "The answer isn't from the API. Check the base URL."
+	case strings.Contains(err.Error(), "connection refused"):
+		return "The connection was refused. Is the server running, and are hostname and port right?"
+	}
+	// the raw error may show URLs and internals, it's only logged
+	slog.Warn("Unexplained error", "error", err)
+	return "Something went wrong, the details are in the bot's log."
+}
+
+// validationMessages returns the messages of a validation failure, like
+// "This movie has already been added".
+func validationMessages(reqErr *starr.ReqError) []string {
+	var failures []struct {
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(reqErr.Body, &failures); err != nil {
+		if reqErr.Msg != "" {
+			return []string{reqErr.Msg}
+		}
+		return nil
+	}
+	var messages []string
+	for _, failure := range failures {
+		if failure.ErrorMessage != "" {
+			messages = append(messages, failure.ErrorMessage)
+		}
+	}
+	return messages
+}
+
+// logError logs the error with the response body of the server, if any.
+func (b *Bot) logError(chatID int64, err error) {
+	logRequestError(b.logger(chatID), err)
+}
+
+func logRequestError(logger *slog.Logger, err error) {
+	var reqErr *starr.ReqError
+	if errors.As(err, &reqErr) && len(reqErr.Body) > 0 {
+		logger.Error("Request failed", "error", err, "status", reqErr.Code, "response", string(reqErr.Body))
+		return
+	}
+	logger.Error("Request failed", "error", err)
+}
+
+// reportError edits the menu message of the command with an explanation of
+// the error and Retry and Cancel buttons. The command state is kept, so
+// retry can repeat the failed action.
+func (b *Bot) reportError(command Command, err error, retry func() bool) bool {
+	chatID := command.GetChatID()
+	b.logError(chatID, err)
+
+	b.muErrorRetries.Lock()
+	b.ErrorRetries[chatID] = &errorRetry{messageID: command.GetMessageID(), retry: retry}
+	b.muErrorRetries.Unlock()
+
+	keyboard := b.createKeyboard(
+		[]string{"Retry", "Cancel - clear command"},
+		[]string{ErrorRetry, ErrorCancel},
+	)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, friendlyError(err))
+	return false
+}
+
+// sendError sends an explanation of the error as new message, for commands
+// without a menu.
+func (b *Bot) sendError(chatID int64, prefix string, err error) {
+	b.logError(chatID, err)
+	msg := tgbotapi.NewMessage(chatID, prefix+friendlyError(err))
+	b.sendMessage(msg)
+}
+
+func (b *Bot) handleErrorCallback(update tgbotapi.Update) {
+	chatID := update.CallbackQuery.Message.Chat.ID
+	messageID := update.CallbackQuery.Message.MessageID
+	command := &statusMessage{chatID: chatID, messageID: messageID}
+
+	b.muErrorRetries.Lock()
+	retry, exists := b.ErrorRetries[chatID]
+	delete(b.ErrorRetries, chatID)
+	b.muErrorRetries.Unlock()
+
+	if update.CallbackQuery.Data == ErrorCancel {
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return
+	}
+	// the buttons of older errors can't be retried
+	if !exists || retry.messageID != messageID {
+		b.sendMessageWithEdit(command, "This action can't be retried anymore, please start over")
+		return
+	}
+	b.sendMessageWithEdit(command, "Retrying... please wait")
+	retry.retry()
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+)
+
+func (b *Bot) processFindCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
+	query := update.Message.CommandArguments()
+	if query == "" {
+		b.clearState(update)
+		msg := tgbotapi.NewMessage(chatID, FindQuerySyntax)
+		b.sendMessage(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Handling find command... please wait")
+	message, _ := b.sendMessage(msg)
+
+	command, err := loadLibrary(r, message)
+	if err != nil {
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.processFindCommand(update, chatID, r)
+			return false
+		})
+		return
+	}
+
+	parsedQuery, err := parseFindQuery(query, command.allTags, command.qualityProfiles)
+	if err != nil {
+		b.clearState(update)
+		b.sendMessageWithEdit(command, fmt.Sprintf("Invalid query: %v\n\n%s", err, FindQuerySyntax))
+		return
+	}
+
+	command.searchCriteria = query
+	command.searchResultsInLibrary = filterMovies(command.library, parsedQuery.matches)
+	command.advancedFilter = libraryFilter{
+		sortBy:         parsedQuery.sortBy,
+		sortDescending: parsedQuery.sortDescending,
+	}
+	command.filter = FilterFindResults
+
+	b.setLibraryState(chatID, command)
+	b.setActiveCommand(chatID, LibraryFilteredCommand)
+	b.showLibraryMenuFiltered(command)
+}
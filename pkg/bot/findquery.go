@@ -0,0 +1,438 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+// FindQuerySyntax explains the /find query language.
+const FindQuerySyntax = `Usage: /find <terms>, all terms must match
+Fields: title, year, size, rating, runtime, added, quality, codec, profile, tag, genre, status, studio, path
+Operators: field:value (contains), field=value, field!=value, and < > <= >= for numbers and dates
+Flags: monitored, available, missing, ondisk, cutoffunmet
+Prefix a term with - to negate it, quote values with spaces: tag:"my tag"
+Sizes: size>20GB (B, KB, MB, GB, TB, default GB)
+Dates: added>2024-01-01 or by age: added<30d (d days, w weeks, m months of 30 days, y years of 365 days)
+Sorting: sort:size (ascending) or sort:-size (descending) by title, year, added, size, rating
+Words without a field search the title
+Example: /find quality:720p year<2000 tag:kids -monitored size>20GB sort:size`
+
+var findTermPattern = regexp.MustCompile(`^([a-zA-Z]+)(<=|>=|!=|:|=|<|>)(.*)$`)
+
+// findFields are the fields parseFindField knows, plus sort. Other terms
+// which look like field:value, e.g. Mission:Impossible, search the title.
+var findFields = map[string]bool{
+	"year": true, "rating": true, "runtime": true, "size": true, "added": true,
+	"title": true, "quality": true, "codec": true, "genre": true, "studio": true,
+	"path": true, "status": true, "tag": true, "profile": true, "sort": true,
+}
+
+type moviePredicate func(movie *radarr.Movie) bool
+
+// findQuery is a parsed /find expression.
+type findQuery struct {
+	predicates     []moviePredicate
+	sortBy         string
+	sortDescending bool
+}
+
+func (q *findQuery) matches(movie *radarr.Movie) bool {
+	for _, predicate := range q.predicates {
+		if !predicate(movie) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFindQuery parses the query into predicates. Tags and quality profiles
+// are needed to resolve their names.
+func parseFindQuery(query string, tags []*starr.Tag, qualityProfiles []*radarr.QualityProfile) (*findQuery, error) {
+	terms, err := splitFindTerms(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("the query is empty")
+	}
+
+	parsed := &findQuery{}
+	for _, term := range terms {
+		negate := false
+		raw := term
+		if len(term) > 1 && strings.HasPrefix(term, "-") {
+			negate = true
+			term = term[1:]
+		}
+
+		var predicate moviePredicate
+		if matches := findTermPattern.FindStringSubmatch(term); matches != nil && findFields[strings.ToLower(matches[1])] {
+			field, operator, value := strings.ToLower(matches[1]), matches[2], matches[3]
+			if value == "" {
+				return nil, fmt.Errorf("%q: missing value after %q", raw, field+operator)
+			}
+			if field == "sort" {
+				if negate || (operator != ":" && operator != "=") {
+					return nil, fmt.Errorf("%q: use sort:field or sort:-field", raw)
+				}
+				if err := parsed.parseSort(value); err != nil {
+					return nil, fmt.Errorf("%q: %w", raw, err)
+				}
+				continue
+			}
+			predicate, err = parseFindField(field, operator, value, tags, qualityProfiles)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", raw, err)
+			}
+		} else if strings.EqualFold(term, "cutoffunmet") {
+			// the custom format cutoff is part of the quality profile
+			predicate = cutoffUnmet(qualityProfiles)
+		} else if flag, exists := findFlags[strings.ToLower(term)]; exists {
+			predicate = flag
+		} else {
+			predicate = titleContains(term)
+		}
+
+		if negate {
+			predicate = not(predicate)
+		}
+		parsed.predicates = append(parsed.predicates, predicate)
+	}
+	return parsed, nil
+}
+
+func (q *findQuery) parseSort(value string) error {
+	if strings.HasPrefix(value, "-") {
+		q.sortDescending = true
+		value = value[1:]
+	}
+	value = strings.ToLower(value)
+	if _, exists := sortLabels[value]; !exists {
+		return fmt.Errorf("cannot sort by %q, use title, year, added, size or rating", value)
+	}
+	q.sortBy = value
+	return nil
+}
+
+// splitFindTerms splits the query at white space, double quotes group words.
+func splitFindTerms(query string) ([]string, error) {
+	var terms []string
+	var term strings.Builder
+	inQuotes := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("missing closing quote in %q", query)
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
+
+var findFlags = map[string]moviePredicate{
+	"monitored": func(movie *radarr.Movie) bool { return movie.Monitored },
+	"available": func(movie *radarr.Movie) bool { return movie.IsAvailable },
+	"missing":   func(movie *radarr.Movie) bool { return movie.Monitored && !movie.HasFile },
+	"ondisk":    func(movie *radarr.Movie) bool { return movie.HasFile },
+	"hasfile":   func(movie *radarr.Movie) bool { return movie.HasFile },
+}
+
+func parseFindField(field, operator, value string, tags []*starr.Tag, qualityProfiles []*radarr.QualityProfile) (moviePredicate, error) {
+	switch field {
+	case "year":
+		return numberPredicate(operator, value, func(movie *radarr.Movie) float64 { return float64(movie.Year) })
+	case "rating":
+		return numberPredicate(operator, value, movieRating)
+	case "runtime":
+		return numberPredicate(operator, value, func(movie *radarr.Movie) float64 { return float64(movie.Runtime) })
+	case "size":
+		size, err := utils.ParseSize(value)
+		if err != nil {
+			return nil, err
+		}
+		return numberPredicate(operator, strconv.FormatInt(size, 10), func(movie *radarr.Movie) float64 { return float64(movie.SizeOnDisk) })
+	case "added":
+		return datePredicate(operator, value, func(movie *radarr.Movie) time.Time { return movie.Added })
+	case "title":
+		if operator == ":" {
+			return titleContains(value), nil
+		}
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string { return []string{movie.Title} })
+	case "quality":
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string {
+			if movie.MovieFile == nil || movie.MovieFile.Quality == nil || movie.MovieFile.Quality.Quality == nil {
+				return nil
+			}
+			return []string{movie.MovieFile.Quality.Quality.Name}
+		})
+	case "codec":
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string {
+			if movie.MovieFile == nil || movie.MovieFile.MediaInfo == nil {
+				return nil
+			}
+			return []string{movie.MovieFile.MediaInfo.VideoCodec, movie.MovieFile.MediaInfo.AudioCodec}
+		})
+	case "genre":
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string { return movie.Genres })
+	case "studio":
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string { return []string{movie.Studio} })
+	case "path":
+		return textPredicate(field, operator, value, func(movie *radarr.Movie) []string { return []string{movie.Path} })
+	case "status":
+		status, exists := findStatuses[strings.ToLower(value)]
+		if !exists {
+			return nil, fmt.Errorf("unknown status %q, use announced, incinemas or released", value)
+		}
+		return textPredicate(field, operator, status, func(movie *radarr.Movie) []string { return []string{movie.Status} })
+	case "tag":
+		tag := findTagByLabel(tags, value)
+		if tag == nil {
+			return nil, fmt.Errorf("unknown tag %q, available tags: %s", value, strings.Join(tagLabels(tags), ", "))
+		}
+		return idPredicate(field, operator, func(movie *radarr.Movie) bool { return isSelectedTag(movie.Tags, tag.ID) })
+	case "profile":
+		profile := findQualityProfileByName(qualityProfiles, value)
+		if profile == nil {
+			return nil, fmt.Errorf("unknown quality profile %q, available profiles: %s", value, strings.Join(qualityProfileNames(qualityProfiles), ", "))
+		}
+		return idPredicate(field, operator, func(movie *radarr.Movie) bool { return movie.QualityProfileID == profile.ID })
+	default:
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+}
+
+var findStatuses = map[string]string{
+	"announced":  "announced",
+	"incinemas":  "inCinemas",
+	"in_cinemas": "inCinemas",
+	"cinemas":    "inCinemas",
+	"released":   "released",
+}
+
+func numberPredicate(operator, value string, get func(movie *radarr.Movie) float64) (moviePredicate, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", value)
+	}
+	compare, err := comparison(operator)
+	if err != nil {
+		return nil, err
+	}
+	return func(movie *radarr.Movie) bool {
+		value := get(movie)
+		switch {
+		case value < number:
+			return compare(-1)
+		case value > number:
+			return compare(1)
+		default:
+			return compare(0)
+		}
+	}, nil
+}
+
+// datePredicate compares dates, or ages if the value is relative like "30d".
+func datePredicate(operator, value string, get func(movie *radarr.Movie) time.Time) (moviePredicate, error) {
+	compare, err := comparison(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	if age, err := parseAge(value); err == nil {
+		return func(movie *radarr.Movie) bool {
+			date := get(movie)
+			if date.IsZero() {
+				return false
+			}
+			movieAge := time.Since(date)
+			switch {
+			case movieAge < age:
+				return compare(-1)
+			case movieAge > age:
+				return compare(1)
+			default:
+				return compare(0)
+			}
+		}, nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a date (2024-01-31) nor an age (30d, 6w, 3m, 1y)", value)
+	}
+	return func(movie *radarr.Movie) bool {
+		movieDate := get(movie)
+		if movieDate.IsZero() {
+			return false
+		}
+		day := time.Date(movieDate.Year(), movieDate.Month(), movieDate.Day(), 0, 0, 0, 0, time.Local)
+		switch {
+		case day.Before(date):
+			return compare(-1)
+		case day.After(date):
+			return compare(1)
+		default:
+			return compare(0)
+		}
+	}, nil
+}
+
+// comparison returns a function which checks the result of a comparison
+// (-1, 0, 1) against the operator. ":" and "=" are equality.
+func comparison(operator string) (func(result int) bool, error) {
+	switch operator {
+	case ":", "=":
+		return func(result int) bool { return result == 0 }, nil
+	case "!=":
+		return func(result int) bool { return result != 0 }, nil
+	case "<":
+		return func(result int) bool { return result < 0 }, nil
+	case "<=":
+		return func(result int) bool { return result <= 0 }, nil
+	case ">":
+		return func(result int) bool { return result > 0 }, nil
+	case ">=":
+		return func(result int) bool { return result >= 0 }, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+// textPredicate matches case insensitive, ":" checks if the text contains the value.
+func textPredicate(field, operator, value string, get func(movie *radarr.Movie) []string) (moviePredicate, error) {
+	value = strings.ToLower(value)
+	var match func(text string) bool
+	switch operator {
+	case ":":
+		match = func(text string) bool { return strings.Contains(strings.ToLower(text), value) }
+	case "=", "!=":
+		match = func(text string) bool { return strings.ToLower(text) == value }
+	default:
+		return nil, fmt.Errorf("operator %q is not supported for %s, use : = or !=", operator, field)
+	}
+	return func(movie *radarr.Movie) bool {
+		found := false
+		for _, text := range get(movie) {
+			if text != "" && match(text) {
+				found = true
+				break
+			}
+		}
+		if operator == "!=" {
+			return !found
+		}
+		return found
+	}, nil
+}
+
+func idPredicate(field, operator string, match moviePredicate) (moviePredicate, error) {
+	switch operator {
+	case ":", "=":
+		return match, nil
+	case "!=":
+		return not(match), nil
+	default:
+		return nil, fmt.Errorf("operator %q is not supported for %s, use : = or !=", operator, field)
+	}
+}
+
+func titleContains(value string) moviePredicate {
+	normalized := utils.NormalizeTitle(value)
+	return func(movie *radarr.Movie) bool {
+		if strings.Contains(utils.NormalizeTitle(movie.Title), normalized) ||
+			strings.Contains(utils.NormalizeTitle(movie.OriginalTitle), normalized) {
+			return true
+		}
+		for _, alternateTitle := range movie.AlternateTitles {
+			if strings.Contains(utils.NormalizeTitle(alternateTitle.Title), normalized) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func not(predicate moviePredicate) moviePredicate {
+	return func(movie *radarr.Movie) bool {
+		return !predicate(movie)
+	}
+}
+
+// parseAge parses relative ages like "30d", "6w", "3m" or "1y". Months are
+// 30 days and years 365 days.
+func parseAge(value string) (time.Duration, error) {
+	if len(value) < 2 {
+		return 0, fmt.Errorf("%q is not an age", value)
+	}
+	number, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an age", value)
+	}
+	day := 24 * time.Hour
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "d":
+		return time.Duration(number) * day, nil
+	case "w":
+		return time.Duration(number) * 7 * day, nil
+	case "m":
+		return time.Duration(number) * 30 * day, nil
+	case "y":
+		return time.Duration(number) * 365 * day, nil
+	default:
+		return 0, fmt.Errorf("%q is not an age", value)
+	}
+}
+
+func findTagByLabel(tags []*starr.Tag, label string) *starr.Tag {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Label, label) {
+			return tag
+		}
+	}
+	return nil
+}
+
+func findQualityProfileByName(qualityProfiles []*radarr.QualityProfile, name string) *radarr.QualityProfile {
+	for _, profile := range qualityProfiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile
+		}
+	}
+	return nil
+}
+
+func tagLabels(tags []*starr.Tag) []string {
+	labels := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		labels = append(labels, tag.Label)
+	}
+	return labels
+}
+
+func qualityProfileNames(qualityProfiles []*radarr.QualityProfile) []string {
+	names := make([]string, 0, len(qualityProfiles))
+	for _, profile := range qualityProfiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
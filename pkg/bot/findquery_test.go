@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+func TestParseFindQuery(t *testing.T) {
+	tags := []*starr.Tag{{ID: 1, Label: "kids"}, {ID: 2, Label: "my tag"}}
+	qualityProfiles := []*radarr.QualityProfile{
+		{ID: 1, Name: "HD", UpgradeAllowed: true, CutoffFormatScore: 100},
+		{ID: 2, Name: "Any"},
+	}
+	movies := []*radarr.Movie{
+		{
+			ID: 1, Title: "Mission: Impossible", Year: 1996, QualityProfileID: 1, Monitored: true, HasFile: true,
+			SizeOnDisk: 30e9, Added: time.Now().AddDate(0, 0, -10), Tags: []int{1},
+			MovieFile: &radarr.MovieFile{
+				Quality:           &starr.Quality{Quality: &starr.BaseQuality{Name: "Bluray-1080p"}},
+				CustomFormatScore: 50,
+			},
+		},
+		{
+			ID: 2, Title: "The Matrix", Year: 1999, QualityProfileID: 2, HasFile: true,
+			SizeOnDisk: 10e9, Added: time.Now().AddDate(-2, 0, 0), Tags: []int{2},
+			Ratings:   starr.OpenRatings{"imdb": {Value: 8.7}},
+			MovieFile: &radarr.MovieFile{Quality: &starr.Quality{Quality: &starr.BaseQuality{Name: "HDTV-720p"}}},
+		},
+		{
+			ID: 3, Title: "Amélie", Year: 2001, QualityProfileID: 2, Monitored: true,
+			Added: time.Now().AddDate(0, -2, 0),
+		},
+	}
+
+	tests := []struct {
+		query   string
+		want    []int64
+		wantErr string
+	}{
+		{query: "matrix", want: []int64{2}},
+		{query: "amelie", want: []int64{3}},
+		{query: "Mission:Impossible", want: []int64{1}},
+		{query: `title:"mission impossible"`, want: []int64{1}},
+		{query: "title=the matrix", want: nil},
+		{query: `title="the matrix"`, want: []int64{2}},
+		{query: "year<1999", want: []int64{1}},
+		{query: "year>=1999", want: []int64{2, 3}},
+		{query: "year=2001", want: []int64{3}},
+		{query: "size>20GB", want: []int64{1}},
+		{query: "size<=20", want: []int64{2, 3}},
+		{query: "rating>8", want: []int64{2}},
+		{query: "added<30d", want: []int64{1}},
+		{query: "added<3m", want: []int64{1, 3}},
+		{query: "added>1y", want: []int64{2}},
+		{query: "added>2000-01-01", want: []int64{1, 2, 3}},
+		{query: "quality:720p", want: []int64{2}},
+		{query: "tag:kids", want: []int64{1}},
+		{query: `tag:"my tag"`, want: []int64{2}},
+		{query: "tag!=kids", want: []int64{2, 3}},
+		{query: "profile:hd", want: []int64{1}},
+		{query: "monitored", want: []int64{1, 3}},
+		{query: "-monitored", want: []int64{2}},
+		{query: "missing", want: []int64{3}},
+		{query: "monitored ondisk", want: []int64{1}},
+		{query: "cutoffunmet", want: []int64{1}},
+		{query: "-year<2000", want: []int64{3}},
+		{query: "sort:-size", want: []int64{1, 2, 3}},
+		{query: "", wantErr: "the query is empty"},
+		{query: `tag:"kids`, wantErr: "missing closing quote"},
+		{query: "year:", wantErr: `missing value after "year:"`},
+		{query: "year>old", wantErr: `"old" is not a number`},
+		{query: "added<soon", wantErr: "is neither a date"},
+		{query: "tag:adults", wantErr: `unknown tag "adults"`},
+		{query: "profile:4k", wantErr: `unknown quality profile "4k"`},
+		{query: "quality>720p", wantErr: `operator ">" is not supported`},
+		{query: "status:leaked", wantErr: `unknown status "leaked"`},
+		{query: "sort:genre", wantErr: `cannot sort by "genre"`},
+		{query: "-sort:size", wantErr: "use sort:field or sort:-field"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			query, err := parseFindQuery(test.query, tags, qualityProfiles)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []int64
+			for _, movie := range movies {
+				if query.matches(movie) {
+					got = append(got, movie.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("matched %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFindQuerySort(t *testing.T) {
+	query, err := parseFindQuery("sort:-Size year>1990", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query.sortBy != "size" || !query.sortDescending {
+		t.Errorf("sort = %q descending %v, want size descending", query.sortBy, query.sortDescending)
+	}
+	if len(query.predicates) != 1 {
+		t.Errorf("got %d predicates, want 1", len(query.predicates))
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30d", want: 30 * day},
+		{input: "6w", want: 42 * day},
+		{input: "3M", want: 90 * day},
+		{input: "1y", want: 365 * day},
+		{input: "d", wantErr: true},
+		{input: "10h", wantErr: true},
+		{input: "xd", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseAge(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseAge(%q) error = %v, want error %v", test.input, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseAge(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Polled records a successful request for updates to Telegram.
+func (b *Bot) Polled() {
+	b.lastPoll.Store(time.Now().Unix())
+}
+
+// HealthHandler serves /healthz, it fails if Telegram wasn't polled
+// successfully within maxPollAge, e.g. because the polling loop is stuck.
+// While the startup check waits for Radarr and Sonarr it's healthy, /readyz
+// reports the connections.
+func (b *Bot) HealthHandler(maxPollAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if b.waiting.Load() {
+			fmt.Fprintln(w, "ok, waiting for Radarr and Sonarr before polling Telegram")
+			return
+		}
+		age := time.Since(time.Unix(b.lastPoll.Load(), 0)).Round(time.Second)
+		if age > maxPollAge {
+			http.Error(w, fmt.Sprintf("last successful Telegram poll %v ago", age), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "ok, last successful Telegram poll %v ago\n", age)
+	})
+}
+
+// ReadyHandler serves /readyz, it fails if a Radarr or Sonarr server isn't
+// reachable or rejects the API key.
+func (b *Bot) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var problems []string
+		for _, instance := range b.RadarrServers {
+			if _, err := instance.Server.GetSystemStatus(); err != nil {
+				problems = append(problems, instance.Name+": "+friendlyError(err))
+			}
+		}
+		if b.SonarrServer != nil {
+			if _, err := b.SonarrServer.GetSystemStatus(); err != nil {
+				problems = append(problems, "Sonarr: "+friendlyError(err))
+			}
+		}
+		if len(problems) > 0 {
+			http.Error(w, strings.Join(problems, "\n"), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
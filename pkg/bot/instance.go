@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+)
+
+const (
+	InstanceSelect = "INSTANCE_"
+	InstanceAll    = "INSTANCE_ALL"
+	InstanceCancel = "INSTANCE_CANCEL"
+)
+
+// RadarrInstance is a named Radarr server, e.g. "HD" and "4K".
+type RadarrInstance struct {
+	Name   string
+	Server *radarr.Radarr
+}
+
+// instanceCommands are the commands which work on a Radarr instance. If
+// the value is true, the command can also run on all instances at once.
+var instanceCommands = map[string]bool{
+	"q": true, "query": true, "add": true, "Q": true, "Query": true, "Add": true,
+	"bulkadd": false, "ba": false,
+	"collections": false, "collection": false, "c": false,
+	"movies": false, "library": false, "l": false,
+	"find": false, "f": false,
+	"upgrades": false, "cutoff": false,
+	"delete": false, "remove": false, "Delete": false, "Remove": false, "d": false,
+	"diskspace": true, "disk": true, "free": true, "rootfolder": true, "rootfolders": true,
+	"stats": false, "statistics": false,
+	"up": true, "upcoming": true,
+	"rss": true, "RSS": true, "searchmonitored": true,
+	"updateAll": true, "updateall": true,
+	"system": true, "System": true, "systemstatus": true, "Systemstatus": true,
+	"health": true,
+	"queue":  true,
+}
+
+// getRadarrServer returns the Radarr instance the chat is working with.
+func (b *Bot) getRadarrServer(chatID int64) *radarr.Radarr {
+	return b.getRadarrInstance(chatID).Server
+}
+
+func (b *Bot) getRadarrInstance(chatID int64) *RadarrInstance {
+	b.muActiveRadarr.Lock()
+	defer b.muActiveRadarr.Unlock()
+	if instance, exists := b.ActiveRadarr[chatID]; exists {
+		return instance
+	}
+	return b.RadarrServers[0]
+}
+
+func (b *Bot) setActiveRadarr(chatID int64, instance *RadarrInstance) {
+	b.muActiveRadarr.Lock()
+	defer b.muActiveRadarr.Unlock()
+	b.ActiveRadarr[chatID] = instance
+}
+
+// instanceLabel returns a prefix naming the instance, empty if there is only one.
+func (b *Bot) instanceLabel(instance *RadarrInstance) string {
+	if len(b.RadarrServers) < 2 {
+		return ""
+	}
+	return instance.Name + ": "
+}
+
+// instanceIndex returns the position of the instance in the configuration.
+func (b *Bot) instanceIndex(instance *RadarrInstance) int {
+	for i, candidate := range b.RadarrServers {
+		if candidate == instance {
+			return i
+		}
+	}
+	return 0
+}
+
+// instanceByName returns the instance with the name, nil if there is none.
+func (b *Bot) instanceByName(name string) *RadarrInstance {
+	for _, instance := range b.RadarrServers {
+		if instance.Name == name {
+			return instance
+		}
+	}
+	return nil
+}
+
+func (b *Bot) needsInstancePick(update tgbotapi.Update) bool {
+	if len(b.RadarrServers) < 2 {
+		return false
+	}
+	_, exists := instanceCommands[update.Message.Command()]
+	return exists
+}
+
+func (b *Bot) showInstancePicker(update tgbotapi.Update) {
+	chatID := update.Message.Chat.ID
+
+	var buttonLabels, buttonData []string
+	for i, instance := range b.RadarrServers {
+		buttonLabels = append(buttonLabels, instance.Name)
+		buttonData = append(buttonData, InstanceSelect+strconv.Itoa(i))
+	}
+	if instanceCommands[update.Message.Command()] {
+		buttonLabels = append(buttonLabels, "All instances")
+		buttonData = append(buttonData, InstanceAll)
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "Cancel - clear command"),
+		append(buttonData, InstanceCancel),
+	)
+
+	b.clearState(update)
+	b.muPendingCommands.Lock()
+	b.PendingCommands[chatID] = update
+	b.muPendingCommands.Unlock()
+	b.setActiveCommand(chatID, InstanceCommand)
+
+	msg := tgbotapi.NewMessage(chatID, "Select Radarr instance:")
+	msg.ReplyMarkup = keyboard
+	b.sendMessage(msg)
+}
+
+func (b *Bot) selectInstance(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot select instance", "error", err)
+		return false
+	}
+
+	b.muPendingCommands.Lock()
+	pending, exists := b.PendingCommands[chatID]
+	b.muPendingCommands.Unlock()
+	if !exists {
+		return false
+	}
+	// the command sets its own state
+	b.clearState(update)
+
+	message := update.CallbackQuery.Message
+	data := update.CallbackQuery.Data
+	var instances []*RadarrInstance
+	switch {
+	case data == InstanceCancel:
+		editMsg := tgbotapi.NewEditMessageText(chatID, message.MessageID, CommandsCleared)
+		b.sendMessage(editMsg)
+		return false
+	case data == InstanceAll:
+		instances = b.RadarrServers
+	case strings.HasPrefix(data, InstanceSelect):
+		index, err := strconv.Atoi(strings.TrimPrefix(data, InstanceSelect))
+		if err != nil || index < 0 || index >= len(b.RadarrServers) {
+			return false
+		}
+		instances = b.RadarrServers[index : index+1]
+	default:
+		return false
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, message.MessageID, "Radarr instance: "+strings.Join(instanceNames(instances), ", "))
+	b.sendMessage(editMsg)
+	b.handleCommand(pending, instances)
+	return false
+}
+
+func instanceNames(instances []*RadarrInstance) []string {
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+	}
+	return names
+}
@@ -10,29 +10,30 @@ import (
 	"github.com/woiza/telegram-bot-radarr/pkg/utils"
 	"golift.io/starr"
 	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
 )
 
-func (b *Bot) sendUpcoming(movies []*radarr.Movie, msg *tgbotapi.MessageConfig) {
-	sort.SliceStable(movies, func(i, j int) bool {
-		return utils.IgnoreArticles(strings.ToLower(movies[i].Title)) < utils.IgnoreArticles(strings.ToLower(movies[j].Title))
+// sendUpcomingEpisodes lists the episodes by air date.
+func (b *Bot) sendUpcomingEpisodes(episodes []*sonarr.Episode, msg *tgbotapi.MessageConfig) {
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].AirDateUtc.Before(episodes[j].AirDateUtc)
 	})
-	for i := 0; i < len(movies); i += b.Config.MaxItems {
-		end := i + b.Config.MaxItems
-		if end > len(movies) {
-			end = len(movies)
+	for i := 0; i < len(episodes); i += b.config().MaxItems {
+		end := i + b.config().MaxItems
+		if end > len(episodes) {
+			end = len(episodes)
 		}
 
 		var text strings.Builder
-		for _, movie := range movies[i:end] {
-			if !movie.InCinemas.IsZero() {
-				fmt.Fprintf(&text, "[%v](https://www.imdb.com/title/%v) \\- cinema %v\n", utils.Escape(movie.Title), movie.ImdbID, utils.Escape(movie.InCinemas.Format("02 Jan 2006")))
-			}
-			if !movie.DigitalRelease.IsZero() {
-				fmt.Fprintf(&text, "[%v](https://www.imdb.com/title/%v) \\- digital %v\n", utils.Escape(movie.Title), movie.ImdbID, utils.Escape(movie.DigitalRelease.Format("02 Jan 2006")))
-			}
-			if !movie.PhysicalRelease.IsZero() {
-				fmt.Fprintf(&text, "[%v](https://www.imdb.com/title/%v) \\- physical %v\n", utils.Escape(movie.Title), movie.ImdbID, utils.Escape(movie.PhysicalRelease.Format("02 Jan 2006")))
+		for _, episode := range episodes[i:end] {
+			title := "unknown series"
+			var tvdbID int64
+			if episode.Series != nil {
+				title = episode.Series.Title
+				tvdbID = episode.Series.TvdbID
 			}
+			episodeName := fmt.Sprintf("S%02dE%02d %s", episode.SeasonNumber, episode.EpisodeNumber, episode.Title)
+			fmt.Fprintf(&text, "[%v](%v) %v \\- %v\n", utils.Escape(title), tvdbURL(tvdbID), utils.Escape(episodeName), utils.Escape(episode.AirDateUtc.Local().Format("02 Jan 2006 15:04")))
 		}
 
 		msg.Text = text.String()
@@ -42,6 +43,31 @@ func (b *Bot) sendUpcoming(movies []*radarr.Movie, msg *tgbotapi.MessageConfig)
 	}
 }
 
+// maxMessageLength is Telegram's size limit of a message text.
+const maxMessageLength = 4096
+
+// sendStats sends the sections, as few messages as the message size limit allows.
+func (b *Bot) sendStats(sections []string, msg *tgbotapi.MessageConfig) {
+	msg.ParseMode = "MarkdownV2"
+	msg.DisableWebPagePreview = true
+	var text strings.Builder
+	for _, section := range sections {
+		if text.Len() > 0 && text.Len()+len(section)+2 > maxMessageLength {
+			msg.Text = text.String()
+			b.sendMessage(msg)
+			text.Reset()
+		}
+		if text.Len() > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(section)
+	}
+	if text.Len() > 0 {
+		msg.Text = text.String()
+		b.sendMessage(msg)
+	}
+}
+
 func (b *Bot) getMoviesAsInlineKeyboard(movies []*radarr.Movie) [][]tgbotapi.InlineKeyboardButton {
 	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
 	for _, movie := range movies {
@@ -55,6 +81,40 @@ func (b *Bot) getMoviesAsInlineKeyboard(movies []*radarr.Movie) [][]tgbotapi.Inl
 	return inlineKeyboard
 }
 
+func (b *Bot) getMoviesAsSelectableInlineKeyboard(movies []*radarr.Movie, selectedMovies []*radarr.Movie) [][]tgbotapi.InlineKeyboardButton {
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+	for _, movie := range movies {
+		buttonText := fmt.Sprintf("%v - %v", movie.Title, movie.Year)
+		if isSelectedMovie(selectedMovies, movie.ID) {
+			buttonText += " \u2705"
+		}
+		button := tgbotapi.NewInlineKeyboardButtonData(
+			buttonText,
+			LibraryBulkTMDBID+strconv.Itoa(int(movie.TmdbID)),
+		)
+		inlineKeyboard = append(inlineKeyboard, []tgbotapi.InlineKeyboardButton{button})
+	}
+	return inlineKeyboard
+}
+
+func (b *Bot) createPaginationButtons(page, totalPages int, firstPage, previousPage, nextPage, lastPage string) []tgbotapi.InlineKeyboardButton {
+	paginationButtons := []tgbotapi.InlineKeyboardButton{}
+	if page > 0 {
+		paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("◀️", previousPage))
+	}
+	paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, totalPages), "current_page"))
+	if page+1 < totalPages {
+		paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("▶️", nextPage))
+	}
+	if page != 0 {
+		paginationButtons = append([]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData("⏮️", firstPage)}, paginationButtons...)
+	}
+	if page+1 != totalPages {
+		paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("⏭️", lastPage))
+	}
+	return paginationButtons
+}
+
 func (b *Bot) createKeyboard(buttonText, buttonData []string) tgbotapi.InlineKeyboardMarkup {
 	buttons := make([][]tgbotapi.InlineKeyboardButton, len(buttonData))
 	for i := range buttonData {
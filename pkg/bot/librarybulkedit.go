@@ -0,0 +1,323 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const (
+	LibraryBulkSelectMode          = "LIBRARY_BULK_SELECT_MODE"
+	LibraryBulkSelectAll           = "LIBRARY_BULK_SELECT_ALL"
+	LibraryBulkSelectNone          = "LIBRARY_BULK_SELECT_NONE"
+	LibraryBulkActions             = "LIBRARY_BULK_ACTIONS"
+	LibraryBulkTMDBID              = "LIBRARY_BULK_TMDBID_"
+	LibraryBulkMonitor             = "LIBRARY_BULK_MONITOR"
+	LibraryBulkUnmonitor           = "LIBRARY_BULK_UNMONITOR"
+	LibraryBulkQualityProfile      = "LIBRARY_BULK_QUALITY_PROFILE"
+	LibraryBulkTags                = "LIBRARY_BULK_TAGS"
+	LibraryBulkMinimumAvailability = "LIBRARY_BULK_MINIMUM_AVAILABILITY"
+	LibraryBulkRootFolder          = "LIBRARY_BULK_ROOTFOLDER"
+	LibraryBulkSearch              = "LIBRARY_BULK_SEARCH"
+	LibraryBulkSetProfile          = "LIBRARY_BULK_SET_PROFILE_"
+	LibraryBulkSetAvailability     = "LIBRARY_BULK_SET_AVAILABILITY_"
+	LibraryBulkSetRootFolder       = "LIBRARY_BULK_SET_ROOTFOLDER_"
+	LibraryBulkTag                 = "LIBRARY_BULK_TAG_"
+	LibraryBulkTagsAdd             = "LIBRARY_BULK_TAGS_ADD"
+	LibraryBulkTagsRemove          = "LIBRARY_BULK_TAGS_REMOVE"
+	LibraryBulkTagsReplace         = "LIBRARY_BULK_TAGS_REPLACE"
+	LibraryBulkActionsGoBack       = "LIBRARY_BULK_ACTIONS_GOBACK"
+	LibraryBulkGoBack              = "LIBRARY_BULK_GOBACK"
+	LibraryBulkCancel              = "LIBRARY_BULK_CANCEL"
+)
+
+func (b *Bot) libraryBulkEdit(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot manage library", "error", err)
+		return false
+	}
+
+	command, exists := b.getLibraryState(chatID)
+	if !exists {
+		return false
+	}
+
+	data := update.CallbackQuery.Data
+	switch data {
+	case LibraryBulkMonitor:
+		return b.applyLibraryBulkEdit(command, radarr.BulkEdit{Monitored: starr.True()}, "Monitored")
+	case LibraryBulkUnmonitor:
+		return b.applyLibraryBulkEdit(command, radarr.BulkEdit{Monitored: starr.False()}, "Unmonitored")
+	case LibraryBulkQualityProfile:
+		return b.showLibraryBulkQualityProfiles(command)
+	case LibraryBulkTags:
+		command.bulkSelectedTags = nil
+		return b.showLibraryBulkTags(command)
+	case LibraryBulkMinimumAvailability:
+		return b.showLibraryBulkMinimumAvailability(command)
+	case LibraryBulkRootFolder:
+		return b.showLibraryBulkRootFolders(command)
+	case LibraryBulkSearch:
+		return b.handleLibraryBulkSearch(command)
+	case LibraryBulkTagsAdd:
+		return b.handleLibraryBulkApplyTags(command, starr.TagsAdd)
+	case LibraryBulkTagsRemove:
+		return b.handleLibraryBulkApplyTags(command, starr.TagsRemove)
+	case LibraryBulkTagsReplace:
+		return b.handleLibraryBulkApplyTags(command, starr.TagsReplace)
+	case LibraryBulkActionsGoBack:
+		return b.handleLibraryBulkActions(command)
+	case LibraryBulkGoBack:
+		b.setActiveCommand(chatID, LibraryFilteredActive)
+		b.setLibraryState(command.chatID, command)
+		return b.showLibraryMenuFiltered(command)
+	case LibraryBulkCancel:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(data, LibraryBulkSetProfile):
+		profileID, err := strconv.ParseInt(strings.TrimPrefix(data, LibraryBulkSetProfile), 10, 64)
+		if err != nil {
+			b.logger(command.chatID).Warn("Cannot convert quality profile ID to int", "error", err)
+			return false
+		}
+		profile := findQualityProfileByID(command.qualityProfiles, profileID)
+		if profile == nil {
+			return false
+		}
+		return b.applyLibraryBulkEdit(command, radarr.BulkEdit{QualityProfileID: &profileID}, "Quality profile changed to "+profile.Name)
+	case strings.HasPrefix(data, LibraryBulkSetAvailability):
+		availability := radarr.Availability(strings.TrimPrefix(data, LibraryBulkSetAvailability))
+		return b.applyLibraryBulkEdit(command, radarr.BulkEdit{MinimumAvailability: availability.Ptr()}, "Minimum availability changed to "+string(availability))
+	case strings.HasPrefix(data, LibraryBulkSetRootFolder):
+		rootFolderID, err := strconv.ParseInt(strings.TrimPrefix(data, LibraryBulkSetRootFolder), 10, 64)
+		if err != nil {
+			b.logger(command.chatID).Warn("Cannot convert root folder ID to int", "error", err)
+			return false
+		}
+		for _, rootFolder := range command.rootFolders {
+			if rootFolder.ID == rootFolderID {
+				path := rootFolder.Path
+				return b.applyLibraryBulkEdit(command, radarr.BulkEdit{RootFolderPath: &path, MoveFiles: starr.True()}, "Moved to "+path)
+			}
+		}
+		return false
+	case strings.HasPrefix(data, LibraryBulkTag):
+		tagID, err := strconv.Atoi(strings.TrimPrefix(data, LibraryBulkTag))
+		if err != nil {
+			b.logger(command.chatID).Warn("Cannot convert tag string to int", "error", err)
+			return false
+		}
+		if isSelectedTag(command.bulkSelectedTags, tagID) {
+			command.bulkSelectedTags = removeTag(command.bulkSelectedTags, tagID)
+		} else {
+			command.bulkSelectedTags = append(command.bulkSelectedTags, tagID)
+		}
+		return b.showLibraryBulkTags(command)
+	}
+	return b.handleLibraryBulkActions(command)
+}
+
+func (b *Bot) handleLibraryBulkSelection(update tgbotapi.Update, command *userLibrary) bool {
+	movieIDStr := strings.TrimPrefix(update.CallbackQuery.Data, LibraryBulkTMDBID)
+	movie, exists := command.libraryFiltered[movieIDStr]
+	if !exists {
+		return false
+	}
+	if isSelectedMovie(command.bulkSelectedMovies, movie.ID) {
+		command.bulkSelectedMovies = removeMovie(command.bulkSelectedMovies, movie.ID)
+	} else {
+		command.bulkSelectedMovies = append(command.bulkSelectedMovies, movie)
+	}
+	return b.showLibraryMenuFiltered(command)
+}
+
+func (b *Bot) handleLibraryBulkActions(command *userLibrary) bool {
+	if len(command.bulkSelectedMovies) == 0 {
+		return b.showLibraryMenuFiltered(command)
+	}
+
+	buttonLabels := []string{"Monitor", "Unmonitor"}
+	buttonData := []string{LibraryBulkMonitor, LibraryBulkUnmonitor}
+	if len(command.qualityProfiles) > 1 {
+		buttonLabels = append(buttonLabels, "Change Quality Profile")
+		buttonData = append(buttonData, LibraryBulkQualityProfile)
+	}
+	if len(command.allTags) > 0 {
+		buttonLabels = append(buttonLabels, "Add/Remove/Replace Tags")
+		buttonData = append(buttonData, LibraryBulkTags)
+	}
+	buttonLabels = append(buttonLabels, "Change Minimum Availability", "Move to Root Folder", "Search Now", "Cancel - clear command", "\U0001F519")
+	buttonData = append(buttonData, LibraryBulkMinimumAvailability, LibraryBulkRootFolder, LibraryBulkSearch, LibraryBulkCancel, LibraryBulkGoBack)
+	keyboard := b.createKeyboard(buttonLabels, buttonData)
+
+	b.setActiveCommand(command.chatID, LibraryBulkEditCommand)
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, fmt.Sprintf("What do you want to do with the %d selected movie(s)?", len(command.bulkSelectedMovies)))
+	return false
+}
+
+func (b *Bot) showLibraryBulkQualityProfiles(command *userLibrary) bool {
+	var buttonLabels, buttonData []string
+	for _, profile := range command.qualityProfiles {
+		buttonLabels = append(buttonLabels, profile.Name)
+		buttonData = append(buttonData, LibraryBulkSetProfile+strconv.Itoa(int(profile.ID)))
+	}
+	keyboard := b.createKeyboard(append(buttonLabels, "\U0001F519"), append(buttonData, LibraryBulkActionsGoBack))
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select quality profile:")
+	return false
+}
+
+func (b *Bot) showLibraryBulkMinimumAvailability(command *userLibrary) bool {
+	keyboard := b.createKeyboard(
+		[]string{"Announced", "In Cinemas", "Released", "\U0001F519"},
+		[]string{
+			LibraryBulkSetAvailability + string(radarr.AvailabilityAnnounced),
+			LibraryBulkSetAvailability + string(radarr.AvailabilityInCinemas),
+			LibraryBulkSetAvailability + string(radarr.AvailabilityReleased),
+			LibraryBulkActionsGoBack,
+		},
+	)
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select minimum availability:")
+	return false
+}
+
+func (b *Bot) showLibraryBulkRootFolders(command *userLibrary) bool {
+	if command.rootFolders == nil {
+		rootFolders, err := b.getRadarrServer(command.chatID).GetRootFolders()
+		if err != nil {
+			return b.reportError(command, err, func() bool { return b.showLibraryBulkRootFolders(command) })
+		}
+		command.rootFolders = rootFolders
+	}
+
+	var buttonLabels, buttonData []string
+	for _, rootFolder := range command.rootFolders {
+		buttonLabels = append(buttonLabels, rootFolder.Path)
+		buttonData = append(buttonData, LibraryBulkSetRootFolder+strconv.Itoa(int(rootFolder.ID)))
+	}
+	keyboard := b.createKeyboard(append(buttonLabels, "\U0001F519"), append(buttonData, LibraryBulkActionsGoBack))
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select root folder, files will be moved:")
+	return false
+}
+
+func (b *Bot) showLibraryBulkTags(command *userLibrary) bool {
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, tag := range command.allTags {
+		buttonText := tag.Label
+		if isSelectedTag(command.bulkSelectedTags, tag.ID) {
+			buttonText += " ✅"
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, LibraryBulkTag+strconv.Itoa(tag.ID)),
+		))
+	}
+
+	if len(command.bulkSelectedTags) > 0 {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("Add", LibraryBulkTagsAdd),
+			tgbotapi.NewInlineKeyboardButtonData("Remove", LibraryBulkTagsRemove),
+			tgbotapi.NewInlineKeyboardButtonData("Replace", LibraryBulkTagsReplace),
+		})
+	}
+	keyboardGoBack := b.createKeyboard(
+		[]string{"\U0001F519"},
+		[]string{LibraryBulkActionsGoBack},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
+
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select tags, then choose whether to add, remove or replace them:")
+	return false
+}
+
+func (b *Bot) handleLibraryBulkApplyTags(command *userLibrary, applyTags starr.ApplyTags) bool {
+	var labels []string
+	for _, tagID := range command.bulkSelectedTags {
+		if tag := findTagByID(command.allTags, tagID); tag != nil {
+			labels = append(labels, tag.Label)
+		}
+	}
+	bulkEdit := radarr.BulkEdit{
+		Tags:      command.bulkSelectedTags,
+		ApplyTags: applyTags.Ptr(),
+	}
+	var summary string
+	switch applyTags {
+	case starr.TagsAdd:
+		summary = "Tags added: "
+	case starr.TagsRemove:
+		summary = "Tags removed: "
+	default:
+		summary = "Tags replaced with: "
+	}
+	return b.applyLibraryBulkEdit(command, bulkEdit, summary+strings.Join(labels, ", "))
+}
+
+// applyLibraryBulkEdit sends one BulkEdit for all selected movies and shows a summary.
+func (b *Bot) applyLibraryBulkEdit(command *userLibrary, bulkEdit radarr.BulkEdit, summary string) bool {
+	for _, movie := range command.bulkSelectedMovies {
+		bulkEdit.MovieIDs = append(bulkEdit.MovieIDs, movie.ID)
+	}
+
+	updatedMovies, err := b.getRadarrServer(command.chatID).EditMovies(&bulkEdit)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.applyLibraryBulkEdit(command, bulkEdit, summary) })
+	}
+
+	// keep the cached library in sync, filters are applied to it
+	updated := make(map[int64]*radarr.Movie, len(updatedMovies))
+	for _, movie := range updatedMovies {
+		updated[movie.ID] = movie
+	}
+	for i, movie := range command.library {
+		if updatedMovie, exists := updated[movie.ID]; exists {
+			command.library[i] = updatedMovie
+		}
+	}
+	for i, movie := range command.bulkSelectedMovies {
+		if updatedMovie, exists := updated[movie.ID]; exists {
+			command.bulkSelectedMovies[i] = updatedMovie
+		}
+	}
+
+	return b.showLibraryBulkResult(command, fmt.Sprintf("%s\n\n%d of %d movie(s) updated", summary, len(updatedMovies), len(bulkEdit.MovieIDs)))
+}
+
+func (b *Bot) handleLibraryBulkSearch(command *userLibrary) bool {
+	var movieIDs []int64
+	for _, movie := range command.bulkSelectedMovies {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+	cmd := radarr.CommandRequest{
+		Name:     "MoviesSearch",
+		MovieIDs: movieIDs,
+	}
+	name := fmt.Sprintf("Search for %d movie(s)", len(movieIDs))
+	err := b.sendTrackedCommand(command.chatID, b.getRadarrInstance(command.chatID), &cmd, name)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleLibraryBulkSearch(command) })
+	}
+	return b.showLibraryBulkResult(command, fmt.Sprintf("Search started for %d movie(s)", len(movieIDs)))
+}
+
+func (b *Bot) showLibraryBulkResult(command *userLibrary, text string) bool {
+	keyboard := b.createKeyboard(
+		[]string{"More actions", "Back to list", "Cancel - clear command"},
+		[]string{LibraryBulkActionsGoBack, LibraryBulkGoBack, LibraryBulkCancel},
+	)
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text)
+	return false
+}
@@ -12,16 +12,20 @@ import (
 )
 
 const (
-	LibraryMovieDelete    = "LIBRARY_MOVIE_DELETE"
-	LibraryMovieDeleteYes = "LIBRARY_MOVIE_DELETE_YES"
-	LibraryMovieDeleteNo  = "LIBRARY_MOVIE_DELETE_NO"
-	LibraryMovieEdit      = "LIBRARY_MOVIE_EDIT"
-	LibraryMovieGoBack    = "LIBRARY_MOVIE_GOBACK"
+	LibraryMovieDelete            = "LIBRARY_MOVIE_DELETE"
+	LibraryMovieDeleteKeepFiles   = "LIBRARY_MOVIE_DELETE_KEEP_FILES"
+	LibraryMovieDeleteWithFiles   = "LIBRARY_MOVIE_DELETE_WITH_FILES"
+	LibraryMovieDeleteExclude     = "LIBRARY_MOVIE_DELETE_EXCLUDE"
+	LibraryMovieDeleteExcludeKeep = "LIBRARY_MOVIE_DELETE_EXCLUDE_KEEP_FILES"
+	LibraryMovieDeleteNo          = "LIBRARY_MOVIE_DELETE_NO"
+	LibraryMovieEdit              = "LIBRARY_MOVIE_EDIT"
+	LibraryMovieGoBack            = "LIBRARY_MOVIE_GOBACK"
 	//LibraryFilteredGoBack        = "LIBRARY_FILTERED_GOBACK" already defined in librarymenu.go
 	LibraryMovieMonitor          = "LIBRARY_MOVIE_MONITOR"
 	LibraryMovieUnmonitor        = "LIBRARY_MOVIE_UNMONITOR"
 	LibraryMovieSearch           = "LIBRARY_MOVIE_SEARCH"
 	LibraryMovieMonitorSearchNow = "LIBRARY_MOVIE_MONITOR_SEARCHNOW"
+	LibraryMovieViewCollection   = "LIBRARY_MOVIE_VIEW_COLLECTION"
 	LibraryFilteredActive        = "LIBRARYFILTERED"
 	//LibraryMenuActive            = "LIBRARYMENU" already defined in librarymenu.go
 	LibraryFirstPage    = "LIBRARY_FIRST_PAGE"
@@ -38,7 +42,7 @@ const (
 func (b *Bot) libraryFiltered(update tgbotapi.Update) bool {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot manage library: %v", err)
+		updateLogger(update).Warn("Cannot manage library", "error", err)
 		return false
 	}
 
@@ -62,7 +66,7 @@ func (b *Bot) libraryFiltered(update tgbotapi.Update) bool {
 		command.page++
 		return b.showLibraryMenuFiltered(command)
 	case LibraryLastPage:
-		totalPages := (len(command.libraryFiltered) + b.Config.MaxItems - 1) / b.Config.MaxItems
+		totalPages := (len(command.libraryFiltered) + b.config().MaxItems - 1) / b.config().MaxItems
 		command.page = totalPages - 1
 		return b.showLibraryMenuFiltered(command)
 	case LibraryMovieGoBack:
@@ -72,6 +76,8 @@ func (b *Bot) libraryFiltered(update tgbotapi.Update) bool {
 		return b.showLibraryMenuFiltered(command)
 	case LibraryFilteredGoBack:
 		command.filter = ""
+		command.bulkSelectMode = false
+		command.bulkSelectedMovies = nil
 		b.setActiveCommand(chatID, LibraryMenuActive)
 		b.setLibraryState(command.chatID, command)
 		return b.showLibraryMenu(command)
@@ -83,15 +89,45 @@ func (b *Bot) libraryFiltered(update tgbotapi.Update) bool {
 		return b.handleLibraryMovieSearch(update, command)
 	case LibraryMovieDelete:
 		return b.handleLibraryMovieDelete(command)
-	case LibraryMovieDeleteYes:
-		return b.handleLibraryMovieDeleteYes(update, command)
+	case LibraryMovieDeleteKeepFiles:
+		return b.handleLibraryMovieDeleteYes(update, command, false, false)
+	case LibraryMovieDeleteWithFiles:
+		return b.handleLibraryMovieDeleteYes(update, command, true, false)
+	case LibraryMovieDeleteExcludeKeep:
+		return b.handleLibraryMovieDeleteYes(update, command, false, true)
+	case LibraryMovieDeleteExclude:
+		return b.handleLibraryMovieDeleteYes(update, command, true, true)
 	case LibraryMovieDeleteNo:
 		return b.showLibraryMovieDetail(update, command)
 	case LibraryMovieEdit:
 		return b.handleLibraryMovieEdit(command)
 	case LibraryMovieMonitorSearchNow:
 		return b.handleLibraryMovieMonitorSearchNow(update, command)
+	case LibraryMovieViewCollection:
+		return b.showCollectionOfMovie(command.movie, LibraryFilteredCommand, command.chatID, command.messageID)
+	case LibraryBulkSelectMode:
+		command.bulkSelectMode = !command.bulkSelectMode
+		command.bulkSelectedMovies = nil
+		return b.showLibraryMenuFiltered(command)
+	case LibraryBulkSelectAll:
+		command.bulkSelectedMovies = nil
+		for _, movie := range command.libraryFiltered {
+			command.bulkSelectedMovies = append(command.bulkSelectedMovies, movie)
+		}
+		return b.showLibraryMenuFiltered(command)
+	case LibraryBulkSelectNone:
+		command.bulkSelectedMovies = nil
+		return b.showLibraryMenuFiltered(command)
+	case LibraryFilters:
+		return b.showLibraryFilters(command)
+	case LibrarySearchAllShown:
+		return b.handleLibrarySearchAllShown(command)
+	case LibraryBulkActions:
+		return b.handleLibraryBulkActions(command)
 	default:
+		if strings.HasPrefix(update.CallbackQuery.Data, LibraryBulkTMDBID) {
+			return b.handleLibraryBulkSelection(update, command)
+		}
 		return b.showLibraryMovieDetail(update, command)
 	}
 }
@@ -133,11 +169,9 @@ func (b *Bot) showLibraryMovieDetail(update tgbotapi.Update, command *userLibrar
 	}
 	tagsString := strings.Join(tagLabels, ", ")
 
-	movieFiles, err := b.RadarrServer.GetMovieFile(movie.ID)
+	movieFiles, err := b.getRadarrServer(command.chatID).GetMovieFile(movie.ID)
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.showLibraryMovieDetail(update, command) })
 	}
 
 	size := int64(0)
@@ -178,18 +212,21 @@ func (b *Bot) showLibraryMovieDetail(update tgbotapi.Update, command *userLibrar
 
 	messageText := message.String()
 
-	var keyboard tgbotapi.InlineKeyboardMarkup
+	var buttonLabels, buttonData []string
 	if !movie.Monitored {
-		keyboard = b.createKeyboard(
-			[]string{"Monitor Movie", "Monitor Movie & Search Now", "Delete Movie", "Edit Movie", "\U0001F519"},
-			[]string{LibraryMovieMonitor, LibraryMovieMonitorSearchNow, LibraryMovieDelete, LibraryMovieEdit, LibraryMovieGoBack},
-		)
+		buttonLabels = []string{"Monitor Movie", "Monitor Movie & Search Now", "Delete Movie", "Edit Movie"}
+		buttonData = []string{LibraryMovieMonitor, LibraryMovieMonitorSearchNow, LibraryMovieDelete, LibraryMovieEdit}
 	} else {
-		keyboard = b.createKeyboard(
-			[]string{"Unmonitor Movie", "Search Movie", "Delete Movie", "Edit Movie", "\U0001F519"},
-			[]string{LibraryMovieUnmonitor, LibraryMovieSearch, LibraryMovieDelete, LibraryMovieEdit, LibraryMovieGoBack},
-		)
+		buttonLabels = []string{"Unmonitor Movie", "Search Movie", "Delete Movie", "Edit Movie"}
+		buttonData = []string{LibraryMovieUnmonitor, LibraryMovieSearch, LibraryMovieDelete, LibraryMovieEdit}
 	}
+	if movie.Collection != nil && movie.Collection.TmdbID != 0 {
+		buttonLabels = append(buttonLabels, "View Collection")
+		buttonData = append(buttonData, LibraryMovieViewCollection)
+	}
+	keyboard := b.createKeyboard(buttonLabels, buttonData)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, b.reminderButtons(command.chatID, b.getRadarrInstance(command.chatID), movie)...)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("\U0001F519", LibraryMovieGoBack)))
 
 	// Send the message containing movie details along with the keyboard
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
@@ -210,11 +247,9 @@ func (b *Bot) handleLibraryMovieMonitor(update tgbotapi.Update, command *userLib
 		MovieIDs:  []int64{command.movie.ID},
 		Monitored: starr.True(),
 	}
-	_, err := b.RadarrServer.EditMovies(&bulkEdit)
+	_, err := b.getRadarrServer(command.chatID).EditMovies(&bulkEdit)
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieMonitor(update, command) })
 	}
 	command.movie.Monitored = true
 	b.setLibraryState(command.chatID, command)
@@ -226,11 +261,9 @@ func (b *Bot) handleLibraryMovieUnMonitor(update tgbotapi.Update, command *userL
 		MovieIDs:  []int64{command.movie.ID},
 		Monitored: starr.False(),
 	}
-	_, err := b.RadarrServer.EditMovies(&bulkEdit)
+	_, err := b.getRadarrServer(command.chatID).EditMovies(&bulkEdit)
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieUnMonitor(update, command) })
 	}
 	command.movie.Monitored = false
 	b.setLibraryState(command.chatID, command)
@@ -242,11 +275,9 @@ func (b *Bot) handleLibraryMovieSearch(update tgbotapi.Update, command *userLibr
 		Name:     "MoviesSearch",
 		MovieIDs: []int64{command.movie.ID},
 	}
-	_, err := b.RadarrServer.SendCommand(&cmd)
+	err := b.sendTrackedCommand(command.chatID, b.getRadarrInstance(command.chatID), &cmd, movieSearchName(command.movie))
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieSearch(update, command) })
 	}
 	command.lastSearch = time.Now()
 	b.setLibraryState(command.chatID, command)
@@ -258,22 +289,18 @@ func (b *Bot) handleLibraryMovieMonitorSearchNow(update tgbotapi.Update, command
 		MovieIDs:  []int64{command.movie.ID},
 		Monitored: starr.True(),
 	}
-	_, err := b.RadarrServer.EditMovies(&bulkEdit)
+	_, err := b.getRadarrServer(command.chatID).EditMovies(&bulkEdit)
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieMonitorSearchNow(update, command) })
 	}
 	command.movie.Monitored = true
 	cmd := radarr.CommandRequest{
 		Name:     "MoviesSearch",
 		MovieIDs: []int64{command.movie.ID},
 	}
-	_, err = b.RadarrServer.SendCommand(&cmd)
+	err = b.sendTrackedCommand(command.chatID, b.getRadarrInstance(command.chatID), &cmd, movieSearchName(command.movie))
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieMonitorSearchNow(update, command) })
 	}
 	command.lastSearch = time.Now()
 	b.setLibraryState(command.chatID, command)
@@ -282,10 +309,16 @@ func (b *Bot) handleLibraryMovieMonitorSearchNow(update tgbotapi.Update, command
 
 func (b *Bot) handleLibraryMovieDelete(command *userLibrary) bool {
 	messageText := fmt.Sprintf("[%v](https://www.imdb.com/title/%v) \\- _%v_\n\n", utils.Escape(command.movie.Title), command.movie.ImdbID, command.movie.Year)
-	keyboard := b.createKeyboard(
-		[]string{"Yes, delete this movie", "\U0001F519"},
-		[]string{LibraryMovieDeleteYes, LibraryMovieDeleteNo},
+	messageText += fmt.Sprintf("Size on disk: %s", utils.Escape(utils.ByteCountSI(command.movie.SizeOnDisk)))
+	keyboard := b.createDeleteOptionsKeyboard(
+		[]*radarr.Movie{command.movie},
+		[]string{LibraryMovieDeleteKeepFiles, LibraryMovieDeleteWithFiles, LibraryMovieDeleteExcludeKeep, LibraryMovieDeleteExclude},
 	)
+	keyboardGoBack := b.createKeyboard(
+		[]string{"\U0001F519"},
+		[]string{LibraryMovieDeleteNo},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardGoBack.InlineKeyboard...)
 	// Send the message containing movie details along with the keyboard
 	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
 		command.chatID,
@@ -301,16 +334,9 @@ func (b *Bot) handleLibraryMovieDelete(command *userLibrary) bool {
 
 }
 
-func (b *Bot) handleLibraryMovieDeleteYes(update tgbotapi.Update, command *userLibrary) bool {
-	err := b.RadarrServer.DeleteMovie(command.movie.ID, *starr.True(), *starr.False())
-	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
-	}
-	text := fmt.Sprintf("Movie '%v' deleted\n", command.movie.Title)
+func (b *Bot) handleLibraryMovieDeleteYes(update tgbotapi.Update, command *userLibrary, deleteFiles, addImportExclusion bool) bool {
 	b.clearState(update)
-	b.sendMessageWithEdit(command, text)
+	b.deleteMovies(command.chatID, command.messageID, []*radarr.Movie{command.movie}, deleteFiles, addImportExclusion)
 	return true
 }
 
@@ -0,0 +1,562 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr/radarr"
+)
+
+const (
+	LibraryFilters                = "LIBRARY_FILTERS"
+	LibraryFiltersGoBack          = "LIBRARY_FILTERS_GOBACK"
+	LibraryFiltersShow            = "LIBRARY_FILTERS_SHOW"
+	LibraryFiltersReset           = "LIBRARY_FILTERS_RESET"
+	LibraryFiltersQualityProfile  = "LIBRARY_FILTERS_QUALITYPROFILE"
+	LibraryFiltersTag             = "LIBRARY_FILTERS_TAG"
+	LibraryFiltersRootFolder      = "LIBRARY_FILTERS_ROOTFOLDER"
+	LibraryFiltersYear            = "LIBRARY_FILTERS_YEAR"
+	LibraryFiltersGenre           = "LIBRARY_FILTERS_GENRE"
+	LibraryFiltersStatus          = "LIBRARY_FILTERS_STATUS"
+	LibraryFiltersSize            = "LIBRARY_FILTERS_SIZE"
+	LibraryFiltersAdded           = "LIBRARY_FILTERS_ADDED"
+	LibraryFiltersSort            = "LIBRARY_FILTERS_SORT"
+	LibraryFiltersToggleCutoff    = "LIBRARY_FILTERS_TOGGLE_CUTOFF"
+	LibraryFiltersToggleUnmonFile = "LIBRARY_FILTERS_TOGGLE_UNMONFILE"
+	LibraryFiltersToggleOrder     = "LIBRARY_FILTERS_TOGGLE_ORDER"
+	// values are appended to the following prefixes
+	LibraryFiltersSetQualityProfile = "LIBRARY_FILTERS_SET_QP_"
+	LibraryFiltersSetTag            = "LIBRARY_FILTERS_SET_TAG_"
+	LibraryFiltersSetRootFolder     = "LIBRARY_FILTERS_SET_RF_"
+	LibraryFiltersSetYear           = "LIBRARY_FILTERS_SET_YEAR_"
+	LibraryFiltersSetGenre          = "LIBRARY_FILTERS_SET_GENRE_"
+	LibraryFiltersSetStatus         = "LIBRARY_FILTERS_SET_STATUS_"
+	LibraryFiltersSetSize           = "LIBRARY_FILTERS_SET_SIZE_"
+	LibraryFiltersSetAdded          = "LIBRARY_FILTERS_SET_ADDED_"
+	LibraryFiltersSetSort           = "LIBRARY_FILTERS_SET_SORT_"
+)
+
+const (
+	SortByTitle  = "title"
+	SortByYear   = "year"
+	SortByAdded  = "added"
+	SortBySize   = "size"
+	SortByRating = "rating"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+var sortLabels = map[string]string{
+	SortByTitle:  "Title",
+	SortByYear:   "Year",
+	SortByAdded:  "Date added",
+	SortBySize:   "Size",
+	SortByRating: "Rating",
+}
+
+var statusLabels = map[string]string{
+	"announced": "Announced",
+	"inCinemas": "In cinemas",
+	"released":  "Released",
+}
+
+// yearRanges are offered in the year menu, "from-to" where either side may be empty.
+var yearRanges = []string{"-1969", "1970-1979", "1980-1989", "1990-1999", "2000-2009", "2010-2019", "2020-"}
+
+var sizeThresholds = []int64{1, 5, 10, 20, 50}
+
+var addedWithinDays = []int{7, 30, 90, 365}
+
+// libraryFilter holds the advanced filters and the sort order of the library
+// lists. All filters are combined, zero values are ignored.
+type libraryFilter struct {
+	qualityProfileID int64
+	tagID            int
+	rootFolder       string
+	yearFrom         int
+	yearTo           int
+	genre            string
+	status           string
+	cutoffUnmet      bool
+	unmonitoredFile  bool // has a file, but is not monitored
+	minSize          int64
+	addedWithin      time.Duration
+	sortBy           string
+	sortDescending   bool
+}
+
+func (f *libraryFilter) matches(movie *radarr.Movie, qualityProfiles []*radarr.QualityProfile) bool {
+	switch {
+	case f.qualityProfileID != 0 && movie.QualityProfileID != f.qualityProfileID:
+		return false
+	case f.tagID != 0 && !isSelectedTag(movie.Tags, f.tagID):
+		return false
+	case f.rootFolder != "" && !isInRootFolder(movie, f.rootFolder):
+		return false
+	case f.yearFrom != 0 && movie.Year < f.yearFrom:
+		return false
+	case f.yearTo != 0 && movie.Year > f.yearTo:
+		return false
+	case f.genre != "" && !containsString(movie.Genres, f.genre):
+		return false
+	case f.status != "" && movie.Status != f.status:
+		return false
+	case f.cutoffUnmet && !cutoffUnmet(qualityProfiles)(movie):
+		return false
+	case f.unmonitoredFile && (!movie.HasFile || movie.Monitored):
+		return false
+	case f.minSize != 0 && movie.SizeOnDisk < f.minSize:
+		return false
+	case f.addedWithin != 0 && time.Since(movie.Added) > f.addedWithin:
+		return false
+	}
+	return true
+}
+
+func (f *libraryFilter) apply(movies []*radarr.Movie, qualityProfiles []*radarr.QualityProfile) []*radarr.Movie {
+	return filterMovies(movies, func(movie *radarr.Movie) bool {
+		return f.matches(movie, qualityProfiles)
+	})
+}
+
+// sort sorts the movies by the selected order, ties are sorted by title.
+func (f *libraryFilter) sort(movies []*radarr.Movie) {
+	byTitle := func(i, j int) bool {
+		return utils.IgnoreArticles(strings.ToLower(movies[i].Title)) < utils.IgnoreArticles(strings.ToLower(movies[j].Title))
+	}
+	var less func(i, j int) bool
+	switch f.sortBy {
+	case SortByYear:
+		less = func(i, j int) bool { return movies[i].Year < movies[j].Year }
+	case SortByAdded:
+		less = func(i, j int) bool { return movies[i].Added.Before(movies[j].Added) }
+	case SortBySize:
+		less = func(i, j int) bool { return movies[i].SizeOnDisk < movies[j].SizeOnDisk }
+	case SortByRating:
+		less = func(i, j int) bool { return movieRating(movies[i]) < movieRating(movies[j]) }
+	default:
+		less = byTitle
+	}
+
+	sort.SliceStable(movies, byTitle)
+	sort.SliceStable(movies, func(i, j int) bool {
+		if f.sortDescending {
+			return less(j, i)
+		}
+		return less(i, j)
+	})
+}
+
+// active reports whether any filter is set, the sort order is not a filter.
+func (f *libraryFilter) active() bool {
+	return len(f.descriptions(nil)) > 0
+}
+
+// descriptions returns a short text for every active filter.
+func (f *libraryFilter) descriptions(command *userLibrary) []string {
+	var descriptions []string
+	if f.qualityProfileID != 0 {
+		descriptions = append(descriptions, "Profile: "+f.qualityProfileName(command))
+	}
+	if f.tagID != 0 {
+		descriptions = append(descriptions, "Tag: "+f.tagName(command))
+	}
+	if f.rootFolder != "" {
+		descriptions = append(descriptions, "Root folder: "+f.rootFolder)
+	}
+	if f.yearFrom != 0 || f.yearTo != 0 {
+		descriptions = append(descriptions, "Year: "+f.yearRange())
+	}
+	if f.genre != "" {
+		descriptions = append(descriptions, "Genre: "+f.genre)
+	}
+	if f.status != "" {
+		descriptions = append(descriptions, "Status: "+statusLabels[f.status])
+	}
+	if f.cutoffUnmet {
+		descriptions = append(descriptions, "Cutoff unmet")
+	}
+	if f.unmonitoredFile {
+		descriptions = append(descriptions, "On disk, unmonitored")
+	}
+	if f.minSize != 0 {
+		descriptions = append(descriptions, fmt.Sprintf("Size > %d GB", f.minSize/gigabyte))
+	}
+	if f.addedWithin != 0 {
+		descriptions = append(descriptions, fmt.Sprintf("Added in last %d days", int(f.addedWithin.Hours()/24)))
+	}
+	return descriptions
+}
+
+func (f *libraryFilter) sortDescription() string {
+	label, exists := sortLabels[f.sortBy]
+	if !exists {
+		label = sortLabels[SortByTitle]
+	}
+	if f.sortDescending {
+		return label + " ↓"
+	}
+	return label + " ↑"
+}
+
+func (f *libraryFilter) yearRange() string {
+	switch {
+	case f.yearFrom == 0 && f.yearTo == 0:
+		return "any"
+	case f.yearFrom == 0:
+		return fmt.Sprintf("until %d", f.yearTo)
+	case f.yearTo == 0:
+		return fmt.Sprintf("since %d", f.yearFrom)
+	default:
+		return fmt.Sprintf("%d-%d", f.yearFrom, f.yearTo)
+	}
+}
+
+func (f *libraryFilter) qualityProfileName(command *userLibrary) string {
+	if command != nil {
+		for _, profile := range command.qualityProfiles {
+			if profile.ID == f.qualityProfileID {
+				return profile.Name
+			}
+		}
+	}
+	return strconv.Itoa(int(f.qualityProfileID))
+}
+
+func (f *libraryFilter) tagName(command *userLibrary) string {
+	if command != nil {
+		if tag := findTagByID(command.allTags, f.tagID); tag != nil {
+			return tag.Label
+		}
+	}
+	return strconv.Itoa(f.tagID)
+}
+
+func (b *Bot) libraryFilters(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot manage library filters", "error", err)
+		return false
+	}
+
+	command, exists := b.getLibraryState(chatID)
+	if !exists {
+		return false
+	}
+
+	data := update.CallbackQuery.Data
+	filter := &command.advancedFilter
+	switch {
+	case data == LibraryFilters:
+		return b.showLibraryFilters(command)
+	case data == LibraryFiltersGoBack:
+		return b.handleLibraryFiltersGoBack(command)
+	case data == LibraryFiltersShow:
+		if command.filter == "" || isResultList(command.filter) {
+			command.filter = FilterShowAll
+		}
+		command.page = 0
+		b.setActiveCommand(chatID, LibraryFilteredCommand)
+		return b.showLibraryMenuFiltered(command)
+	case data == LibraryFiltersReset:
+		command.advancedFilter = libraryFilter{}
+		return b.showLibraryFilters(command)
+	case data == LibraryFiltersToggleCutoff:
+		filter.cutoffUnmet = !filter.cutoffUnmet
+		return b.showLibraryFilters(command)
+	case data == LibraryFiltersToggleUnmonFile:
+		filter.unmonitoredFile = !filter.unmonitoredFile
+		return b.showLibraryFilters(command)
+	case data == LibraryFiltersToggleOrder:
+		filter.sortDescending = !filter.sortDescending
+		return b.showLibraryFilters(command)
+	case data == LibraryFiltersQualityProfile,
+		data == LibraryFiltersTag,
+		data == LibraryFiltersRootFolder,
+		data == LibraryFiltersYear,
+		data == LibraryFiltersGenre,
+		data == LibraryFiltersStatus,
+		data == LibraryFiltersSize,
+		data == LibraryFiltersAdded,
+		data == LibraryFiltersSort:
+		return b.showLibraryFilterOptions(command, data)
+	case strings.HasPrefix(data, LibraryFiltersSetQualityProfile):
+		filter.qualityProfileID, _ = strconv.ParseInt(strings.TrimPrefix(data, LibraryFiltersSetQualityProfile), 10, 64)
+	case strings.HasPrefix(data, LibraryFiltersSetTag):
+		filter.tagID, _ = strconv.Atoi(strings.TrimPrefix(data, LibraryFiltersSetTag))
+	case strings.HasPrefix(data, LibraryFiltersSetRootFolder):
+		filter.rootFolder = ""
+		rootFolderID, _ := strconv.ParseInt(strings.TrimPrefix(data, LibraryFiltersSetRootFolder), 10, 64)
+		for _, rootFolder := range command.rootFolders {
+			if rootFolder.ID == rootFolderID {
+				filter.rootFolder = rootFolder.Path
+			}
+		}
+	case strings.HasPrefix(data, LibraryFiltersSetYear):
+		from, to, _ := strings.Cut(strings.TrimPrefix(data, LibraryFiltersSetYear), "-")
+		filter.yearFrom, _ = strconv.Atoi(from)
+		filter.yearTo, _ = strconv.Atoi(to)
+	case strings.HasPrefix(data, LibraryFiltersSetGenre):
+		filter.genre = ""
+		genres := libraryGenres(command.library)
+		if index, err := strconv.Atoi(strings.TrimPrefix(data, LibraryFiltersSetGenre)); err == nil && index >= 0 && index < len(genres) {
+			filter.genre = genres[index]
+		}
+	case strings.HasPrefix(data, LibraryFiltersSetStatus):
+		filter.status = strings.TrimPrefix(data, LibraryFiltersSetStatus)
+	case strings.HasPrefix(data, LibraryFiltersSetSize):
+		size, _ := strconv.ParseInt(strings.TrimPrefix(data, LibraryFiltersSetSize), 10, 64)
+		filter.minSize = size * gigabyte
+	case strings.HasPrefix(data, LibraryFiltersSetAdded):
+		days, _ := strconv.Atoi(strings.TrimPrefix(data, LibraryFiltersSetAdded))
+		filter.addedWithin = time.Duration(days) * 24 * time.Hour
+	case strings.HasPrefix(data, LibraryFiltersSetSort):
+		filter.sortBy = strings.TrimPrefix(data, LibraryFiltersSetSort)
+	default:
+		return false
+	}
+	// a value was selected, go back to the overview
+	return b.showLibraryFilters(command)
+}
+
+func (b *Bot) showLibraryFilters(command *userLibrary) bool {
+	filter := &command.advancedFilter
+
+	var text strings.Builder
+	text.WriteString("Advanced filters and sort order, all filters are combined.\n")
+	if command.filter != "" && !isResultList(command.filter) {
+		fmt.Fprintf(&text, "\nList: %s", filterLabels[command.filter])
+	}
+	if descriptions := filter.descriptions(command); len(descriptions) > 0 {
+		fmt.Fprintf(&text, "\nFilters: %s", strings.Join(descriptions, ", "))
+	} else {
+		text.WriteString("\nFilters: none")
+	}
+	fmt.Fprintf(&text, "\nSort: %s", filter.sortDescription())
+
+	profileLabel := "any"
+	if filter.qualityProfileID != 0 {
+		profileLabel = filter.qualityProfileName(command)
+	}
+	tagLabel := "any"
+	if filter.tagID != 0 {
+		tagLabel = filter.tagName(command)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Profile: "+profileLabel, LibraryFiltersQualityProfile),
+			tgbotapi.NewInlineKeyboardButtonData("Tag: "+tagLabel, LibraryFiltersTag),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Root folder: "+valueOrAny(filter.rootFolder), LibraryFiltersRootFolder),
+			tgbotapi.NewInlineKeyboardButtonData("Year: "+filter.yearRange(), LibraryFiltersYear),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Genre: "+valueOrAny(filter.genre), LibraryFiltersGenre),
+			tgbotapi.NewInlineKeyboardButtonData("Status: "+valueOrAny(statusLabels[filter.status]), LibraryFiltersStatus),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(toggleLabel("Cutoff unmet", filter.cutoffUnmet), LibraryFiltersToggleCutoff),
+			tgbotapi.NewInlineKeyboardButtonData(toggleLabel("On disk, unmonitored", filter.unmonitoredFile), LibraryFiltersToggleUnmonFile),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Size: "+sizeLabel(filter.minSize), LibraryFiltersSize),
+			tgbotapi.NewInlineKeyboardButtonData("Added: "+addedLabel(filter.addedWithin), LibraryFiltersAdded),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Sort: "+filter.sortDescription(), LibraryFiltersSort),
+			tgbotapi.NewInlineKeyboardButtonData("Reverse order", LibraryFiltersToggleOrder),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Reset", LibraryFiltersReset),
+			tgbotapi.NewInlineKeyboardButtonData("Show movies", LibraryFiltersShow),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("\U0001F519", LibraryFiltersGoBack),
+		),
+	)
+
+	b.setLibraryState(command.chatID, command)
+	b.setActiveCommand(command.chatID, LibraryFiltersCommand)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text.String())
+	return false
+}
+
+func (b *Bot) showLibraryFilterOptions(command *userLibrary, option string) bool {
+	var buttonLabels, buttonData []string
+	var text string
+	add := func(label, data string) {
+		buttonLabels = append(buttonLabels, label)
+		buttonData = append(buttonData, data)
+	}
+
+	switch option {
+	case LibraryFiltersQualityProfile:
+		text = "Filter by quality profile:"
+		add("Any", LibraryFiltersSetQualityProfile+"0")
+		for _, profile := range command.qualityProfiles {
+			add(profile.Name, LibraryFiltersSetQualityProfile+strconv.Itoa(int(profile.ID)))
+		}
+	case LibraryFiltersTag:
+		text = "Filter by tag:"
+		add("Any", LibraryFiltersSetTag+"0")
+		for _, tag := range command.allTags {
+			add(tag.Label, LibraryFiltersSetTag+strconv.Itoa(tag.ID))
+		}
+	case LibraryFiltersRootFolder:
+		if command.rootFolders == nil {
+			rootFolders, err := b.getRadarrServer(command.chatID).GetRootFolders()
+			if err != nil {
+				return b.reportError(command, err, func() bool { return b.showLibraryFilterOptions(command, option) })
+			}
+			command.rootFolders = rootFolders
+		}
+		text = "Filter by root folder:"
+		add("Any", LibraryFiltersSetRootFolder+"0")
+		for _, rootFolder := range command.rootFolders {
+			add(rootFolder.Path, LibraryFiltersSetRootFolder+strconv.Itoa(int(rootFolder.ID)))
+		}
+	case LibraryFiltersYear:
+		text = "Filter by year:"
+		add("Any", LibraryFiltersSetYear+"-")
+		for _, yearRange := range yearRanges {
+			from, to, _ := strings.Cut(yearRange, "-")
+			label := yearRange
+			switch {
+			case from == "":
+				label = "until " + to
+			case to == "":
+				label = "since " + from
+			}
+			add(label, LibraryFiltersSetYear+yearRange)
+		}
+		add("Last 5 years", fmt.Sprintf("%s%d-", LibraryFiltersSetYear, time.Now().Year()-4))
+	case LibraryFiltersGenre:
+		text = "Filter by genre:"
+		add("Any", LibraryFiltersSetGenre+"-1")
+		for i, genre := range libraryGenres(command.library) {
+			add(genre, LibraryFiltersSetGenre+strconv.Itoa(i))
+		}
+	case LibraryFiltersStatus:
+		text = "Filter by status:"
+		add("Any", LibraryFiltersSetStatus)
+		for _, status := range []string{"announced", "inCinemas", "released"} {
+			add(statusLabels[status], LibraryFiltersSetStatus+status)
+		}
+	case LibraryFiltersSize:
+		text = "Filter by size on disk:"
+		add("Any", LibraryFiltersSetSize+"0")
+		for _, size := range sizeThresholds {
+			add(fmt.Sprintf("> %d GB", size), LibraryFiltersSetSize+strconv.Itoa(int(size)))
+		}
+	case LibraryFiltersAdded:
+		text = "Filter by date added:"
+		add("Any", LibraryFiltersSetAdded+"0")
+		for _, days := range addedWithinDays {
+			add(fmt.Sprintf("Last %d days", days), LibraryFiltersSetAdded+strconv.Itoa(days))
+		}
+	case LibraryFiltersSort:
+		text = "Sort by:"
+		for _, sortBy := range []string{SortByTitle, SortByYear, SortByAdded, SortBySize, SortByRating} {
+			add(sortLabels[sortBy], LibraryFiltersSetSort+sortBy)
+		}
+	}
+
+	keyboard := b.createKeyboard(append(buttonLabels, "\U0001F519"), append(buttonData, LibraryFilters))
+	b.setLibraryState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text)
+	return false
+}
+
+func (b *Bot) handleLibraryFiltersGoBack(command *userLibrary) bool {
+	if command.filter == "" {
+		b.setActiveCommand(command.chatID, LibraryMenuCommand)
+		return b.showLibraryMenu(command)
+	}
+	b.setActiveCommand(command.chatID, LibraryFilteredCommand)
+	return b.showLibraryMenuFiltered(command)
+}
+
+// libraryGenres returns the sorted genres of all movies.
+func libraryGenres(movies []*radarr.Movie) []string {
+	unique := make(map[string]bool)
+	for _, movie := range movies {
+		for _, genre := range movie.Genres {
+			unique[genre] = true
+		}
+	}
+	genres := make([]string, 0, len(unique))
+	for genre := range unique {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+	return genres
+}
+
+func isInRootFolder(movie *radarr.Movie, rootFolder string) bool {
+	return isSubPath(movie.Path, rootFolder)
+}
+
+// isSubPath reports whether path is dir or inside it, /mnt/data2 is not
+// inside /mnt/data. Radarr on Windows uses backslashes.
+func isSubPath(path, dir string) bool {
+	if !strings.HasPrefix(path, dir) {
+		return false
+	}
+	if len(path) == len(dir) || strings.HasSuffix(dir, "/") || strings.HasSuffix(dir, `\`) {
+		return true
+	}
+	next := path[len(dir)]
+	return next == '/' || next == '\\'
+}
+
+// movieRating returns the IMDb rating, or the TMDB rating if there is none.
+func movieRating(movie *radarr.Movie) float64 {
+	if rating, exists := movie.Ratings["imdb"]; exists && rating.Value > 0 {
+		return rating.Value
+	}
+	return movie.Ratings["tmdb"].Value
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func valueOrAny(value string) string {
+	if value == "" {
+		return "any"
+	}
+	return value
+}
+
+func toggleLabel(label string, enabled bool) string {
+	if enabled {
+		return label + " ✅"
+	}
+	return label
+}
+
+func sizeLabel(size int64) string {
+	if size == 0 {
+		return "any"
+	}
+	return fmt.Sprintf("> %d GB", size/gigabyte)
+}
+
+func addedLabel(within time.Duration) string {
+	if within == 0 {
+		return "any"
+	}
+	return fmt.Sprintf("last %d days", int(within.Hours()/24))
+}
@@ -2,7 +2,6 @@ package bot
 
 import (
 	"fmt"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -15,6 +14,7 @@ const (
 	LibraryFilteredGoBack = "LIBRARY_FILTERED_GOBACK"
 	LibraryMenu           = "LIBRARY_MENU"
 	LibraryCancel         = "LIBRARY_CANCEL"
+	LibrarySearchLookup   = "LIBRARY_SEARCH_LOOKUP"
 	LibraryMenuActive     = "LIBRARYMENU"
 	LibraryFiltered       = "LIBRARYFILTERED"
 	CommandsCleared       = "All commands have been cleared"
@@ -28,62 +28,91 @@ const (
 	FilterOnDisk        = "FILTER_ONDISK"
 	FilterShowAll       = "FILTER_SHOWALL"
 	FilterSearchResults = "FILTER_SEARCHRESULTS"
+	FilterFindResults   = "FILTER_FINDRESULTS"
+	FilterCutoffUnmet   = "FILTER_CUTOFFUNMET"
+	FilterUpcoming      = "FILTER_UPCOMING"
 )
 
+var filterLabels = map[string]string{
+	FilterMonitored:     "Monitored Movies",
+	FilterUnmonitored:   "Unmonitored Movies",
+	FilterMissing:       "Missing Movies",
+	FilterWanted:        "Wanted Movies",
+	FilterOnDisk:        "Movies on Disk",
+	FilterShowAll:       "All Movies",
+	FilterSearchResults: "Search Results",
+	FilterFindResults:   "Find Results",
+	FilterCutoffUnmet:   "Cutoff Unmet",
+	FilterUpcoming:      "Releases",
+}
+
 func (b *Bot) processLibraryCommand(update tgbotapi.Update, userID int64, r *radarr.Radarr) {
 	msg := tgbotapi.NewMessage(userID, "Handling library command... please wait")
 	message, _ := b.sendMessage(msg)
 
-	qualityProfiles, err := r.GetQualityProfiles()
+	command, err := loadLibrary(r, message)
 	if err != nil {
-		msg := tgbotapi.NewMessage(userID, err.Error())
-		b.sendMessage(msg)
+		b.reportError(&statusMessage{userID, message.MessageID}, err, func() bool {
+			b.processLibraryCommand(update, userID, r)
+			return false
+		})
 		return
 	}
+	movies := command.library
+
+	criteria := update.Message.CommandArguments()
+	// no search criteria --> show menu and return
+	if len(criteria) < 1 {
+		b.setLibraryState(userID, command)
+		b.showLibraryMenu(command)
+		return
+	}
+
+	// search the fetched library, a Radarr lookup is only done on request
+	command.searchCriteria = criteria
+	searchResults := utils.SearchMovies(movies, criteria)
+	if len(searchResults) == 0 {
+		keyboard := b.createKeyboard(
+			[]string{"Search via Radarr lookup", "Cancel - clear command"},
+			[]string{LibrarySearchLookup, LibraryCancel},
+		)
+		b.setLibraryState(userID, command)
+		b.sendMessageWithEditAndKeyboard(command, keyboard, fmt.Sprintf("No movies found in your library matching '%s'", criteria))
+		return
+	}
+
+	b.showLibrarySearchResults(update, searchResults, command)
+}
+
+// loadLibrary fetches the movies, quality profiles and tags for a library
+// command whose output is shown in the message.
+func loadLibrary(r *radarr.Radarr, message tgbotapi.Message) (*userLibrary, error) {
+	qualityProfiles, err := r.GetQualityProfiles()
+	if err != nil {
+		return nil, err
+	}
 	tags, err := r.GetTags()
 	if err != nil {
-		msg := tgbotapi.NewMessage(userID, err.Error())
-		b.sendMessage(msg)
-		return
+		return nil, err
 	}
 	movies, err := r.GetMovie(0)
 	if err != nil {
-		msg := tgbotapi.NewMessage(userID, err.Error())
-		b.sendMessage(msg)
-		return
+		return nil, err
 	}
 
 	command := userLibrary{}
 	command.qualityProfiles = qualityProfiles
 	command.allTags = tags
 	command.library = movies
-	command.filter = ""
 	command.chatID = message.Chat.ID
 	command.messageID = message.MessageID
-
-	criteria := update.Message.CommandArguments()
-	// no search criteria --> show menu and return
-	if len(criteria) < 1 {
-		b.setLibraryState(userID, &command)
-		b.showLibraryMenu(&command)
-		return
-	}
-
-	searchResults, err := r.Lookup(criteria)
-	if err != nil {
-		msg := tgbotapi.NewMessage(userID, err.Error())
-		b.sendMessage(msg)
-		return
-	}
-
-	b.handleSearchResults(update, searchResults, &command)
-
+	return &command, nil
 }
 
 func (b *Bot) libraryMenu(update tgbotapi.Update) bool {
 	userID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot manage library: %v", err)
+		updateLogger(update).Warn("Cannot manage library", "error", err)
 		return false
 	}
 
@@ -106,6 +135,16 @@ func (b *Bot) libraryMenu(update tgbotapi.Update) bool {
 		b.clearState(update)
 		b.sendMessageWithEdit(command, CommandsCleared)
 		return false
+	case LibraryFilters:
+		return b.showLibraryFilters(command)
+	case LibrarySearchLookup:
+		b.sendMessageWithEdit(command, "Searching via Radarr lookup... please wait")
+		searchResults, err := b.getRadarrServer(command.chatID).Lookup(command.searchCriteria)
+		if err != nil {
+			return b.reportError(command, err, func() bool { return b.libraryMenu(update) })
+		}
+		b.handleSearchResults(update, searchResults, command)
+		return false
 	default:
 		command.filter = update.CallbackQuery.Data
 		b.setLibraryState(command.chatID, command)
@@ -126,13 +165,21 @@ func (b *Bot) showLibraryMenu(command *userLibrary) bool {
 			tgbotapi.NewInlineKeyboardButtonData("Movies on Disk", FilterOnDisk),
 			tgbotapi.NewInlineKeyboardButtonData("All Movies", FilterShowAll),
 		},
+		{
+			tgbotapi.NewInlineKeyboardButtonData("Cutoff Unmet", FilterCutoffUnmet),
+			tgbotapi.NewInlineKeyboardButtonData("Advanced filters & sort", LibraryFilters),
+		},
 		{
 			tgbotapi.NewInlineKeyboardButtonData("Cancel - clear command", LibraryCancel),
 		},
 	}
+	text := "Select an option:"
+	if descriptions := command.advancedFilter.descriptions(command); len(descriptions) > 0 {
+		text = fmt.Sprintf("Select an option:\nAdvanced filters: %s", strings.Join(descriptions, ", "))
+	}
 	command.page = 0
 	b.setLibraryState(command.chatID, command)
-	b.sendMessageWithEditAndKeyboard(command, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboard}, "Select an option:")
+	b.sendMessageWithEditAndKeyboard(command, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboard}, text)
 	return false
 }
 
@@ -147,60 +194,99 @@ func (b *Bot) showLibraryMenuFiltered(command *userLibrary) bool {
 			return movie.Monitored
 		})
 		command.filter = FilterMonitored
-		responseText = "Monitored Movies"
+		responseText = filterLabels[FilterMonitored]
 	case FilterUnmonitored:
 		filteredMovies = filterMovies(command.library, func(movie *radarr.Movie) bool {
 			return !movie.Monitored
 		})
 		command.filter = FilterUnmonitored
-		responseText = "Unmonitored Movies"
+		responseText = filterLabels[FilterUnmonitored]
 	case FilterMissing:
 		filteredMovies = filterMovies(command.library, func(movie *radarr.Movie) bool {
 			return movie.SizeOnDisk == 0 && movie.Monitored
 		})
 		command.filter = FilterMissing
-		responseText = "Missing Movies"
+		responseText = filterLabels[FilterMissing]
 	case FilterWanted:
 		filteredMovies = filterMovies(command.library, func(movie *radarr.Movie) bool {
 			return movie.SizeOnDisk == 0 && movie.Monitored && movie.IsAvailable
 		})
 		command.filter = FilterWanted
-		responseText = "Wanted Movies"
+		responseText = filterLabels[FilterWanted]
 	case FilterOnDisk:
 		filteredMovies = filterMovies(command.library, func(movie *radarr.Movie) bool {
 			return movie.SizeOnDisk > 0
 		})
 		command.filter = FilterOnDisk
-		responseText = "Movies on Disk"
+		responseText = filterLabels[FilterOnDisk]
 	case FilterShowAll:
 		filteredMovies = filterMovies(command.library, func(movie *radarr.Movie) bool {
 			return true // All movies included
 		})
 		command.filter = FilterShowAll
-		responseText = "All Movies"
+		responseText = filterLabels[FilterShowAll]
+	case FilterCutoffUnmet:
+		filteredMovies = filterMovies(command.library, cutoffUnmet(command.qualityProfiles))
+		responseText = filterLabels[FilterCutoffUnmet]
 	case FilterSearchResults:
 		filteredMovies = command.searchResultsInLibrary
 		command.filter = FilterSearchResults
-		responseText = "Search Results"
+		responseText = filterLabels[FilterSearchResults]
+	case FilterFindResults:
+		filteredMovies = command.searchResultsInLibrary
+		responseText = fmt.Sprintf("%s for '%s'", filterLabels[FilterFindResults], command.searchCriteria)
+	case FilterUpcoming:
+		filteredMovies = command.searchResultsInLibrary
+		responseText = fmt.Sprintf("%s in the %s", filterLabels[FilterUpcoming], command.upcoming.describe())
 	default:
 		command.filter = ""
 		b.setLibraryState(command.chatID, command)
 		return false
 	}
 
+	// search and find results are not filtered, everything else is narrowed down by the advanced filters
+	if !isResultList(command.filter) && command.advancedFilter.active() {
+		filteredMovies = command.advancedFilter.apply(filteredMovies, command.qualityProfiles)
+		responseText = fmt.Sprintf("%s (%s)", responseText, strings.Join(command.advancedFilter.descriptions(command), ", "))
+	}
+
+	// a bulk edit may have moved selected movies out of the list, they must not
+	// be part of the next bulk action
+	if len(command.bulkSelectedMovies) > 0 {
+		selected := make(map[int64]bool, len(command.bulkSelectedMovies))
+		for _, movie := range command.bulkSelectedMovies {
+			selected[movie.ID] = true
+		}
+		var visible []*radarr.Movie
+		for _, movie := range filteredMovies {
+			if selected[movie.ID] {
+				visible = append(visible, movie)
+			}
+		}
+		command.bulkSelectedMovies = visible
+	}
+
 	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
 	var row []tgbotapi.InlineKeyboardButton
 
 	if len(filteredMovies) == 0 {
 		responseText = "No movies found matching your filter criteria"
 		row = append(row, tgbotapi.NewInlineKeyboardButtonData("\U0001F519", LibraryFilteredGoBack))
+		if !isResultList(command.filter) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("Filter & sort", LibraryFilters))
+		}
 		inlineKeyboard = append(inlineKeyboard, row)
 	} else {
 
 		// Pagination parameters
 		page := command.page
-		pageSize := b.Config.MaxItems
+		pageSize := b.config().MaxItems
 		totalPages := (len(filteredMovies) + pageSize - 1) / pageSize
+		// the list might have shrunk, e.g. after a bulk edit
+		if page >= totalPages {
+			page = totalPages - 1
+			command.page = page
+		}
 
 		// Calculate start and end index for the current page
 		startIndex := page * pageSize
@@ -211,29 +297,52 @@ func (b *Bot) showLibraryMenuFiltered(command *userLibrary) bool {
 
 		responseText = fmt.Sprintf("%s - page %d/%d", responseText, page+1, totalPages)
 
-		sort.SliceStable(filteredMovies, func(i, j int) bool {
-			return utils.IgnoreArticles(strings.ToLower(filteredMovies[i].Title)) < utils.IgnoreArticles(strings.ToLower(filteredMovies[j].Title))
-		})
-		inlineKeyboard = b.getMoviesAsInlineKeyboard(filteredMovies[startIndex:endIndex])
+		command.advancedFilter.sort(filteredMovies)
+		if command.bulkSelectMode {
+			responseText = fmt.Sprintf("%s\n%d movie(s) selected", responseText, len(command.bulkSelectedMovies))
+			inlineKeyboard = b.getMoviesAsSelectableInlineKeyboard(filteredMovies[startIndex:endIndex], command.bulkSelectedMovies)
+		} else {
+			inlineKeyboard = b.getMoviesAsInlineKeyboard(filteredMovies[startIndex:endIndex])
+		}
+		if command.filter == FilterCutoffUnmet {
+			responseText += "\n" + cutoffUnmetDetails(filteredMovies[startIndex:endIndex], command.qualityProfiles)
+			if !command.lastSearch.IsZero() {
+				responseText += fmt.Sprintf("\n\nLast search: %s", command.lastSearch.Format("02 Jan 06 - 15:04"))
+			}
+		}
 
 		// Create pagination buttons
 		if len(filteredMovies) > pageSize {
-			paginationButtons := []tgbotapi.InlineKeyboardButton{}
-			if page > 0 {
-				paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("◀️", LibraryPreviousPage))
-			}
-			paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, totalPages), "current_page"))
-			if page+1 < totalPages {
-				paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("▶️", LibraryNextPage))
+			paginationButtons := b.createPaginationButtons(page, totalPages, LibraryFirstPage, LibraryPreviousPage, LibraryNextPage, LibraryLastPage)
+			inlineKeyboard = append(inlineKeyboard, paginationButtons)
+		}
+
+		if command.bulkSelectMode {
+			inlineKeyboard = append(inlineKeyboard, []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Select all (%d)", len(filteredMovies)), LibraryBulkSelectAll),
+				tgbotapi.NewInlineKeyboardButtonData("Select none", LibraryBulkSelectNone),
+			})
+			if len(command.bulkSelectedMovies) > 0 {
+				inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Bulk actions (%d)", len(command.bulkSelectedMovies)), LibraryBulkActions),
+				))
 			}
-			if page != 0 {
-				paginationButtons = append([]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData("⏮️", LibraryFirstPage)}, paginationButtons...)
+			inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Done selecting", LibraryBulkSelectMode),
+			))
+		} else {
+			row := tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Select movies", LibraryBulkSelectMode),
+			)
+			if !isResultList(command.filter) {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("Filter & sort", LibraryFilters))
 			}
-			if page+1 != totalPages {
-				paginationButtons = append(paginationButtons, tgbotapi.NewInlineKeyboardButtonData("⏭️", LibraryLastPage))
+			inlineKeyboard = append(inlineKeyboard, row)
+			if command.filter == FilterCutoffUnmet {
+				inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Search all shown (%d)", len(filteredMovies)), LibrarySearchAllShown),
+				))
 			}
-
-			inlineKeyboard = append(inlineKeyboard, paginationButtons)
 		}
 
 		row = append(row, tgbotapi.NewInlineKeyboardButtonData("\U0001F519", LibraryFilteredGoBack))
@@ -261,6 +370,12 @@ func (b *Bot) showLibraryMenuFiltered(command *userLibrary) bool {
 	return false
 }
 
+// isResultList reports whether the list shows search, find or calendar results instead
+// of a filtered library.
+func isResultList(filter string) bool {
+	return filter == FilterSearchResults || filter == FilterFindResults || filter == FilterUpcoming
+}
+
 func filterMovies(movies []*radarr.Movie, filterCondition func(movie *radarr.Movie) bool) []*radarr.Movie {
 	var filtered []*radarr.Movie
 	for _, movie := range movies {
@@ -282,10 +397,14 @@ func (b *Bot) handleSearchResults(update tgbotapi.Update, searchResults []*radar
 	}
 
 	// if movie has a radarr ID, it's in the library
+	libraryByID := make(map[int64]*radarr.Movie, len(command.library))
+	for _, movie := range command.library {
+		libraryByID[movie.ID] = movie
+	}
 	var moviesInLibrary []*radarr.Movie
 	for _, movie := range searchResults {
-		if movie.ID != 0 {
-			moviesInLibrary = append(moviesInLibrary, movie)
+		if libraryMovie, exists := libraryByID[movie.ID]; movie.ID != 0 && exists {
+			moviesInLibrary = append(moviesInLibrary, libraryMovie)
 		}
 	}
 	if len(moviesInLibrary) == 0 {
@@ -293,6 +412,10 @@ func (b *Bot) handleSearchResults(update tgbotapi.Update, searchResults []*radar
 		return
 	}
 
+	b.showLibrarySearchResults(update, moviesInLibrary, command)
+}
+
+func (b *Bot) showLibrarySearchResults(update tgbotapi.Update, moviesInLibrary []*radarr.Movie, command *userLibrary) {
 	command.searchResultsInLibrary = moviesInLibrary
 
 	// go to movie details
@@ -22,7 +22,7 @@ const (
 func (b *Bot) libraryMovieEdit(update tgbotapi.Update) bool {
 	chatID, err := b.getChatID(update)
 	if err != nil {
-		fmt.Printf("Cannot manage library: %v", err)
+		updateLogger(update).Warn("Cannot manage library", "error", err)
 		return false
 	}
 
@@ -134,7 +134,7 @@ func (b *Bot) handleLibraryMovieEditSelectTag(update tgbotapi.Update, command *u
 	// Parse the tag ID
 	tagID, err := strconv.Atoi(tagIDStr)
 	if err != nil {
-		fmt.Printf("Cannot convert tag string to int: %v", err)
+		b.logger(command.chatID).Warn("Cannot convert tag string to int", "error", err)
 		return false
 	}
 
@@ -178,11 +178,9 @@ func (b *Bot) handleLibraryMovieEditSubmitChanges(update tgbotapi.Update, comman
 		}
 	}
 
-	_, err := b.RadarrServer.EditMovies(&bulkEdit)
+	_, err := b.getRadarrServer(command.chatID).EditMovies(&bulkEdit)
 	if err != nil {
-		msg := tgbotapi.NewMessage(command.chatID, err.Error())
-		b.sendMessage(msg)
-		return false
+		return b.reportError(command, err, func() bool { return b.handleLibraryMovieEditSubmitChanges(update, command) })
 	}
 	text := fmt.Sprintf("Movie '%v' updated\n", command.movie.Title)
 	b.clearState(update)
@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golift.io/starr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/metrics"
+)
+
+var (
+	updatesTotal       = metrics.NewCounter("radarrbot_updates_total", "Telegram updates handled, by type.", "type")
+	commandsTotal      = metrics.NewCounter("radarrbot_commands_total", "Commands handled, by command.", "command")
+	callbacksTotal     = metrics.NewCounter("radarrbot_callbacks_total", "Button presses handled, by action.", "action")
+	sendErrorsTotal    = metrics.NewCounter("radarrbot_telegram_send_errors_total", "Messages which couldn't be sent or edited.")
+	apiRequestDuration = metrics.NewHistogram("radarrbot_api_request_duration_seconds", "Latency of Radarr and Sonarr requests.",
+		metrics.DefaultBuckets, "instance", "method", "endpoint")
+	apiRequestErrors = metrics.NewCounter("radarrbot_api_request_errors_total", "Failed Radarr and Sonarr requests.",
+		"instance", "method", "endpoint")
+	radarrMovies    = metrics.NewGauge("radarrbot_radarr_movies", "Movies in the Radarr library.", "instance")
+	radarrMissing   = metrics.NewGauge("radarrbot_radarr_missing_movies", "Monitored and available movies without a file.", "instance")
+	radarrQueue     = metrics.NewGauge("radarrbot_radarr_queue_size", "Downloads in the Radarr queue.", "instance")
+	radarrFreeSpace = metrics.NewGauge("radarrbot_radarr_root_folder_free_bytes", "Free space of the Radarr root folders.", "instance", "path")
+)
+
+// callbackAction returns the action of the callback data for the metrics,
+// the upper case prefix without IDs or names: "REMIND_ME_603_digital_HD" is
+// remind_me. Data without such a prefix is counted as none.
+func callbackAction(data string) string {
+	end := strings.IndexFunc(data, func(r rune) bool { return r != '_' && (r < 'A' || r > 'Z') })
+	if end >= 0 {
+		// a value may start with capitals, like a genre, keep whole words only
+		end = strings.LastIndex(data[:end], "_") + 1
+		data = data[:end]
+	}
+	if action := strings.Trim(data, "_"); action != "" {
+		return strings.ToLower(action)
+	}
+	return "none"
+}
+
+// idSegment matches IDs in API paths, so /api/v3/movie/12 is counted as /movie/{id}.
+var idSegment = regexp.MustCompile(`/\d+(/|$)`)
+
+// instrumentedTransport measures the requests to a Radarr or Sonarr instance.
+type instrumentedTransport struct {
+	instance string
+	next     http.RoundTripper
+}
+
+// InstrumentClient records the latency and errors of the requests of the
+// client in the metrics.
+func InstrumentClient(config *starr.Config, instance string) {
+	next := config.Client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	config.Client.Transport = &instrumentedTransport{instance: instance, next: next}
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := apiEndpoint(req.URL.Path)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	apiRequestDuration.Observe(time.Since(start).Seconds(), t.instance, req.Method, endpoint)
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		apiRequestErrors.Inc(t.instance, req.Method, endpoint)
+	}
+	return resp, err
+}
+
+// apiEndpoint returns the path without base URL, API version and IDs.
+func apiEndpoint(path string) string {
+	if _, after, found := strings.Cut(path, "/api/"); found {
+		path = after
+		if _, endpoint, found := strings.Cut(path, "/"); found && strings.HasPrefix(path, "v") {
+			path = endpoint
+		}
+		path = "/" + path
+	}
+	for idSegment.MatchString(path) {
+		path = idSegment.ReplaceAllString(path, "/{id}$1")
+	}
+	return path
+}
+
+// RegisterMetrics adds the gauges of the bot's state, like the number of
+// active sessions.
+func (b *Bot) RegisterMetrics() {
+	metrics.NewGaugeFunc("radarrbot_sessions", "Active sessions, by state.", []string{"state"},
+		func(set func(float64, ...string)) {
+			set(float64(countState(&b.muActiveCommand, b.ActiveCommand)), "command")
+			set(float64(countState(&b.muAddMovieStates, b.AddMovieStates)), "add_movie")
+			set(float64(countState(&b.muDeleteMovieStates, b.DeleteMovieStates)), "delete_movie")
+			set(float64(countState(&b.muLibraryStates, b.LibraryStates)), "library")
+			set(float64(countState(&b.muBulkAddStates, b.BulkAddStates)), "bulk_add")
+			set(float64(countState(&b.muCollectionStates, b.CollectionStates)), "collection")
+			set(float64(countState(&b.muAddSeriesStates, b.AddSeriesStates)), "add_series")
+			set(float64(countState(&b.muSeriesStates, b.SeriesStates)), "series_library")
+			set(float64(countState(&b.muDigestStates, b.DigestStates)), "digest")
+			set(float64(countState(&b.muCalendarStates, b.CalendarStates)), "calendar")
+			set(float64(countState(&b.muPendingDeletions, b.PendingDeletions)), "pending_deletion")
+		})
+}
+
+func countState[K comparable, V any](mu sync.Locker, states map[K]V) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(states)
+}
+
+// CollectMetrics updates the Radarr gauges every interval.
+func (b *Bot) CollectMetrics(interval time.Duration) {
+	for {
+		for _, instance := range b.RadarrServers {
+			b.collectRadarrMetrics(instance)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// collectRadarrMetrics updates the gauges of the instance, failed requests
+// are counted by the instrumented client and the previous values are kept.
+func (b *Bot) collectRadarrMetrics(instance *RadarrInstance) {
+	logger := slog.With("instance", instance.Name)
+	movies, err := instance.Server.GetMovie(0)
+	if err != nil {
+		logger.Debug("Cannot collect library metrics", "error", err)
+	} else {
+		missing := 0
+		for _, movie := range movies {
+			if movie.Monitored && !movie.HasFile && movie.IsAvailable {
+				missing++
+			}
+		}
+		radarrMovies.Set(float64(len(movies)), instance.Name)
+		radarrMissing.Set(float64(missing), instance.Name)
+	}
+
+	queue, err := instance.Server.GetQueuePage(&starr.PageReq{PageSize: 1, Page: 1})
+	if err != nil {
+		logger.Debug("Cannot collect queue metrics", "error", err)
+	} else {
+		radarrQueue.Set(float64(queue.TotalRecords), instance.Name)
+	}
+
+	rootFolders, err := instance.Server.GetRootFolders()
+	if err != nil {
+		logger.Debug("Cannot collect root folder metrics", "error", err)
+	} else {
+		radarrFreeSpace.Reset(instance.Name)
+		for _, rootFolder := range rootFolders {
+			radarrFreeSpace.Set(float64(rootFolder.FreeSpace), instance.Name, rootFolder.Path)
+		}
+	}
+}
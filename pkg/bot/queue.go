@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+// queueMaxItems is how many downloads are listed per instance.
+const queueMaxItems = 25
+
+// sendQueue lists the downloads of the instances with their progress.
+func (b *Bot) sendQueue(chatID int64, instances []*RadarrInstance) {
+	var sections []string
+	for _, instance := range instances {
+		queue, err := instance.Server.GetQueue(0, 100)
+		if err != nil {
+			b.sendError(chatID, b.instanceLabel(instance), err)
+			continue
+		}
+		var text strings.Builder
+		if len(b.RadarrServers) > 1 {
+			fmt.Fprintf(&text, "*%s*\n", utils.Escape(instance.Name))
+		}
+		if len(queue.Records) == 0 {
+			text.WriteString("The queue is empty")
+			sections = append(sections, text.String())
+			continue
+		}
+		fmt.Fprintf(&text, "*Queue: %d download\\(s\\)*", len(queue.Records))
+		sections = append(sections, text.String())
+		for i, record := range queue.Records {
+			if i == queueMaxItems {
+				sections = append(sections, utils.Escape(fmt.Sprintf("... and %d more", len(queue.Records)-i)))
+				break
+			}
+			sections = append(sections, queueRecord(record))
+		}
+	}
+	if len(sections) > 0 {
+		msg := tgbotapi.NewMessage(chatID, "")
+		b.sendStats(sections, &msg)
+	}
+}
+
+// queueRecord formats a download: release, progress, state and warnings.
+func queueRecord(record *radarr.QueueRecord) string {
+	icon := "\u2B07\uFE0F" // Down arrow
+	switch record.TrackedDownloadStatus {
+	case "warning":
+		icon = "\u26A0\uFE0F" // Warning sign
+	case "error":
+		icon = "\u274C" // Red X
+	}
+	details := []string{record.Status}
+	if record.TrackedDownloadState != "" && record.TrackedDownloadState != "downloading" {
+		details = append(details, record.TrackedDownloadState)
+	}
+	if record.Size > 0 {
+		details = append(details, fmt.Sprintf("%.0f%% of %s", (record.Size-record.Sizeleft)*100/record.Size, utils.ByteCountSI(int64(record.Size))))
+	}
+	if record.Timeleft != "" {
+		details = append(details, record.Timeleft+" left")
+	}
+	if record.Quality != nil && record.Quality.Quality != nil {
+		details = append(details, record.Quality.Quality.Name)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s %s\n%s", icon, utils.Escape(record.Title), utils.Escape(strings.Join(details, ", ")))
+	if record.ErrorMessage != "" {
+		text.WriteString("\n" + utils.Escape(record.ErrorMessage))
+	}
+	for _, status := range record.StatusMessages {
+		for _, message := range status.Messages {
+			text.WriteString("\n_" + utils.Escape(message) + "_")
+		}
+	}
+	return text.String()
+}
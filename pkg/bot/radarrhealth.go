@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+const healthIssuesStoreKey = "health_issues"
+
+var healthIcons = map[string]string{
+	"error":   "\u274C",       // Red X
+	"warning": "\u26A0\uFE0F", // Warning sign
+	"notice":  "\u2139\uFE0F", // Information
+}
+
+func (b *Bot) loadHealthIssues() {
+	if err := b.Store.Load(healthIssuesStoreKey, &b.HealthIssues); err != nil {
+		slog.Error("Cannot load health issues", "error", err)
+	}
+	if b.HealthIssues == nil {
+		b.HealthIssues = make(map[string]*radarrapi.Health)
+	}
+}
+
+// healthIssueKey identifies an issue across polls, Radarr has no IDs for them.
+func healthIssueKey(instance string, issue *radarrapi.Health) string {
+	return instance + "|" + issue.Source + "|" + issue.Message
+}
+
+// healthIssues returns the issues of the health check, without "ok" entries.
+func healthIssues(health []*radarrapi.Health) []*radarrapi.Health {
+	var issues []*radarrapi.Health
+	for _, issue := range health {
+		if issue.Type != "ok" {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func healthIcon(issue *radarrapi.Health) string {
+	if icon, exists := healthIcons[issue.Type]; exists {
+		return icon
+	}
+	return healthIcons["notice"]
+}
+
+// sendHealth lists the current health issues of the instances.
+func (b *Bot) sendHealth(chatID int64, instances []*RadarrInstance) {
+	for _, instance := range instances {
+		health, err := radarrapi.GetHealth(instance.Server)
+		if err != nil {
+			b.sendError(chatID, b.instanceLabel(instance), err)
+			continue
+		}
+		issues := healthIssues(health)
+
+		var text strings.Builder
+		if len(b.RadarrServers) > 1 {
+			fmt.Fprintf(&text, "*%s*\n", utils.Escape(instance.Name))
+		}
+		if len(issues) == 0 {
+			text.WriteString(MonitorIcon + " No health issues")
+		}
+		for i, issue := range issues {
+			if i > 0 {
+				text.WriteString("\n\n")
+			}
+			fmt.Fprintf(&text, "%s *%s*: %s", healthIcon(issue), utils.Escape(issue.Type), utils.Escape(issue.Message))
+			if issue.WikiURL != "" {
+				fmt.Fprintf(&text, " [wiki](%s)", strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(issue.WikiURL))
+			}
+		}
+		msg := tgbotapi.NewMessage(chatID, text.String())
+		msg.ParseMode = "MarkdownV2"
+		msg.DisableWebPagePreview = true
+		b.sendMessage(msg)
+	}
+}
+
+// RunHealthMonitor notifies the admins about new and resolved Radarr health
+// issues every RBOT_HEALTH_ALERT_INTERVAL.
+func (b *Bot) RunHealthMonitor() {
+	for {
+		interval := b.config().HealthInterval
+		if interval == 0 {
+			// disabled, check again later in case a reload enables it
+			time.Sleep(time.Minute)
+			continue
+		}
+		for _, instance := range b.RadarrServers {
+			b.checkHealth(instance)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// checkHealth compares the issues with the ones of the last poll, only
+// changes are reported. A failed request keeps the previous issues.
+func (b *Bot) checkHealth(instance *RadarrInstance) {
+	health, err := radarrapi.GetHealth(instance.Server)
+	if err != nil {
+		slog.Warn("Cannot check health", "instance", instance.Name, "error", err)
+		return
+	}
+
+	issues := healthIssues(health)
+	current := make(map[string]*radarrapi.Health, len(issues))
+	for _, issue := range issues {
+		current[healthIssueKey(instance.Name, issue)] = issue
+	}
+
+	var added, resolved []*radarrapi.Health
+	b.muHealthIssues.Lock()
+	for _, issue := range issues {
+		key := healthIssueKey(instance.Name, issue)
+		if _, exists := b.HealthIssues[key]; !exists {
+			added = append(added, issue)
+			b.HealthIssues[key] = issue
+		}
+	}
+	for key, issue := range b.HealthIssues {
+		if _, exists := current[key]; !exists && strings.HasPrefix(key, instance.Name+"|") {
+			resolved = append(resolved, issue)
+			delete(b.HealthIssues, key)
+		}
+	}
+	if len(added) > 0 || len(resolved) > 0 {
+		if err := b.Store.Save(healthIssuesStoreKey, b.HealthIssues); err != nil {
+			slog.Error("Cannot save health issues", "error", err)
+		}
+	}
+	b.muHealthIssues.Unlock()
+
+	if len(added) == 0 && len(resolved) == 0 {
+		return
+	}
+	slog.Info("Health changed", "instance", instance.Name, "new", len(added), "resolved", len(resolved))
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%sRadarr health changed", b.instanceLabel(instance))
+	for _, issue := range added {
+		fmt.Fprintf(&text, "\n\n%s %s: %s", healthIcon(issue), issue.Type, issue.Message)
+		if issue.WikiURL != "" {
+			text.WriteString("\n" + issue.WikiURL)
+		}
+	}
+	for _, issue := range resolved {
+		fmt.Fprintf(&text, "\n\n%s Resolved: %s", MonitorIcon, issue.Message)
+	}
+	for chatID := range b.config().AdminChats() {
+		msg := tgbotapi.NewMessage(chatID, text.String())
+		msg.DisableWebPagePreview = true
+		b.sendMessage(msg)
+	}
+}
@@ -0,0 +1,320 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+)
+
+const (
+	ReminderToggle    = "REMIND_ME_"
+	ReminderCancel    = "REMINDER_CANCEL_"
+	remindersStoreKey = "reminders"
+	// reminderInterval is how often due reminders are sent.
+	reminderInterval = 15 * time.Minute
+	// reminderRecheck is how often the release dates of pending reminders
+	// are checked, they often shift.
+	reminderRecheck = 24 * time.Hour
+)
+
+// reminder is a pending release reminder of a chat, saved in the data file.
+type reminder struct {
+	ChatID   int64     `json:"chatId"`
+	Instance string    `json:"instance"`
+	TmdbID   int64     `json:"tmdbId"`
+	Title    string    `json:"title"`
+	Year     int       `json:"year"`
+	Kind     string    `json:"kind"` // cinema, digital or physical
+	Date     time.Time `json:"date"`
+	Checked  time.Time `json:"checked"`
+}
+
+func reminderKey(chatID, tmdbID int64, kind string) string {
+	return fmt.Sprintf("%d_%d_%s", chatID, tmdbID, kind)
+}
+
+func (r *reminder) describe() string {
+	return fmt.Sprintf("%v (%v) %s release %s", r.Title, r.Year, r.Kind, r.Date.Format("02 Jan 2006"))
+}
+
+func (b *Bot) loadReminders() {
+	if err := b.Store.Load(remindersStoreKey, &b.Reminders); err != nil {
+		slog.Error("Cannot load reminders", "error", err)
+	}
+	if b.Reminders == nil {
+		b.Reminders = make(map[string]*reminder)
+	}
+}
+
+// saveReminders writes the reminders, the caller holds muReminders.
+func (b *Bot) saveReminders() {
+	if err := b.Store.Save(remindersStoreKey, b.Reminders); err != nil {
+		slog.Error("Cannot save reminders", "error", err)
+	}
+}
+
+// today returns the current day in UTC, like the release dates.
+func today() time.Time {
+	year, month, day := time.Now().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// noButtons returns an empty keyboard, which removes the buttons of an
+// edited message.
+func noButtons() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+}
+
+// reminderButtons returns a button per upcoming release of the movie, which
+// sets or cancels a reminder. The buttons name the instance, its index may
+// change with a reload.
+func (b *Bot) reminderButtons(chatID int64, instance *RadarrInstance, movie *radarr.Movie) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	b.muReminders.Lock()
+	defer b.muReminders.Unlock()
+	for _, kind := range releaseKinds {
+		date := releaseDate(movie, kind)
+		if date.IsZero() || date.Before(today()) {
+			continue
+		}
+		label := fmt.Sprintf("Remind me: %s %s", kind, date.Format("02 Jan 2006"))
+		if _, exists := b.Reminders[reminderKey(chatID, movie.TmdbID, kind)]; exists {
+			label = fmt.Sprintf("\U0001F514 Cancel reminder: %s %s", kind, date.Format("02 Jan 2006"))
+		}
+		data := fmt.Sprintf("%s%d_%s_%s", ReminderToggle, movie.TmdbID, kind, instance.Name)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, data)))
+	}
+	return rows
+}
+
+// handleReminderToggle sets or cancels a reminder. The buttons are shown
+// after adding a movie and in the library, they outlive the command.
+func (b *Bot) handleReminderToggle(update tgbotapi.Update) {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot toggle reminder", "error", err)
+		return
+	}
+	// the instance name comes last, it may contain underscores
+	parts := strings.SplitN(strings.TrimPrefix(update.CallbackQuery.Data, ReminderToggle), "_", 3)
+	if len(parts) != 3 {
+		b.logger(chatID).Warn("Cannot toggle reminder", "error", "invalid callback data")
+		return
+	}
+	tmdbID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		b.logger(chatID).Warn("Cannot toggle reminder", "error", err)
+		return
+	}
+	kind := parts[1]
+	instance := b.instanceByName(parts[2])
+	if instance == nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("The Radarr instance %q doesn't exist anymore", parts[2]))
+		b.sendMessage(msg)
+		return
+	}
+
+	movies, err := instance.Server.GetMovie(tmdbID)
+	if err != nil {
+		b.sendError(chatID, b.instanceLabel(instance), err)
+		return
+	}
+	if len(movies) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "The movie is no longer in the library")
+		b.sendMessage(msg)
+		return
+	}
+	movie := movies[0]
+
+	key := reminderKey(chatID, tmdbID, kind)
+	b.muReminders.Lock()
+	if _, exists := b.Reminders[key]; exists {
+		delete(b.Reminders, key)
+	} else if date := releaseDate(movie, kind); !date.IsZero() && !date.Before(today()) {
+		b.Reminders[key] = &reminder{
+			ChatID:   chatID,
+			Instance: instance.Name,
+			TmdbID:   movie.TmdbID,
+			Title:    movie.Title,
+			Year:     movie.Year,
+			Kind:     kind,
+			Date:     date,
+			Checked:  time.Now(),
+		}
+	}
+	b.saveReminders()
+	b.muReminders.Unlock()
+
+	// redraw the library detail view, or just the buttons of the added movie
+	message := update.CallbackQuery.Message
+	if command, exists := b.getLibraryState(chatID); exists && command.messageID == message.MessageID && command.movie != nil {
+		b.showLibraryMovieDetail(update, command)
+		return
+	}
+	keyboard := noButtons()
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, b.reminderButtons(chatID, instance, movie)...)
+	editMsg := tgbotapi.NewEditMessageReplyMarkup(chatID, message.MessageID, keyboard)
+	b.sendMessage(editMsg)
+}
+
+// processRemindersCommand lists the pending reminders of the chat.
+func (b *Bot) processRemindersCommand(chatID int64) {
+	text, keyboard := b.remindersList(chatID)
+	msg := tgbotapi.NewMessage(chatID, text)
+	if len(keyboard.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = keyboard
+	}
+	b.sendMessage(msg)
+}
+
+func (b *Bot) remindersList(chatID int64) (string, tgbotapi.InlineKeyboardMarkup) {
+	b.muReminders.Lock()
+	var reminders []*reminder
+	for _, reminder := range b.Reminders {
+		if reminder.ChatID == chatID {
+			reminders = append(reminders, reminder)
+		}
+	}
+	b.muReminders.Unlock()
+
+	if len(reminders) == 0 {
+		return "No pending reminders, set them with the \"Remind me\" buttons of a movie", noButtons()
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		if !reminders[i].Date.Equal(reminders[j].Date) {
+			return reminders[i].Date.Before(reminders[j].Date)
+		}
+		return reminders[i].Title < reminders[j].Title
+	})
+
+	var text strings.Builder
+	text.WriteString("Pending reminders:\n")
+	var buttonLabels, buttonData []string
+	for _, reminder := range reminders {
+		fmt.Fprintf(&text, "\n%s", reminder.describe())
+		buttonLabels = append(buttonLabels, fmt.Sprintf("Cancel: %v - %s", reminder.Title, reminder.Kind))
+		buttonData = append(buttonData, fmt.Sprintf("%s%d_%s", ReminderCancel, reminder.TmdbID, reminder.Kind))
+	}
+	return text.String(), b.createKeyboard(buttonLabels, buttonData)
+}
+
+// handleReminderCancel cancels a reminder of the /reminders list.
+func (b *Bot) handleReminderCancel(update tgbotapi.Update) {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot cancel reminder", "error", err)
+		return
+	}
+	tmdbID, kind, found := strings.Cut(strings.TrimPrefix(update.CallbackQuery.Data, ReminderCancel), "_")
+	id, err := strconv.ParseInt(tmdbID, 10, 64)
+	if !found || err != nil {
+		b.logger(chatID).Warn("Cannot cancel reminder", "error", "invalid callback data")
+		return
+	}
+
+	b.muReminders.Lock()
+	delete(b.Reminders, reminderKey(chatID, id, kind))
+	b.saveReminders()
+	b.muReminders.Unlock()
+
+	text, keyboard := b.remindersList(chatID)
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(chatID, update.CallbackQuery.Message.MessageID, text, keyboard)
+	b.sendMessage(editMsg)
+}
+
+// RunReminders sends the due reminders and rechecks the release dates.
+func (b *Bot) RunReminders() {
+	for {
+		b.checkReminders()
+		time.Sleep(reminderInterval)
+	}
+}
+
+func (b *Bot) checkReminders() {
+	b.muReminders.Lock()
+	var pending []reminder
+	for _, reminder := range b.Reminders {
+		if !reminder.Date.After(today()) || time.Since(reminder.Checked) > reminderRecheck {
+			pending = append(pending, *reminder)
+		}
+	}
+	b.muReminders.Unlock()
+
+	for _, reminder := range pending {
+		// the reminders of a chat removed by a reload stay pending
+		if !b.isAllowed(reminder.ChatID) {
+			continue
+		}
+		b.checkReminder(reminder)
+	}
+}
+
+// checkReminder looks up the current release date, sends the reminder if
+// it's due and otherwise moves it to the new date.
+func (b *Bot) checkReminder(reminder reminder) {
+	logger := chatLogger(reminder.ChatID).With("tmdb_id", reminder.TmdbID, "kind", reminder.Kind)
+	key := reminderKey(reminder.ChatID, reminder.TmdbID, reminder.Kind)
+	instance := b.instanceByName(reminder.Instance)
+	if instance == nil {
+		// the instance was renamed or removed, any other one may have the movie
+		instance = b.RadarrServers[0]
+	}
+	movies, err := instance.Server.GetMovie(reminder.TmdbID)
+	if err != nil {
+		logger.Warn("Cannot check reminder, retrying later", "error", err)
+		return
+	}
+
+	var text string
+	var date time.Time
+	if len(movies) > 0 {
+		date = releaseDate(movies[0], reminder.Kind)
+	}
+	switch {
+	case len(movies) == 0:
+		text = fmt.Sprintf("Reminder cancelled, %v (%v) was removed from Radarr", reminder.Title, reminder.Year)
+	case date.IsZero():
+		text = fmt.Sprintf("Reminder cancelled, %v (%v) has no %s release date anymore", reminder.Title, reminder.Year, reminder.Kind)
+	case !date.After(today()):
+		// the date may have passed while the bot was down or been moved into the past
+		released := "release today"
+		if date.Before(today()) {
+			released = "released on " + date.Format("02 Jan 2006")
+		}
+		text = fmt.Sprintf("\U0001F514 %v (%v): %s %s\nhttps://www.themoviedb.org/movie/%d", reminder.Title, reminder.Year, reminder.Kind, released, reminder.TmdbID)
+		if movies[0].HasFile {
+			text += "\nIt's already in your library."
+		}
+	}
+
+	b.muReminders.Lock()
+	current, exists := b.Reminders[key]
+	if !exists {
+		// cancelled in the meantime
+		b.muReminders.Unlock()
+		return
+	}
+	if text == "" {
+		moved := !date.Equal(current.Date)
+		current.Date = date
+		current.Checked = time.Now()
+		b.saveReminders()
+		b.muReminders.Unlock()
+		if moved {
+			logger.Info("Release date moved", "date", date)
+			text = fmt.Sprintf("The %s release of %v (%v) moved to %s, the reminder moved with it", reminder.Kind, reminder.Title, reminder.Year, date.Format("02 Jan 2006"))
+			b.sendMessage(tgbotapi.NewMessage(reminder.ChatID, text))
+		}
+		return
+	}
+	delete(b.Reminders, key)
+	b.saveReminders()
+	b.muReminders.Unlock()
+	b.sendMessage(tgbotapi.NewMessage(reminder.ChatID, text))
+}
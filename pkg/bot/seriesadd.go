@@ -0,0 +1,533 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr/sonarr"
+)
+
+const (
+	AddSeriesTVDBID           = "ADDSERIES_TVDBID_"
+	AddSeriesYes              = "ADDSERIES_YES"
+	AddSeriesGoBack           = "ADDSERIES_GOBACK"
+	AddSeriesProfile          = "ADDSERIES_PROFILE_"
+	AddSeriesProfileGoBack    = "ADDSERIES_PROFILE_GOBACK"
+	AddSeriesRootFolder       = "ADDSERIES_ROOTFOLDER_"
+	AddSeriesRootFolderGoBack = "ADDSERIES_ROOTFOLDER_GOBACK"
+	AddSeriesTag              = "ADDSERIES_TAG_"
+	AddSeriesTagsDone         = "ADDSERIES_TAGS_DONE"
+	AddSeriesTagsGoBack       = "ADDSERIES_TAGS_GOBACK"
+	AddSeriesType             = "ADDSERIES_TYPE_"
+	AddSeriesTypeGoBack       = "ADDSERIES_TYPE_GOBACK"
+	AddSeriesMonitor          = "ADDSERIES_MONITOR_"
+	AddSeriesMonitorGoBack    = "ADDSERIES_MONITOR_GOBACK"
+	AddSeriesSearchGoBack     = "ADDSERIES_SEARCH_GOBACK"
+	AddSeriesSearch           = "ADDSERIES_SEARCH"
+	AddSeriesNoSearch         = "ADDSERIES_NOSEARCH"
+	AddSeriesCancel           = "ADDSERIES_CANCEL"
+)
+
+// seriesTypes are the series types of Sonarr, they decide how episodes are numbered.
+var seriesTypes = []string{"standard", "daily", "anime"}
+
+// seriesMonitorOptions are the choices which seasons and episodes of a new
+// series are monitored, in the order they are shown.
+var seriesMonitorOptions = []struct {
+	key   string
+	label string
+}{
+	{"all", "All episodes"},
+	{"future", "Future episodes"},
+	{"missing", "Missing episodes"},
+	{"existing", "Existing episodes"},
+	{"firstSeason", "First season"},
+	{"latestSeason", "Latest season"},
+	{"none", "None"},
+}
+
+func (b *Bot) processAddSeriesCommand(update tgbotapi.Update, chatID int64, s *sonarr.Sonarr) {
+	msg := tgbotapi.NewMessage(chatID, "Handling add series command... please wait")
+	message, _ := b.sendMessage(msg)
+	command := userAddSeries{
+		chatID:    message.Chat.ID,
+		messageID: message.MessageID,
+	}
+
+	criteria := update.Message.CommandArguments()
+	if len(criteria) < 1 {
+		b.sendMessageWithEdit(&command, "Please provide a search criteria /tv [query]")
+		return
+	}
+	searchResults, err := s.Lookup(criteria)
+	if err != nil {
+		b.reportError(&command, err, func() bool {
+			b.processAddSeriesCommand(update, chatID, s)
+			return false
+		})
+		return
+	}
+
+	if len(searchResults) == 0 {
+		b.sendMessageWithEdit(&command, "No series found matching your search criteria")
+		return
+	}
+	if len(searchResults) > 25 {
+		b.sendMessageWithEdit(&command, "Result size too large, please narrow down your search criteria")
+		return
+	}
+
+	command.searchResults = make(map[string]*sonarr.Series, len(searchResults))
+	for _, series := range searchResults {
+		tvdbID := strconv.Itoa(int(series.TvdbID))
+		command.searchResults[tvdbID] = series
+	}
+
+	b.setAddSeriesState(command.chatID, &command)
+	b.setActiveCommand(command.chatID, AddSeriesCommand)
+	b.showAddSeriesSearchResults(&command)
+}
+
+func (b *Bot) addSeries(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot add series", "error", err)
+		return false
+	}
+	command, exists := b.getAddSeriesState(chatID)
+	if !exists {
+		return false
+	}
+	data := update.CallbackQuery.Data
+	switch data {
+	case AddSeriesYes:
+		return b.handleAddSeriesYes(update, command)
+	case AddSeriesGoBack, AddSeriesProfileGoBack:
+		return b.showAddSeriesSearchResults(command)
+	case AddSeriesRootFolderGoBack:
+		return b.showAddSeriesProfilesBack(command)
+	case AddSeriesTagsGoBack:
+		return b.showAddSeriesRootFoldersBack(command)
+	case AddSeriesTypeGoBack:
+		if len(command.allTags) == 0 || b.config().IgnoreTags {
+			return b.showAddSeriesRootFoldersBack(command)
+		}
+		return b.showAddSeriesTags(command)
+	case AddSeriesMonitorGoBack:
+		return b.showAddSeriesTypes(command)
+	case AddSeriesSearchGoBack:
+		return b.showAddSeriesMonitor(command)
+	case AddSeriesTagsDone:
+		return b.showAddSeriesTypes(command)
+	case AddSeriesSearch:
+		return b.addSeriesToLibrary(update, command, true)
+	case AddSeriesNoSearch:
+		return b.addSeriesToLibrary(update, command, false)
+	case AddSeriesCancel:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(data, AddSeriesTVDBID):
+		command.series = command.searchResults[strings.TrimPrefix(data, AddSeriesTVDBID)]
+		if command.series == nil {
+			return b.showAddSeriesSearchResults(command)
+		}
+		return b.showAddSeriesDetails(command)
+	case strings.HasPrefix(data, AddSeriesProfile):
+		profileID, err := strconv.ParseInt(strings.TrimPrefix(data, AddSeriesProfile), 10, 64)
+		if err != nil {
+			msg := tgbotapi.NewMessage(command.chatID, err.Error())
+			b.logger(command.chatID).Warn("Cannot convert quality profile ID to int", "error", err)
+			b.sendMessage(msg)
+			return false
+		}
+		command.profileID = profileID
+		return b.showAddSeriesRootFolders(command)
+	case strings.HasPrefix(data, AddSeriesRootFolder):
+		return b.handleAddSeriesRootFolder(data, command)
+	case strings.HasPrefix(data, AddSeriesTag):
+		tagID, err := strconv.Atoi(strings.TrimPrefix(data, AddSeriesTag))
+		if err != nil {
+			b.logger(command.chatID).Warn("Cannot convert tag string to int", "error", err)
+			return false
+		}
+		if isSelectedTag(command.selectedTags, tagID) {
+			command.selectedTags = removeTag(command.selectedTags, tagID)
+		} else {
+			command.selectedTags = append(command.selectedTags, tagID)
+		}
+		return b.showAddSeriesTags(command)
+	case strings.HasPrefix(data, AddSeriesType):
+		command.seriesType = strings.TrimPrefix(data, AddSeriesType)
+		return b.showAddSeriesMonitor(command)
+	case strings.HasPrefix(data, AddSeriesMonitor):
+		command.monitor = strings.TrimPrefix(data, AddSeriesMonitor)
+		// nothing to search for if nothing is monitored
+		if command.monitor == "none" {
+			return b.addSeriesToLibrary(update, command, false)
+		}
+		return b.showAddSeriesSearchOptions(command)
+	}
+	return b.showAddSeriesSearchResults(command)
+}
+
+func (b *Bot) showAddSeriesSearchResults(command *userAddSeries) bool {
+	seriesList := make([]*sonarr.Series, 0, len(command.searchResults))
+	for _, series := range command.searchResults {
+		seriesList = append(seriesList, series)
+	}
+
+	// Sort series by year in ascending order
+	sort.SliceStable(seriesList, func(i, j int) bool {
+		return seriesList[i].Year < seriesList[j].Year
+	})
+
+	var buttonLabels []string
+	var buttonData []string
+	var text strings.Builder
+	for _, series := range seriesList {
+		fmt.Fprintf(&text, "[%v](%v) \\- _%v_\n", utils.Escape(series.Title), tvdbURL(series.TvdbID), series.Year)
+		buttonLabels = append(buttonLabels, fmt.Sprintf("%v - %v", series.Title, series.Year))
+		buttonData = append(buttonData, AddSeriesTVDBID+strconv.Itoa(int(series.TvdbID)))
+	}
+
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "Cancel - clear command"),
+		append(buttonData, AddSeriesCancel),
+	)
+
+	responseText := fmt.Sprintf("*Found %d series*\n\n", len(command.searchResults))
+	responseText += text.String()
+
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
+		command.chatID,
+		command.messageID,
+		responseText,
+		keyboard,
+	)
+	editMsg.ParseMode = "MarkdownV2"
+	editMsg.DisableWebPagePreview = true
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessage(editMsg)
+	return false
+}
+
+func (b *Bot) showAddSeriesDetails(command *userAddSeries) bool {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Is this the correct series?\n\n")
+	fmt.Fprintf(&text, "[%v](%v) \\- _%v_\n", utils.Escape(command.series.Title), tvdbURL(command.series.TvdbID), command.series.Year)
+	fmt.Fprintf(&text, "%s", utils.Escape(fmt.Sprintf("%d season(s), %s, %s", seasonCount(command.series), command.series.Network, command.series.Status)))
+
+	keyboard := b.createKeyboard(
+		[]string{"Yes, add this series", "\U0001F519"},
+		[]string{AddSeriesYes, AddSeriesGoBack},
+	)
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
+		command.chatID,
+		command.messageID,
+		text.String(),
+		keyboard,
+	)
+	editMsg.ParseMode = "MarkdownV2"
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessage(editMsg)
+	return false
+}
+
+func (b *Bot) handleAddSeriesYes(update tgbotapi.Update, command *userAddSeries) bool {
+	// series already in library...
+	if command.series.ID != 0 {
+		b.sendMessageWithEdit(command, "Series already in library\nAll commands have been cleared")
+		b.clearState(update)
+		return false
+	}
+
+	s := b.SonarrServer
+	profiles, err := s.GetQualityProfiles()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleAddSeriesYes(update, command) })
+	}
+	if len(profiles) == 0 {
+		b.sendMessageWithEdit(command, "No quality profile(s) found on your sonarr server.\nAll commands have been cleared.")
+		b.clearState(update)
+		return false
+	}
+	command.allProfiles = profiles
+	command.profileID = 0
+	if len(profiles) == 1 {
+		command.profileID = profiles[0].ID
+	}
+
+	// language profiles only exist up to Sonarr v3, the first one is used
+	command.languageProfileID = 0
+	if languageProfiles, err := s.GetLanguageProfiles(); err == nil && len(languageProfiles) > 0 {
+		command.languageProfileID = languageProfiles[0].ID
+	}
+
+	rootFolders, err := s.GetRootFolders()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleAddSeriesYes(update, command) })
+	}
+	if len(rootFolders) == 0 {
+		b.sendMessageWithEdit(command, "No root folder(s) found on your sonarr server.\nAll commands have been cleared.")
+		b.clearState(update)
+		return false
+	}
+	command.allRootFolders = rootFolders
+	command.rootFolder = nil
+	if len(rootFolders) == 1 {
+		command.rootFolder = rootFolders[0]
+	}
+
+	tags, err := s.GetTags()
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleAddSeriesYes(update, command) })
+	}
+	command.allTags = tags
+	command.selectedTags = nil
+
+	return b.showAddSeriesProfiles(command)
+}
+
+func (b *Bot) showAddSeriesProfiles(command *userAddSeries) bool {
+	// If there is only one profile, skip this step
+	if len(command.allProfiles) == 1 {
+		return b.showAddSeriesRootFolders(command)
+	}
+	var buttonLabels, buttonData []string
+	for _, profile := range command.allProfiles {
+		buttonLabels = append(buttonLabels, profile.Name)
+		buttonData = append(buttonData, AddSeriesProfile+strconv.Itoa(int(profile.ID)))
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "\U0001F519"),
+		append(buttonData, AddSeriesProfileGoBack),
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select quality profile:")
+	return false
+}
+
+// showAddSeriesProfilesBack goes back to the quality profiles, or further if
+// they were skipped.
+func (b *Bot) showAddSeriesProfilesBack(command *userAddSeries) bool {
+	if len(command.allProfiles) == 1 {
+		return b.showAddSeriesSearchResults(command)
+	}
+	return b.showAddSeriesProfiles(command)
+}
+
+func (b *Bot) showAddSeriesRootFolders(command *userAddSeries) bool {
+	// If there is only one root folder, skip this step
+	if len(command.allRootFolders) == 1 {
+		return b.showAddSeriesTags(command)
+	}
+	var buttonLabels, buttonData []string
+	for _, rootFolder := range command.allRootFolders {
+		buttonLabels = append(buttonLabels, rootFolder.Path)
+		buttonData = append(buttonData, AddSeriesRootFolder+strconv.Itoa(int(rootFolder.ID)))
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "\U0001F519"),
+		append(buttonData, AddSeriesRootFolderGoBack),
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select root folder:")
+	return false
+}
+
+// showAddSeriesRootFoldersBack goes back to the root folders, or further if
+// they were skipped.
+func (b *Bot) showAddSeriesRootFoldersBack(command *userAddSeries) bool {
+	if len(command.allRootFolders) == 1 {
+		return b.showAddSeriesProfilesBack(command)
+	}
+	return b.showAddSeriesRootFolders(command)
+}
+
+func (b *Bot) handleAddSeriesRootFolder(data string, command *userAddSeries) bool {
+	id, err := strconv.ParseInt(strings.TrimPrefix(data, AddSeriesRootFolder), 10, 64)
+	if err != nil {
+		msg := tgbotapi.NewMessage(command.chatID, "Invalid root folder selection.")
+		b.logger(command.chatID).Warn("Cannot convert root folder ID to int", "error", err)
+		b.sendMessage(msg)
+		return false
+	}
+	command.rootFolder = nil
+	for _, rootFolder := range command.allRootFolders {
+		if rootFolder.ID == id {
+			command.rootFolder = rootFolder
+			break
+		}
+	}
+	if command.rootFolder == nil {
+		msg := tgbotapi.NewMessage(command.chatID, "Root folder not found.")
+		b.sendMessage(msg)
+		return false
+	}
+	return b.showAddSeriesTags(command)
+}
+
+func (b *Bot) showAddSeriesTags(command *userAddSeries) bool {
+	// If there are no tags or tags should be ignored, skip this step
+	if len(command.allTags) == 0 || b.config().IgnoreTags {
+		return b.showAddSeriesTypes(command)
+	}
+	var buttonLabels, buttonData []string
+	for _, tag := range command.allTags {
+		buttonText := tag.Label
+		if isSelectedTag(command.selectedTags, tag.ID) {
+			buttonText += " \u2705"
+		}
+		buttonLabels = append(buttonLabels, buttonText)
+		buttonData = append(buttonData, AddSeriesTag+strconv.Itoa(tag.ID))
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "Done - Continue", "\U0001F519"),
+		append(buttonData, AddSeriesTagsDone, AddSeriesTagsGoBack),
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select tags:")
+	return false
+}
+
+func (b *Bot) showAddSeriesTypes(command *userAddSeries) bool {
+	var buttonLabels, buttonData []string
+	for _, seriesType := range seriesTypes {
+		label := strings.ToUpper(seriesType[:1]) + seriesType[1:]
+		if seriesType == command.series.SeriesType {
+			label += " (suggested)"
+		}
+		buttonLabels = append(buttonLabels, label)
+		buttonData = append(buttonData, AddSeriesType+seriesType)
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "\U0001F519"),
+		append(buttonData, AddSeriesTypeGoBack),
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Select series type:")
+	return false
+}
+
+func (b *Bot) showAddSeriesMonitor(command *userAddSeries) bool {
+	var buttonLabels, buttonData []string
+	for _, option := range seriesMonitorOptions {
+		buttonLabels = append(buttonLabels, option.label)
+		buttonData = append(buttonData, AddSeriesMonitor+option.key)
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, "\U0001F519"),
+		append(buttonData, AddSeriesMonitorGoBack),
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "Which episodes should be monitored?")
+	return false
+}
+
+func (b *Bot) showAddSeriesSearchOptions(command *userAddSeries) bool {
+	keyboard := b.createKeyboard(
+		[]string{"Add series + search missing episodes", "Add series", "Cancel, clear command", "\U0001F519"},
+		[]string{AddSeriesSearch, AddSeriesNoSearch, AddSeriesCancel, AddSeriesSearchGoBack},
+	)
+	b.setAddSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, "How would you like to add the series?")
+	return false
+}
+
+func (b *Bot) addSeriesToLibrary(update tgbotapi.Update, command *userAddSeries, search bool) bool {
+	addSeriesInput := sonarr.AddSeriesInput{
+		Monitored:         command.monitor != "none",
+		SeasonFolder:      true,
+		LanguageProfileID: command.languageProfileID,
+		QualityProfileID:  command.profileID,
+		TvdbID:            command.series.TvdbID,
+		ImdbID:            command.series.ImdbID,
+		SeriesType:        command.seriesType,
+		Title:             command.series.Title,
+		TitleSlug:         command.series.TitleSlug,
+		RootFolderPath:    command.rootFolder.Path,
+		Tags:              command.selectedTags,
+		Seasons:           monitoredSeasons(command.series.Seasons, command.monitor),
+		Images:            command.series.Images,
+		AddOptions:        seriesAddOptions(command.monitor, search),
+	}
+
+	series, err := b.SonarrServer.AddSeries(&addSeriesInput)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.addSeriesToLibrary(update, command, search) })
+	}
+	b.sendMessageWithEdit(command, fmt.Sprintf("Series '%v' added\n", series.Title))
+	b.clearState(update)
+	return true
+}
+
+// monitoredSeasons returns the seasons with the monitoring of the chosen
+// option. Specials are never monitored.
+func monitoredSeasons(seasons []*sonarr.Season, monitor string) []*sonarr.Season {
+	first, latest := -1, -1
+	for _, season := range seasons {
+		if season.SeasonNumber == 0 {
+			continue
+		}
+		if first == -1 || season.SeasonNumber < first {
+			first = season.SeasonNumber
+		}
+		if season.SeasonNumber > latest {
+			latest = season.SeasonNumber
+		}
+	}
+
+	result := make([]*sonarr.Season, 0, len(seasons))
+	for _, season := range seasons {
+		monitored := season.SeasonNumber != 0
+		switch monitor {
+		case "firstSeason":
+			monitored = season.SeasonNumber == first
+		case "latestSeason":
+			monitored = season.SeasonNumber == latest
+		case "none":
+			monitored = false
+		}
+		result = append(result, &sonarr.Season{SeasonNumber: season.SeasonNumber, Monitored: monitored})
+	}
+	return result
+}
+
+// seriesAddOptions maps the monitor option to the episodes Sonarr ignores when
+// it sets the monitoring of the episodes.
+func seriesAddOptions(monitor string, search bool) *sonarr.AddSeriesOptions {
+	options := &sonarr.AddSeriesOptions{SearchForMissingEpisodes: search}
+	switch monitor {
+	case "future":
+		options.IgnoreEpisodesWithFiles = true
+		options.IgnoreEpisodesWithoutFiles = true
+	case "missing":
+		options.IgnoreEpisodesWithFiles = true
+	case "existing":
+		options.IgnoreEpisodesWithoutFiles = true
+	}
+	return options
+}
+
+// seasonCount returns the number of seasons without specials.
+func seasonCount(series *sonarr.Series) int {
+	count := 0
+	for _, season := range series.Seasons {
+		if season.SeasonNumber != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func tvdbURL(tvdbID int64) string {
+	return fmt.Sprintf("https://www.thetvdb.com/?tab=series&id=%d", tvdbID)
+}
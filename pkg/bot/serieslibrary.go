@@ -0,0 +1,372 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr/sonarr"
+)
+
+const (
+	SeriesLibraryID              = "SERIESLIB_ID_"
+	SeriesLibrarySeason          = "SERIESLIB_SEASON_"
+	SeriesLibraryToggleMonitor   = "SERIESLIB_TOGGLE_MONITOR"
+	SeriesLibrarySearch          = "SERIESLIB_SEARCH"
+	SeriesLibraryDelete          = "SERIESLIB_DELETE"
+	SeriesLibraryDeleteKeepFiles = "SERIESLIB_DELETE_KEEP_FILES"
+	SeriesLibraryDeleteWithFiles = "SERIESLIB_DELETE_WITH_FILES"
+	SeriesLibraryDeleteExclude   = "SERIESLIB_DELETE_EXCLUDE"
+	SeriesLibraryDeleteGoBack    = "SERIESLIB_DELETE_GOBACK"
+	SeriesLibraryGoBack          = "SERIESLIB_GOBACK"
+	SeriesLibraryCancel          = "SERIESLIB_CANCEL"
+	SeriesLibraryFirstPage       = "SERIESLIB_FIRST_PAGE"
+	SeriesLibraryPreviousPage    = "SERIESLIB_PREV_PAGE"
+	SeriesLibraryNextPage        = "SERIESLIB_NEXT_PAGE"
+	SeriesLibraryLastPage        = "SERIESLIB_LAST_PAGE"
+)
+
+func (b *Bot) processSeriesLibraryCommand(update tgbotapi.Update, chatID int64, s *sonarr.Sonarr) {
+	msg := tgbotapi.NewMessage(chatID, "Handling series command... please wait")
+	message, _ := b.sendMessage(msg)
+
+	library, err := s.GetAllSeries()
+	if err != nil {
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.processSeriesLibraryCommand(update, chatID, s)
+			return false
+		})
+		return
+	}
+	qualityProfiles, err := s.GetQualityProfiles()
+	if err != nil {
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.processSeriesLibraryCommand(update, chatID, s)
+			return false
+		})
+		return
+	}
+
+	// Sort the series alphabetically based on their titles
+	sort.SliceStable(library, func(i, j int) bool {
+		return utils.IgnoreArticles(strings.ToLower(library[i].Title)) < utils.IgnoreArticles(strings.ToLower(library[j].Title))
+	})
+
+	command := userSeriesLibrary{
+		seriesForSelection: library,
+		qualityProfiles:    qualityProfiles,
+		chatID:             message.Chat.ID,
+		messageID:          message.MessageID,
+	}
+
+	criteria := update.Message.CommandArguments()
+	if len(criteria) > 0 {
+		command.searchCriteria = criteria
+		command.seriesForSelection = utils.SearchSeries(library, criteria)
+	}
+
+	switch {
+	case len(command.seriesForSelection) == 0 && criteria != "":
+		b.clearState(update)
+		b.sendMessageWithEdit(&command, fmt.Sprintf("No series found in your library matching '%s'", criteria))
+	case len(command.seriesForSelection) == 0:
+		b.clearState(update)
+		b.sendMessageWithEdit(&command, "No series found in your library")
+	case len(command.seriesForSelection) == 1:
+		command.series = command.seriesForSelection[0]
+		b.setSeriesState(chatID, &command)
+		b.showSeriesDetails(&command)
+	default:
+		b.setSeriesState(chatID, &command)
+		b.showSeriesSelection(&command)
+	}
+}
+
+func (b *Bot) seriesLibrary(update tgbotapi.Update) bool {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot handle series library", "error", err)
+		return false
+	}
+	command, exists := b.getSeriesState(chatID)
+	if !exists {
+		return false
+	}
+
+	data := update.CallbackQuery.Data
+	switch data {
+	// ignore click on page number
+	case "current_page":
+		return false
+	case SeriesLibraryFirstPage:
+		command.page = 0
+		return b.showSeriesSelection(command)
+	case SeriesLibraryPreviousPage:
+		if command.page > 0 {
+			command.page--
+		}
+		return b.showSeriesSelection(command)
+	case SeriesLibraryNextPage:
+		command.page++
+		return b.showSeriesSelection(command)
+	case SeriesLibraryLastPage:
+		totalPages := (len(command.seriesForSelection) + b.config().MaxItems - 1) / b.config().MaxItems
+		command.page = totalPages - 1
+		return b.showSeriesSelection(command)
+	case SeriesLibraryGoBack:
+		command.lastSearch = time.Time{}
+		if len(command.seriesForSelection) < 2 {
+			b.clearState(update)
+			b.sendMessageWithEdit(command, CommandsCleared)
+			return false
+		}
+		return b.showSeriesSelection(command)
+	case SeriesLibraryToggleMonitor:
+		command.series.Monitored = !command.series.Monitored
+		return b.updateSeries(command)
+	case SeriesLibrarySearch:
+		return b.handleSeriesSearch(command)
+	case SeriesLibraryDelete:
+		return b.showSeriesDeleteConfirm(command)
+	case SeriesLibraryDeleteGoBack:
+		return b.showSeriesDetails(command)
+	case SeriesLibraryDeleteKeepFiles:
+		return b.handleSeriesDelete(update, command, false, false)
+	case SeriesLibraryDeleteWithFiles:
+		return b.handleSeriesDelete(update, command, true, false)
+	case SeriesLibraryDeleteExclude:
+		return b.handleSeriesDelete(update, command, true, true)
+	case SeriesLibraryCancel:
+		b.clearState(update)
+		b.sendMessageWithEdit(command, CommandsCleared)
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(data, SeriesLibraryID):
+		seriesID, err := strconv.ParseInt(strings.TrimPrefix(data, SeriesLibraryID), 10, 64)
+		if err != nil {
+			b.logger(chatID).Warn("Cannot convert series ID to int", "error", err)
+			return false
+		}
+		for _, series := range command.seriesForSelection {
+			if series.ID == seriesID {
+				command.series = series
+				command.lastSearch = time.Time{}
+				return b.showSeriesDetails(command)
+			}
+		}
+	case strings.HasPrefix(data, SeriesLibrarySeason):
+		seasonNumber, err := strconv.Atoi(strings.TrimPrefix(data, SeriesLibrarySeason))
+		if err != nil {
+			b.logger(chatID).Warn("Cannot convert season number to int", "error", err)
+			return false
+		}
+		for _, season := range command.series.Seasons {
+			if season.SeasonNumber == seasonNumber {
+				season.Monitored = !season.Monitored
+			}
+		}
+		return b.updateSeries(command)
+	}
+	return false
+}
+
+func (b *Bot) showSeriesSelection(command *userSeriesLibrary) bool {
+	seriesList := command.seriesForSelection
+
+	// Pagination parameters
+	page := command.page
+	pageSize := b.config().MaxItems
+	totalPages := (len(seriesList) + pageSize - 1) / pageSize
+
+	// Calculate start and end index for the current page
+	startIndex := page * pageSize
+	endIndex := (page + 1) * pageSize
+	if endIndex > len(seriesList) {
+		endIndex = len(seriesList)
+	}
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	for _, series := range seriesList[startIndex:endIndex] {
+		row := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%v - %v", series.Title, series.Year), SeriesLibraryID+strconv.Itoa(int(series.ID))),
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+	}
+
+	// Create pagination buttons
+	if len(seriesList) > pageSize {
+		paginationButtons := b.createPaginationButtons(page, totalPages, SeriesLibraryFirstPage, SeriesLibraryPreviousPage, SeriesLibraryNextPage, SeriesLibraryLastPage)
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, paginationButtons)
+	}
+
+	keyboardCancel := b.createKeyboard(
+		[]string{"Cancel - clear command"},
+		[]string{SeriesLibraryCancel},
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, keyboardCancel.InlineKeyboard...)
+
+	text := fmt.Sprintf("%d series - page %d/%d", len(seriesList), page+1, totalPages)
+	if command.searchCriteria != "" {
+		text = fmt.Sprintf("%d series matching '%s' - page %d/%d", len(seriesList), command.searchCriteria, page+1, totalPages)
+	}
+	b.setSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, text)
+	return false
+}
+
+func (b *Bot) showSeriesDetails(command *userSeriesLibrary) bool {
+	series := command.series
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "[%v](%v) \\- _%v_\n\n", utils.Escape(series.Title), tvdbURL(series.TvdbID), series.Year)
+	fmt.Fprintf(&text, "Status: %s\n", utils.Escape(series.Status))
+	fmt.Fprintf(&text, "Network: %s\n", utils.Escape(series.Network))
+	fmt.Fprintf(&text, "Type: %s\n", utils.Escape(series.SeriesType))
+	fmt.Fprintf(&text, "Monitored: %s\n", utils.Escape(strconv.FormatBool(series.Monitored)))
+	for _, profile := range command.qualityProfiles {
+		if profile.ID == series.QualityProfileID {
+			fmt.Fprintf(&text, "Quality profile: %s\n", utils.Escape(profile.Name))
+		}
+	}
+	if series.Statistics != nil {
+		fmt.Fprintf(&text, "Episodes: %d/%d\n", series.Statistics.EpisodeFileCount, series.Statistics.EpisodeCount)
+		fmt.Fprintf(&text, "Size: %s\n", utils.Escape(utils.ByteCountSI(series.Statistics.SizeOnDisk)))
+	}
+	fmt.Fprintf(&text, "Path: %s\n", utils.Escape(series.Path))
+	if !series.NextAiring.IsZero() {
+		fmt.Fprintf(&text, "Next airing: %s\n", utils.Escape(series.NextAiring.Local().Format("02 Jan 2006 15:04")))
+	}
+	if !command.lastSearch.IsZero() {
+		fmt.Fprintf(&text, "\nLast search: %s\n", utils.Escape(command.lastSearch.Format("02 Jan 06 - 15:04")))
+	}
+	text.WriteString("\nTap a season to toggle its monitoring:")
+
+	var buttonLabels, buttonData []string
+	for _, season := range series.Seasons {
+		buttonLabels = append(buttonLabels, seasonLabel(season))
+		buttonData = append(buttonData, SeriesLibrarySeason+strconv.Itoa(season.SeasonNumber))
+	}
+	toggleMonitor := "Unmonitor series"
+	if !series.Monitored {
+		toggleMonitor = "Monitor series"
+	}
+	keyboard := b.createKeyboard(
+		append(buttonLabels, toggleMonitor, "Search monitored episodes", "Delete series", "\U0001F519", "Cancel - clear command"),
+		append(buttonData, SeriesLibraryToggleMonitor, SeriesLibrarySearch, SeriesLibraryDelete, SeriesLibraryGoBack, SeriesLibraryCancel),
+	)
+
+	editMsg := tgbotapi.NewEditMessageTextAndMarkup(
+		command.chatID,
+		command.messageID,
+		text.String(),
+		keyboard,
+	)
+	editMsg.ParseMode = "MarkdownV2"
+	editMsg.DisableWebPagePreview = true
+	b.setSeriesState(command.chatID, command)
+	b.sendMessage(editMsg)
+	return false
+}
+
+// seasonLabel shows the season with its episode counts and monitoring.
+func seasonLabel(season *sonarr.Season) string {
+	name := fmt.Sprintf("Season %d", season.SeasonNumber)
+	if season.SeasonNumber == 0 {
+		name = "Specials"
+	}
+	if season.Statistics != nil {
+		name += fmt.Sprintf(" (%d/%d)", season.Statistics.EpisodeFileCount, season.Statistics.EpisodeCount)
+	}
+	if season.Monitored {
+		return MonitorIcon + " " + name
+	}
+	return UnmonitorIcon + " " + name
+}
+
+// updateSeries saves the monitoring of the series and its seasons.
+func (b *Bot) updateSeries(command *userSeriesLibrary) bool {
+	series := command.series
+	input := sonarr.AddSeriesInput{
+		ID:                series.ID,
+		Monitored:         series.Monitored,
+		SeasonFolder:      series.SeasonFolder,
+		UseSceneNumbering: series.UseSceneNumbering,
+		LanguageProfileID: series.LanguageProfileID,
+		QualityProfileID:  series.QualityProfileID,
+		TvdbID:            series.TvdbID,
+		ImdbID:            series.ImdbID,
+		TvMazeID:          series.TvMazeID,
+		TvRageID:          series.TvRageID,
+		Path:              series.Path,
+		SeriesType:        series.SeriesType,
+		Title:             series.Title,
+		TitleSlug:         series.TitleSlug,
+		RootFolderPath:    series.RootFolderPath,
+		Tags:              series.Tags,
+		Seasons:           series.Seasons,
+		Images:            series.Images,
+	}
+	_, err := b.SonarrServer.UpdateSeries(&input, false)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.updateSeries(command) })
+	}
+
+	// the update response lacks the statistics
+	updated, err := b.SonarrServer.GetSeriesByID(series.ID)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.updateSeries(command) })
+	}
+	*command.series = *updated
+	return b.showSeriesDetails(command)
+}
+
+func (b *Bot) handleSeriesSearch(command *userSeriesLibrary) bool {
+	cmd := sonarr.CommandRequest{
+		Name:     "SeriesSearch",
+		SeriesID: command.series.ID,
+	}
+	_, err := b.SonarrServer.SendCommand(&cmd)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleSeriesSearch(command) })
+	}
+	command.lastSearch = time.Now()
+	return b.showSeriesDetails(command)
+}
+
+func (b *Bot) showSeriesDeleteConfirm(command *userSeriesLibrary) bool {
+	var size int64
+	if command.series.Statistics != nil {
+		size = command.series.Statistics.SizeOnDisk
+	}
+	keyboard := b.createKeyboard(
+		[]string{
+			"Delete from Sonarr only, keep files",
+			fmt.Sprintf("Delete with files, frees %s", utils.ByteCountSI(size)),
+			"Delete with files + exclude from import lists",
+			"Cancel, clear command",
+			"\U0001F519",
+		},
+		[]string{SeriesLibraryDeleteKeepFiles, SeriesLibraryDeleteWithFiles, SeriesLibraryDeleteExclude, SeriesLibraryCancel, SeriesLibraryDeleteGoBack},
+	)
+	b.setSeriesState(command.chatID, command)
+	b.sendMessageWithEditAndKeyboard(command, keyboard, fmt.Sprintf("Do you want to delete the series '%s'?", command.series.Title))
+	return false
+}
+
+func (b *Bot) handleSeriesDelete(update tgbotapi.Update, command *userSeriesLibrary, deleteFiles, addImportExclusion bool) bool {
+	err := b.SonarrServer.DeleteSeries(int(command.series.ID), deleteFiles, addImportExclusion)
+	if err != nil {
+		return b.reportError(command, err, func() bool {
+			return b.handleSeriesDelete(update, command, deleteFiles, addImportExclusion)
+		})
+	}
+	b.clearState(update)
+	b.sendMessageWithEdit(command, fmt.Sprintf("Series '%s' deleted", command.series.Title))
+	return true
+}
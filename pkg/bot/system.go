@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/radarrapi"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+// SystemRaw is the prefix of the button which sends the full system status
+// of an instance, it outlives the command.
+const SystemRaw = "SYSTEM_RAW_"
+
+// sendSystemStatus sends an overview of each instance. Paths and other
+// details of the host are only shown to admins.
+func (b *Bot) sendSystemStatus(chatID int64, instances []*RadarrInstance) {
+	admin := b.config().IsAdmin(chatID)
+	for _, instance := range instances {
+		status, err := instance.Server.GetSystemStatus()
+		if err != nil {
+			b.sendError(chatID, b.instanceLabel(instance), err)
+			continue
+		}
+		msg := tgbotapi.NewMessage(chatID, b.systemOverview(instance, status, admin))
+		if admin {
+			data := fmt.Sprintf("%s%d", SystemRaw, b.instanceIndex(instance))
+			msg.ReplyMarkup = b.createKeyboard([]string{"Raw"}, []string{data})
+		}
+		b.sendMessage(msg)
+	}
+}
+
+func (b *Bot) systemOverview(instance *RadarrInstance, status *radarr.SystemStatus, admin bool) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s%s %s (%s)\n", b.instanceLabel(instance), status.AppName, status.Version, status.Branch)
+	fmt.Fprintf(&text, "OS: %s, %s %s", status.OsName, status.RuntimeName, status.RuntimeVersion)
+	if status.IsDocker {
+		text.WriteString(", Docker")
+	}
+	fmt.Fprintf(&text, "\nUptime: %s, since %s\n", formatUptime(time.Since(status.StartTime)), status.StartTime.Local().Format("02 Jan 2006 15:04"))
+	fmt.Fprintf(&text, "Database: %s %s\n", status.DatabaseType, status.DatabaseVersion)
+	fmt.Fprintf(&text, "Authentication: %s\n", status.Authentication)
+	if admin {
+		fmt.Fprintf(&text, "App data: %s\n", status.AppData)
+		fmt.Fprintf(&text, "Startup path: %s\n", status.StartupPath)
+		if status.URLBase != "" {
+			fmt.Fprintf(&text, "URL base: %s\n", status.URLBase)
+		}
+	}
+
+	// the sections below are best effort, a failure doesn't hide the rest
+	updates, err := radarrapi.GetUpdates(instance.Server)
+	switch {
+	case err != nil:
+		text.WriteString("\nUpdate: unknown, " + friendlyError(err))
+	case radarrapi.AvailableUpdate(updates) != nil:
+		update := radarrapi.AvailableUpdate(updates)
+		fmt.Fprintf(&text, "\nUpdate: %s available, released %s", update.Version, update.ReleaseDate.Local().Format("02 Jan 2006"))
+	default:
+		text.WriteString("\nUpdate: up to date")
+	}
+
+	health, err := radarrapi.GetHealth(instance.Server)
+	if err != nil {
+		text.WriteString("\nHealth: unknown, " + friendlyError(err))
+	} else if issues := healthIssues(health); len(issues) > 0 {
+		fmt.Fprintf(&text, "\nHealth: %d issue(s), see /health", len(issues))
+	} else {
+		text.WriteString("\nHealth: no issues")
+	}
+
+	queue, err := instance.Server.GetQueuePage(&starr.PageReq{PageSize: 1, Page: 1})
+	if err != nil {
+		text.WriteString("\nQueue: unknown, " + friendlyError(err))
+	} else {
+		fmt.Fprintf(&text, "\nQueue: %d item(s)", queue.TotalRecords)
+	}
+
+	disks, err := radarrapi.GetDiskSpace(instance.Server)
+	if err != nil {
+		text.WriteString("\nDisks: unknown, " + friendlyError(err))
+		return text.String()
+	}
+	if !admin {
+		// the mount points tell how the host is laid out, only show the sum
+		var free, total int64
+		for _, disk := range disks {
+			free += disk.FreeSpace
+			total += disk.TotalSpace
+		}
+		fmt.Fprintf(&text, "\nDisks: %s", diskUsage(free, total))
+		return text.String()
+	}
+	text.WriteString("\nDisks:")
+	for _, disk := range disks {
+		fmt.Fprintf(&text, "\n- %s: %s", disk.Path, diskUsage(disk.FreeSpace, disk.TotalSpace))
+	}
+	return text.String()
+}
+
+func diskUsage(free, total int64) string {
+	if total <= 0 {
+		return utils.ByteCountSI(free) + " free"
+	}
+	return fmt.Sprintf("%s free of %s (%.0f%% used)", utils.ByteCountSI(free), utils.ByteCountSI(total), float64(total-free)*100/float64(total))
+}
+
+// formatUptime formats a duration in days, hours and minutes.
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// handleSystemRaw sends the full system status of the instance.
+func (b *Bot) handleSystemRaw(update tgbotapi.Update) {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot send system status", "error", err)
+		return
+	}
+	// the button is only shown to admins, the config may have been reloaded since
+	if !b.config().IsAdmin(chatID) {
+		b.sendMessage(tgbotapi.NewMessage(chatID, "Only admins can see the full system status"))
+		return
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(update.CallbackQuery.Data, SystemRaw))
+	if err != nil || index < 0 || index >= len(b.RadarrServers) {
+		b.logger(chatID).Warn("Cannot send system status", "error", "invalid instance")
+		return
+	}
+	instance := b.RadarrServers[index]
+	status, err := instance.Server.GetSystemStatus()
+	if err != nil {
+		b.sendError(chatID, b.instanceLabel(instance), err)
+		return
+	}
+	b.sendMessage(tgbotapi.NewMessage(chatID, b.instanceLabel(instance)+prettyPrint(status)))
+}
@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
+)
+
+const (
+	UpcomingMovie = "UPCOMING_MOVIE_"
+)
+
+const UpcomingQuerySyntax = `Usage: /up [past] [days] [filters], e.g. /up 7, /up 90, /up past 14
+Releases: cinema, digital, physical (default all)
+Status: monitored, unmonitored, ondisk, missing
+Grouping: day or week (default day up to 31 days, week beyond)
+Example: /up 90 digital monitored missing`
+
+const (
+	upcomingDefaultDays = 30
+	upcomingMaxDays     = 365
+	// episodesDefaultDays is the range of the Sonarr calendar without days.
+	episodesDefaultDays = 7
+)
+
+var releaseKinds = []string{"cinema", "digital", "physical"}
+
+// upcomingQuery is a parsed /up expression.
+type upcomingQuery struct {
+	days       int // 0 if not given
+	past       bool
+	byWeek     bool
+	kinds      map[string]bool // empty for all release types
+	predicates []moviePredicate
+	filters    []string // the status filters, for the description
+}
+
+// upcomingRelease is a cinema, digital or physical release of a movie.
+type upcomingRelease struct {
+	date  time.Time
+	kind  string
+	movie *radarr.Movie
+}
+
+// parseUpcomingQuery parses the arguments of /up.
+func parseUpcomingQuery(args string) (*upcomingQuery, error) {
+	query := &upcomingQuery{kinds: make(map[string]bool)}
+	grouping := ""
+	for _, term := range strings.Fields(strings.ToLower(args)) {
+		if days, err := strconv.Atoi(term); err == nil {
+			if days < 1 || days > upcomingMaxDays {
+				return nil, fmt.Errorf("%q: days must be between 1 and %d", term, upcomingMaxDays)
+			}
+			query.days = days
+			continue
+		}
+		switch term {
+		case "past":
+			query.past = true
+		case "cinema", "digital", "physical":
+			query.kinds[term] = true
+		case "monitored", "unmonitored", "ondisk", "missing":
+			query.predicates = append(query.predicates, upcomingFilters[term])
+			query.filters = append(query.filters, term)
+		case "day", "days", "daily", "date":
+			grouping = "day"
+		case "week", "weeks", "weekly":
+			grouping = "week"
+		default:
+			return nil, fmt.Errorf("unknown term %q", term)
+		}
+	}
+	query.byWeek = grouping == "week" || (grouping == "" && query.rangeDays() > 31)
+	return query, nil
+}
+
+var upcomingFilters = map[string]moviePredicate{
+	"monitored":   func(movie *radarr.Movie) bool { return movie.Monitored },
+	"unmonitored": func(movie *radarr.Movie) bool { return !movie.Monitored },
+	"ondisk":      func(movie *radarr.Movie) bool { return movie.HasFile },
+	"missing":     func(movie *radarr.Movie) bool { return !movie.HasFile },
+}
+
+func (q *upcomingQuery) rangeDays() int {
+	if q.days == 0 {
+		return upcomingDefaultDays
+	}
+	return q.days
+}
+
+// window returns the time range of the query, starting today or ending
+// yesterday for past releases. Release dates are days in UTC.
+func (q *upcomingQuery) window(now time.Time) (time.Time, time.Time) {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if q.past {
+		return today.AddDate(0, 0, -q.rangeDays()), today
+	}
+	return today, today.AddDate(0, 0, q.rangeDays())
+}
+
+// describe returns the range and filters of the query, e.g. "next 30 days".
+func (q *upcomingQuery) describe() string {
+	text := fmt.Sprintf("next %d days", q.rangeDays())
+	if q.past {
+		text = fmt.Sprintf("past %d days", q.rangeDays())
+	}
+	var kinds []string
+	for _, kind := range releaseKinds {
+		if q.kinds[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	if filters := append(kinds, q.filters...); len(filters) > 0 {
+		text = fmt.Sprintf("%s (%s)", text, strings.Join(filters, ", "))
+	}
+	return text
+}
+
+// releases returns the releases within the window which match the filters,
+// by date and title.
+func (q *upcomingQuery) releases(movies []*radarr.Movie, now time.Time) []upcomingRelease {
+	start, end := q.window(now)
+	var releases []upcomingRelease
+	for _, movie := range movies {
+		if !q.matchesStatus(movie) {
+			continue
+		}
+		for _, kind := range releaseKinds {
+			if len(q.kinds) > 0 && !q.kinds[kind] {
+				continue
+			}
+			date := releaseDate(movie, kind)
+			if !date.IsZero() && !date.Before(start) && date.Before(end) {
+				releases = append(releases, upcomingRelease{date: date, kind: kind, movie: movie})
+			}
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		if !releases[i].date.Equal(releases[j].date) {
+			return releases[i].date.Before(releases[j].date)
+		}
+		return utils.IgnoreArticles(strings.ToLower(releases[i].movie.Title)) < utils.IgnoreArticles(strings.ToLower(releases[j].movie.Title))
+	})
+	return releases
+}
+
+// matches reports whether the movie has a release matching the query, it's
+// used to list the movies of the calendar in the library.
+func (q *upcomingQuery) matches(now time.Time) moviePredicate {
+	return func(movie *radarr.Movie) bool {
+		return len(q.releases([]*radarr.Movie{movie}, now)) > 0
+	}
+}
+
+func (q *upcomingQuery) matchesStatus(movie *radarr.Movie) bool {
+	for _, predicate := range q.predicates {
+		if !predicate(movie) {
+			return false
+		}
+	}
+	return true
+}
+
+func releaseDate(movie *radarr.Movie, kind string) time.Time {
+	switch kind {
+	case "cinema":
+		return movie.InCinemas
+	case "digital":
+		return movie.DigitalRelease
+	case "physical":
+		return movie.PhysicalRelease
+	}
+	return time.Time{}
+}
+
+// releaseGroup returns the header of the day or week of the release.
+func releaseGroup(date time.Time, byWeek bool) string {
+	if !byWeek {
+		return date.Format("Monday, 02 Jan 2006")
+	}
+	// weeks start on Monday
+	offset := (int(date.Weekday()) + 6) % 7
+	return "Week of " + date.AddDate(0, 0, -offset).Format("Mon 02 Jan 2006")
+}
+
+func (b *Bot) processUpcomingCommand(update tgbotapi.Update, chatID int64, instances []*RadarrInstance) {
+	query, err := parseUpcomingQuery(update.Message.CommandArguments())
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Invalid range: %v\n\n%s", err, UpcomingQuerySyntax))
+		b.sendMessage(msg)
+		return
+	}
+	b.muUpcomingQueries.Lock()
+	b.UpcomingQueries[chatID] = query
+	b.muUpcomingQueries.Unlock()
+
+	start, end := query.window(time.Now())
+	calendar := radarr.Calendar{
+		Start:       start,
+		End:         end,
+		Unmonitored: *starr.True(),
+	}
+	for _, instance := range instances {
+		msg := tgbotapi.NewMessage(chatID, "")
+		movies, err := instance.Server.GetCalendar(calendar)
+		if err != nil {
+			b.sendError(chatID, b.instanceLabel(instance), err)
+			continue
+		}
+		releases := query.releases(movies, time.Now())
+		if len(releases) == 0 {
+			msg.Text = fmt.Sprintf("%sno releases in the %s", b.instanceLabel(instance), query.describe())
+			b.sendMessage(msg)
+			continue
+		}
+		if len(b.RadarrServers) > 1 {
+			msg.Text = instance.Name
+			b.sendMessage(msg)
+		}
+		b.sendUpcoming(b.instanceIndex(instance), releases, query.byWeek, &msg)
+	}
+
+	// episodes have no release types, the status filters only apply to movies
+	if b.SonarrServer == nil || len(query.kinds) > 0 || len(query.predicates) > 0 {
+		return
+	}
+	episodeQuery := *query
+	if query.days == 0 {
+		episodeQuery.days = episodesDefaultDays
+	}
+	start, end = episodeQuery.window(time.Now())
+	msg := tgbotapi.NewMessage(chatID, "")
+	episodes, err := b.SonarrServer.GetCalendar(sonarr.Calendar{
+		Start:         start,
+		End:           end,
+		IncludeSeries: true,
+	})
+	if err != nil {
+		b.sendError(chatID, "Sonarr: ", err)
+		return
+	}
+	if len(episodes) == 0 {
+		msg.Text = fmt.Sprintf("Sonarr: no episodes airing in the %s", episodeQuery.describe())
+		b.sendMessage(msg)
+		return
+	}
+	msg.Text = "Sonarr"
+	b.sendMessage(msg)
+	b.sendUpcomingEpisodes(episodes, &msg)
+}
+
+// sendUpcoming lists the releases under day or week headers, with a button
+// per movie to open it in the library. A message holds up to MaxItems movies.
+func (b *Bot) sendUpcoming(instanceIndex int, releases []upcomingRelease, byWeek bool, msg *tgbotapi.MessageConfig) {
+	msg.ParseMode = "MarkdownV2"
+	msg.DisableWebPagePreview = true
+
+	var text strings.Builder
+	var buttonLabels, buttonData []string
+	shown := make(map[int64]bool)
+	group := ""
+	flush := func() {
+		msg.Text = text.String()
+		msg.ReplyMarkup = b.createKeyboard(buttonLabels, buttonData)
+		b.sendMessage(msg)
+		text.Reset()
+		buttonLabels, buttonData = nil, nil
+		shown = make(map[int64]bool)
+	}
+
+	for _, release := range releases {
+		movie := release.movie
+		line := fmt.Sprintf("[%v](https://www.imdb.com/title/%v) \\- %s", utils.Escape(movie.Title), movie.ImdbID, release.kind)
+		if byWeek {
+			line = utils.Escape(release.date.Format("Mon 02")) + " " + line
+		}
+		if movie.HasFile {
+			line += " \U0001F4BE" // floppy disk
+		}
+		header := releaseGroup(release.date, byWeek)
+
+		newMovie := !shown[movie.TmdbID]
+		if text.Len() > 0 && ((newMovie && len(buttonData) >= b.config().MaxItems) || text.Len()+len(header)+len(line)+4 > maxMessageLength) {
+			flush()
+		}
+		if text.Len() == 0 || header != group {
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			fmt.Fprintf(&text, "*%s*\n", utils.Escape(header))
+			group = header
+		}
+		text.WriteString(line + "\n")
+
+		if newMovie {
+			shown[movie.TmdbID] = true
+			buttonLabels = append(buttonLabels, fmt.Sprintf("%v - %v", movie.Title, movie.Year))
+			buttonData = append(buttonData, fmt.Sprintf("%s%d_%d", UpcomingMovie, instanceIndex, movie.TmdbID))
+		}
+	}
+	if text.Len() > 0 {
+		flush()
+	}
+}
+
+// handleUpcomingMovie opens a movie of a calendar in the library. The
+// calendar buttons outlive the command that created them.
+func (b *Bot) handleUpcomingMovie(update tgbotapi.Update) {
+	chatID, err := b.getChatID(update)
+	if err != nil {
+		updateLogger(update).Warn("Cannot open upcoming movie", "error", err)
+		return
+	}
+	instanceIndex, tmdbID, found := strings.Cut(strings.TrimPrefix(update.CallbackQuery.Data, UpcomingMovie), "_")
+	index, err := strconv.Atoi(instanceIndex)
+	if !found || err != nil || index < 0 || index >= len(b.RadarrServers) {
+		b.logger(chatID).Warn("Cannot open upcoming movie", "error", "invalid callback data")
+		return
+	}
+	instance := b.RadarrServers[index]
+
+	b.muUpcomingQueries.Lock()
+	query, exists := b.UpcomingQueries[chatID]
+	b.muUpcomingQueries.Unlock()
+	if !exists {
+		// e.g. after a restart
+		query, _ = parseUpcomingQuery("")
+	}
+
+	b.clearState(update)
+	b.setActiveRadarr(chatID, instance)
+	msg := tgbotapi.NewMessage(chatID, "Loading movie... please wait")
+	message, _ := b.sendMessage(msg)
+
+	command, err := loadLibrary(instance.Server, message)
+	if err != nil {
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.handleUpcomingMovie(update)
+			return false
+		})
+		return
+	}
+
+	for _, movie := range command.library {
+		if strconv.Itoa(int(movie.TmdbID)) == tmdbID {
+			command.movie = movie
+		}
+	}
+	if command.movie == nil {
+		b.sendMessageWithEdit(command, "The movie is no longer in the library")
+		return
+	}
+
+	now := time.Now()
+	command.upcoming = query
+	command.filter = FilterUpcoming
+	command.searchResultsInLibrary = filterMovies(command.library, query.matches(now))
+	command.libraryFiltered = make(map[string]*radarr.Movie, len(command.searchResultsInLibrary))
+	for _, movie := range command.searchResultsInLibrary {
+		command.libraryFiltered[strconv.Itoa(int(movie.TmdbID))] = movie
+	}
+
+	b.setLibraryState(chatID, command)
+	b.setActiveCommand(chatID, LibraryFilteredCommand)
+	b.showLibraryMovieDetail(update, command)
+}
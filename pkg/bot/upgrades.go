@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golift.io/starr/radarr"
+)
+
+const (
+	LibrarySearchAllShown = "LIBRARY_SEARCH_ALL_SHOWN"
+)
+
+func (b *Bot) processUpgradesCommand(update tgbotapi.Update, chatID int64, r *radarr.Radarr) {
+	msg := tgbotapi.NewMessage(chatID, "Handling upgrades command... please wait")
+	message, _ := b.sendMessage(msg)
+
+	command, err := loadLibrary(r, message)
+	if err != nil {
+		b.reportError(&statusMessage{chatID, message.MessageID}, err, func() bool {
+			b.processUpgradesCommand(update, chatID, r)
+			return false
+		})
+		return
+	}
+	command.filter = FilterCutoffUnmet
+
+	b.setLibraryState(chatID, command)
+	b.setActiveCommand(chatID, LibraryFilteredCommand)
+	b.showLibraryMenuFiltered(command)
+}
+
+// cutoffUnmet returns whether the movie can be upgraded: its quality is
+// below the cutoff or its custom format score is below the cutoff score of
+// a profile which allows upgrades.
+func cutoffUnmet(qualityProfiles []*radarr.QualityProfile) moviePredicate {
+	return func(movie *radarr.Movie) bool {
+		if movie.MovieFile == nil {
+			return false
+		}
+		if movie.MovieFile.QualityCutoffNotMet {
+			return true
+		}
+		profile := findQualityProfileByID(qualityProfiles, movie.QualityProfileID)
+		return profile != nil && profile.UpgradeAllowed && int64(movie.MovieFile.CustomFormatScore) < profile.CutoffFormatScore
+	}
+}
+
+// cutoffUnmetDetails lists the current quality and the cutoff of every movie.
+func cutoffUnmetDetails(movies []*radarr.Movie, qualityProfiles []*radarr.QualityProfile) string {
+	var text strings.Builder
+	for _, movie := range movies {
+		current := "unknown"
+		score := 0
+		if movie.MovieFile != nil {
+			score = movie.MovieFile.CustomFormatScore
+			if movie.MovieFile.Quality != nil && movie.MovieFile.Quality.Quality != nil {
+				current = movie.MovieFile.Quality.Quality.Name
+			}
+		}
+		cutoff := "unknown"
+		cutoffScore := int64(0)
+		if profile := findQualityProfileByID(qualityProfiles, movie.QualityProfileID); profile != nil {
+			cutoff = qualityCutoffName(profile)
+			cutoffScore = profile.CutoffFormatScore
+		}
+		fmt.Fprintf(&text, "\n%s (%d): %s → %s, CF score %d/%d", movie.Title, movie.Year, current, cutoff, score, cutoffScore)
+	}
+	return text.String()
+}
+
+// qualityCutoffName returns the name of the cutoff quality or quality group.
+func qualityCutoffName(profile *radarr.QualityProfile) string {
+	for _, item := range profile.Qualities {
+		if len(item.Items) > 0 && int64(item.ID) == profile.Cutoff {
+			return item.Name
+		}
+		if item.Quality != nil && item.Quality.ID == profile.Cutoff {
+			return item.Quality.Name
+		}
+	}
+	return "unknown"
+}
+
+// handleLibrarySearchAllShown searches all movies of the filtered list.
+func (b *Bot) handleLibrarySearchAllShown(command *userLibrary) bool {
+	var movieIDs []int64
+	for _, movie := range command.libraryFiltered {
+		movieIDs = append(movieIDs, movie.ID)
+	}
+	if len(movieIDs) == 0 {
+		return false
+	}
+
+	cmd := radarr.CommandRequest{
+		Name:     "MoviesSearch",
+		MovieIDs: movieIDs,
+	}
+	name := fmt.Sprintf("Search for %d movie(s)", len(movieIDs))
+	err := b.sendTrackedCommand(command.chatID, b.getRadarrInstance(command.chatID), &cmd, name)
+	if err != nil {
+		return b.reportError(command, err, func() bool { return b.handleLibrarySearchAllShown(command) })
+	}
+	command.lastSearch = time.Now()
+	b.setLibraryState(command.chatID, command)
+	return b.showLibraryMenuFiltered(command)
+}
@@ -3,97 +3,446 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/woiza/telegram-bot-radarr/pkg/utils"
+)
+
+// Defaults of the optional settings.
+const (
+	DefaultMaxItems   = 10
+	DefaultProtocol   = "http"
+	DefaultRadarrPort = 7878
+	DefaultSonarrPort = 8989
+
+	DefaultMetricsInterval = time.Minute
+	DefaultMaxPollAge      = 5 * time.Minute
+	DefaultDiskInterval    = 10 * time.Minute
+	DefaultHealthInterval  = 5 * time.Minute
+)
+
+// Startup checks, what happens if Radarr or Sonarr can't be used on startup.
+const (
+	StartupCheckWarn  = "warn"  // log the problems and start anyway
+	StartupCheckFail  = "fail"  // refuse to start
+	StartupCheckRetry = "retry" // retry with backoff until the checks pass
+)
+
+// Log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
 )
 
 // BotConfig ...
 type Config struct {
-	TelegramBotToken string
-	AllowedChatIDs   map[int64]bool
-	MaxItems         int
-	IgnoreTags       bool
-	RadarrProtocol   string
-	RadarrHostname   string
-	RadarrPort       int
-	RadarrAPIKey     string
-	RadarrBaseUrl    string
+	TelegramBotToken  string
+	AllowedChatIDs    map[int64]bool
+	AdminChatIDs      map[int64]bool // all allowed users are admins if empty
+	MaxItems          int
+	IgnoreTags        bool
+	DeleteGracePeriod time.Duration
+	StartupCheck      string
+	LogLevel          slog.Level
+	LogFormat         string
+	HTTPAddress       string         // the HTTP server is disabled if empty
+	MetricsInterval   time.Duration  // how often the Radarr metrics are collected
+	MaxPollAge        time.Duration  // /healthz fails if Telegram wasn't polled for longer
+	DataFile          string         // data like digest subscriptions is only kept in memory if empty
+	PublicURL         string         // address of the HTTP server for links, e.g. https://bot.example.com
+	DiskMinFree       int64          // bytes, root folders with less free space are alerted
+	DiskMinFreePct    float64        // percent of the disk, used instead of DiskMinFree if set
+	DiskAlertChatIDs  map[int64]bool // the admins are alerted if empty
+	DiskInterval      time.Duration  // how often the free space is checked
+	HealthInterval    time.Duration  // how often Radarr's health is checked, 0 disables the alerts
+	Radarrs           []ServerConfig
+	Sonarr            *ServerConfig // nil if Sonarr isn't configured
 }
 
+// ServerConfig is the connection of a single, named Radarr or Sonarr instance.
+type ServerConfig struct {
+	Name     string
+	Protocol string
+	Hostname string
+	Port     int
+	APIKey   string
+	BaseUrl  string
+}
+
+// URL returns the base URL of the instance.
+func (r ServerConfig) URL() string {
+	return fmt.Sprintf("%v://%v:%v%v", r.Protocol, r.Hostname, r.Port, r.BaseUrl)
+}
+
+// source looks up a setting in the environment, a secret file named by the
+// _FILE variant of the variable and the config file, in this order. Problems
+// are collected, so all of them can be reported at once.
+type source struct {
+	file map[string]string
+	errs []error
+}
+
+func (s *source) get(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		return s.readSecret(name+"_FILE", path)
+	}
+	if value := s.file[name]; value != "" {
+		return value
+	}
+	if path := s.file[name+"_FILE"]; path != "" {
+		return s.readSecret(name+"_FILE", path)
+	}
+	return ""
+}
+
+// readSecret reads a secret from a file, e.g. a Docker secret.
+func (s *source) readSecret(name, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.fail("%s: %v", name, err)
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (s *source) fail(format string, args ...any) {
+	s.errs = append(s.errs, fmt.Errorf(format, args...))
+}
+
+// LoadConfig reads the configuration from the environment variables and the
+// optional config file named by RBOT_CONFIG_FILE. Environment variables take
+// precedence over the config file. All invalid settings are reported at once.
 func LoadConfig() (Config, error) {
 	var config Config
 
-	config.TelegramBotToken = os.Getenv("RBOT_TELEGRAM_BOT_TOKEN")
-	allowedUserIDs := os.Getenv("RBOT_BOT_ALLOWED_USERIDS")
-	botMaxItems := os.Getenv("RBOT_BOT_MAX_ITEMS")
-	botIgnoreTags := os.Getenv("RBOT_BOT_IGNORE_TAGS")
-	config.RadarrProtocol = os.Getenv("RBOT_RADARR_PROTOCOL")
-	config.RadarrHostname = os.Getenv("RBOT_RADARR_HOSTNAME")
-	radarrPort := os.Getenv("RBOT_RADARR_PORT")
-	config.RadarrAPIKey = os.Getenv("RBOT_RADARR_API_KEY")
-	config.RadarrBaseUrl = os.Getenv("RBOT_RADARR_BASE_URL")
-
-	// Validate required fields
+	src := &source{}
+	if path := os.Getenv("RBOT_CONFIG_FILE"); path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return config, err
+		}
+		src.file = file
+	}
+
+	config.TelegramBotToken = src.get("RBOT_TELEGRAM_BOT_TOKEN")
 	if config.TelegramBotToken == "" {
-		return config, errors.New("RBOT_TELEGRAM_BOT_TOKEN is empty or not set")
+		src.fail("RBOT_TELEGRAM_BOT_TOKEN is empty or not set")
 	}
+
+	// Parsing RBOT_BOT_ALLOWED_USERIDS as a list of integers
+	config.AllowedChatIDs = make(map[int64]bool)
+	allowedUserIDs := src.get("RBOT_BOT_ALLOWED_USERIDS")
 	if allowedUserIDs == "" {
-		return config, errors.New("RBOT_BOT_ALLOWED_USERIDS is empty or not set")
+		src.fail("RBOT_BOT_ALLOWED_USERIDS is empty or not set")
+	}
+	for _, id := range strings.Split(allowedUserIDs, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		parsedID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			src.fail("RBOT_BOT_ALLOWED_USERIDS contains non-integer value: %s", id)
+			continue
+		}
+		config.AllowedChatIDs[parsedID] = true
 	}
-	if botMaxItems == "" {
-		return config, errors.New("RBOT_BOT_MAX_ITEMS is empty or not set")
+
+	// Parsing RBOT_BOT_ADMIN_USERIDS as a list of integers, optional
+	config.AdminChatIDs = make(map[int64]bool)
+	for _, id := range strings.Split(src.get("RBOT_BOT_ADMIN_USERIDS"), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		parsedID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			src.fail("RBOT_BOT_ADMIN_USERIDS contains non-integer value: %s", id)
+			continue
+		}
+		config.AdminChatIDs[parsedID] = true
 	}
-	if botIgnoreTags == "" {
-		return config, errors.New("RBOT_BOT_IGNORE_TAGS is empty or not set")
+
+	// Parsing RBOT_BOT_MAX_ITEMS as a number, optional
+	config.MaxItems = DefaultMaxItems
+	if botMaxItems := src.get("RBOT_BOT_MAX_ITEMS"); botMaxItems != "" {
+		maxItems, err := strconv.Atoi(botMaxItems)
+		if err != nil || maxItems < 1 {
+			src.fail("RBOT_BOT_MAX_ITEMS is not a valid number")
+		}
+		config.MaxItems = maxItems
 	}
-	// Normalize and validate RBOT_RADARR_PROTOCOL
-	config.RadarrProtocol = strings.ToLower(config.RadarrProtocol)
-	if config.RadarrProtocol != "http" && config.RadarrProtocol != "https" {
-		return config, errors.New("RBOT_RADARR_PROTOCOL must be http or https")
+
+	// Parsing RBOT_BOT_IGNORE_TAGS as a boolean, optional
+	if botIgnoreTags := src.get("RBOT_BOT_IGNORE_TAGS"); botIgnoreTags != "" {
+		ignoreTags, err := strconv.ParseBool(botIgnoreTags)
+		if err != nil {
+			src.fail("RBOT_BOT_IGNORE_TAGS is not a valid boolean")
+		}
+		config.IgnoreTags = ignoreTags
 	}
-	if config.RadarrHostname == "" {
-		return config, errors.New("RBOT_RADARR_HOSTNAME is empty or not set")
+
+	// Parsing RBOT_BOT_DELETE_GRACE_PERIOD as a number of seconds, optional
+	if botDeleteGracePeriod := src.get("RBOT_BOT_DELETE_GRACE_PERIOD"); botDeleteGracePeriod != "" {
+		gracePeriod, err := strconv.Atoi(botDeleteGracePeriod)
+		if err != nil || gracePeriod < 0 {
+			src.fail("RBOT_BOT_DELETE_GRACE_PERIOD is not a valid number of seconds")
+		}
+		config.DeleteGracePeriod = time.Duration(gracePeriod) * time.Second
 	}
-	if radarrPort == "" {
-		return config, errors.New("RBOT_RADARR_PORT is empty or not set")
+
+	// Parsing RBOT_BOT_STARTUP_CHECK, optional
+	config.StartupCheck = strings.ToLower(src.get("RBOT_BOT_STARTUP_CHECK"))
+	switch config.StartupCheck {
+	case "":
+		config.StartupCheck = StartupCheckWarn
+	case StartupCheckWarn, StartupCheckFail, StartupCheckRetry:
+	default:
+		src.fail("RBOT_BOT_STARTUP_CHECK must be warn, fail or retry")
 	}
-	if config.RadarrAPIKey == "" {
-		return config, errors.New("RBOT_RADARR_API_KEY is empty or not set")
+
+	// Parsing RBOT_LOG_LEVEL as debug, info, warn or error, optional
+	if logLevel := src.get("RBOT_LOG_LEVEL"); logLevel != "" {
+		if err := config.LogLevel.UnmarshalText([]byte(logLevel)); err != nil {
+			src.fail("RBOT_LOG_LEVEL must be debug, info, warn or error")
+		}
 	}
 
-	// Parsing RBOT_BOT_MAX_ITEMS as a number
-	maxItems, err := strconv.Atoi(botMaxItems)
-	if err != nil {
-		return config, errors.New("RBOT_BOT_MAX_ITEMS is not a valid number")
+	// Parsing RBOT_LOG_FORMAT, optional
+	config.LogFormat = strings.ToLower(src.get("RBOT_LOG_FORMAT"))
+	switch config.LogFormat {
+	case "":
+		config.LogFormat = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		src.fail("RBOT_LOG_FORMAT must be text or json")
 	}
-	config.MaxItems = maxItems
 
-	// Parsing RBOT_BOT_IGNORE_TAGS as a boolean
-	ignoreTags, err := strconv.ParseBool(botIgnoreTags)
-	if err != nil {
-		return config, errors.New("RBOT_BOT_IGNORE_TAGS is not a valid boolean")
+	// RBOT_HTTP_ADDRESS like :9090 enables the HTTP server, optional
+	config.HTTPAddress = src.get("RBOT_HTTP_ADDRESS")
+
+	// Parsing RBOT_METRICS_INTERVAL as a number of seconds, optional
+	config.MetricsInterval = DefaultMetricsInterval
+	if metricsInterval := src.get("RBOT_METRICS_INTERVAL"); metricsInterval != "" {
+		interval, err := strconv.Atoi(metricsInterval)
+		if err != nil || interval < 1 {
+			src.fail("RBOT_METRICS_INTERVAL is not a valid number of seconds")
+		}
+		config.MetricsInterval = time.Duration(interval) * time.Second
 	}
-	config.IgnoreTags = ignoreTags
 
-	// Parsing RBOT_BOT_ALLOWED_USERIDS as a list of integers
-	userIDs := strings.Split(allowedUserIDs, ",")
-	parsedUserIDs := make(map[int64]bool)
-	for _, id := range userIDs {
+	// Parsing RBOT_HEALTH_MAX_POLL_AGE as a number of seconds, optional
+	config.MaxPollAge = DefaultMaxPollAge
+	if maxPollAge := src.get("RBOT_HEALTH_MAX_POLL_AGE"); maxPollAge != "" {
+		age, err := strconv.Atoi(maxPollAge)
+		if err != nil || age < 1 {
+			src.fail("RBOT_HEALTH_MAX_POLL_AGE is not a valid number of seconds")
+		}
+		config.MaxPollAge = time.Duration(age) * time.Second
+	}
+
+	// RBOT_DATA_FILE keeps data like digest subscriptions across restarts, optional
+	config.DataFile = src.get("RBOT_DATA_FILE")
+
+	// Parsing RBOT_DISK_MIN_FREE as a size like 50GB or a percentage like 10%, optional
+	if minFree := src.get("RBOT_DISK_MIN_FREE"); minFree != "" {
+		if percent, found := strings.CutSuffix(minFree, "%"); found {
+			value, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+			if err != nil || value <= 0 || value >= 100 {
+				src.fail("RBOT_DISK_MIN_FREE is not a valid percentage")
+			}
+			config.DiskMinFreePct = value
+		} else {
+			size, err := utils.ParseSize(strings.ReplaceAll(minFree, " ", ""))
+			if err != nil || size <= 0 {
+				src.fail("RBOT_DISK_MIN_FREE is not a valid size or percentage")
+			}
+			config.DiskMinFree = size
+		}
+	}
+
+	// Parsing RBOT_DISK_ALERT_USERIDS as a list of integers, optional
+	config.DiskAlertChatIDs = make(map[int64]bool)
+	for _, id := range strings.Split(src.get("RBOT_DISK_ALERT_USERIDS"), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
 		parsedID, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			return config, fmt.Errorf("RBOT_BOT_ALLOWED_USERIDS contains non-integer value: %s", err)
+			src.fail("RBOT_DISK_ALERT_USERIDS contains non-integer value: %s", id)
+			continue
 		}
-		parsedUserIDs[parsedID] = true
+		config.DiskAlertChatIDs[parsedID] = true
 	}
-	config.AllowedChatIDs = parsedUserIDs
 
-	// Parsing RBOT_RADARR_PORT as a number
-	port, err := strconv.Atoi(radarrPort)
-	if err != nil {
-		return config, errors.New("RBOT_RADARR_PORT is not a valid number")
+	// Parsing RBOT_DISK_CHECK_INTERVAL as a number of seconds, optional
+	config.DiskInterval = DefaultDiskInterval
+	if diskInterval := src.get("RBOT_DISK_CHECK_INTERVAL"); diskInterval != "" {
+		interval, err := strconv.Atoi(diskInterval)
+		if err != nil || interval < 1 {
+			src.fail("RBOT_DISK_CHECK_INTERVAL is not a valid number of seconds")
+		}
+		config.DiskInterval = time.Duration(interval) * time.Second
+	}
+
+	// Parsing RBOT_HEALTH_ALERT_INTERVAL as a number of seconds, 0 disables the alerts, optional
+	config.HealthInterval = DefaultHealthInterval
+	if healthInterval := src.get("RBOT_HEALTH_ALERT_INTERVAL"); healthInterval != "" {
+		interval, err := strconv.Atoi(healthInterval)
+		if err != nil || interval < 0 {
+			src.fail("RBOT_HEALTH_ALERT_INTERVAL is not a valid number of seconds")
+		}
+		config.HealthInterval = time.Duration(interval) * time.Second
+	}
+
+	// RBOT_PUBLIC_URL is the address of the HTTP server in calendar links, optional
+	config.PublicURL = strings.TrimSuffix(src.get("RBOT_PUBLIC_URL"), "/")
+	if config.PublicURL != "" && !strings.HasPrefix(config.PublicURL, "http://") && !strings.HasPrefix(config.PublicURL, "https://") {
+		src.fail("RBOT_PUBLIC_URL must start with http:// or https://")
+	}
+
+	// Without RBOT_RADARR_INSTANCES there is a single instance configured by
+	// RBOT_RADARR_*, otherwise every named instance uses RBOT_RADARR_<NAME>_*.
+	radarrInstances := src.get("RBOT_RADARR_INSTANCES")
+	if radarrInstances == "" {
+		name := src.get("RBOT_RADARR_NAME")
+		if name == "" {
+			name = "Radarr"
+		}
+		config.Radarrs = append(config.Radarrs, loadServerConfig(src, name, "RBOT_RADARR_", DefaultRadarrPort))
+	} else {
+		names := make(map[string]bool)
+		for _, name := range strings.Split(radarrInstances, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if names[strings.ToLower(name)] {
+				src.fail("RBOT_RADARR_INSTANCES contains %s more than once", name)
+				continue
+			}
+			names[strings.ToLower(name)] = true
+			config.Radarrs = append(config.Radarrs, loadServerConfig(src, name, "RBOT_RADARR_"+envName(name)+"_", DefaultRadarrPort))
+		}
+		if len(config.Radarrs) == 0 {
+			src.fail("RBOT_RADARR_INSTANCES contains no instance names")
+		}
+	}
+
+	// Sonarr is optional, it's enabled by setting RBOT_SONARR_HOSTNAME
+	if src.get("RBOT_SONARR_HOSTNAME") != "" {
+		sonarr := loadServerConfig(src, "Sonarr", "RBOT_SONARR_", DefaultSonarrPort)
+		config.Sonarr = &sonarr
+	}
+
+	return config, errors.Join(src.errs...)
+}
+
+// loadServerConfig reads the connection of a Radarr or Sonarr instance from
+// the settings starting with prefix.
+func loadServerConfig(src *source, name, prefix string, defaultPort int) ServerConfig {
+	server := ServerConfig{
+		Name:     name,
+		Protocol: strings.ToLower(src.get(prefix + "PROTOCOL")),
+		Hostname: src.get(prefix + "HOSTNAME"),
+		Port:     defaultPort,
+		APIKey:   src.get(prefix + "API_KEY"),
+		BaseUrl:  src.get(prefix + "BASE_URL"),
+	}
+
+	if server.Protocol == "" {
+		server.Protocol = DefaultProtocol
 	}
-	config.RadarrPort = port
+	if server.Protocol != "http" && server.Protocol != "https" {
+		src.fail("%sPROTOCOL must be http or https", prefix)
+	}
+	if server.Hostname == "" {
+		src.fail("%sHOSTNAME is empty or not set", prefix)
+	}
+	if server.APIKey == "" {
+		src.fail("%sAPI_KEY is empty or not set", prefix)
+	}
+
+	// Parsing PORT as a number, optional
+	if port := src.get(prefix + "PORT"); port != "" {
+		portNumber, err := strconv.Atoi(port)
+		if err != nil || portNumber < 1 || portNumber > 65535 {
+			src.fail("%sPORT is not a valid port", prefix)
+		}
+		server.Port = portNumber
+	}
+
+	return server
+}
+
+// envName converts an instance name to its environment variable part, e.g. "4k-hdr" to "4K_HDR".
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
+// IsAdmin reports whether the chat may use admin commands.
+func (c Config) IsAdmin(chatID int64) bool {
+	if len(c.AdminChatIDs) == 0 {
+		return c.AllowedChatIDs[chatID]
+	}
+	return c.AdminChatIDs[chatID]
+}
 
-	return config, nil
+// AdminChats returns the chats of the admins, all allowed chats if no
+// admins are configured.
+func (c Config) AdminChats() map[int64]bool {
+	if len(c.AdminChatIDs) == 0 {
+		return c.AllowedChatIDs
+	}
+	return c.AdminChatIDs
+}
+
+// DiskAlertChats returns the chats which are alerted about low disk space.
+func (c Config) DiskAlertChats() map[int64]bool {
+	if len(c.DiskAlertChatIDs) > 0 {
+		return c.DiskAlertChatIDs
+	}
+	return c.AdminChats()
+}
+
+// RestartRequired reports whether connection, HTTP server or log format
+// settings changed, they are only applied by a restart and not by a reload.
+func (c Config) RestartRequired(previous Config) bool {
+	if c.TelegramBotToken != previous.TelegramBotToken || c.LogFormat != previous.LogFormat ||
+		c.HTTPAddress != previous.HTTPAddress || c.MetricsInterval != previous.MetricsInterval || c.MaxPollAge != previous.MaxPollAge ||
+		c.DataFile != previous.DataFile ||
+		len(c.Radarrs) != len(previous.Radarrs) {
+		return true
+	}
+	for i := range c.Radarrs {
+		if c.Radarrs[i] != previous.Radarrs[i] {
+			return true
+		}
+	}
+	if (c.Sonarr == nil) != (previous.Sonarr == nil) {
+		return true
+	}
+	return c.Sonarr != nil && *c.Sonarr != *previous.Sonarr
+}
+
+// Secrets returns the bot token and API keys, which must never be logged.
+func (c Config) Secrets() []string {
+	secrets := []string{c.TelegramBotToken}
+	for _, radarr := range c.Radarrs {
+		secrets = append(secrets, radarr.APIKey)
+	}
+	if c.Sonarr != nil {
+		secrets = append(secrets, c.Sonarr.APIKey)
+	}
+	return secrets
 }
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// keyPattern matches the keys of the config file, they're parts of
+// environment variable names.
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// readConfigFile reads a config file in YAML or TOML. The nested keys are
+// mapped to the names of the environment variables, e.g. max_items in the
+// bot section to RBOT_BOT_MAX_ITEMS, lists are joined with commas.
+//
+// Only the subset of both formats a config needs is supported: sections,
+// keys of letters, digits, - and _, single line values and flat lists.
+// Anything else is rejected with the line number, rather than misread.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err = parseYAML(lines)
+	case ".toml":
+		values, err = parseTOML(lines)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+type yamlSection struct {
+	indent  int
+	path    []string
+	hasKeys bool // a mapping, list items can't follow
+}
+
+func parseYAML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+	var sections []yamlSection
+	for number, line := range lines {
+		content := strings.TrimSpace(stripComment(line))
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.ContainsRune(line[:len(line)-len(strings.TrimLeft(line, " \t"))], '\t') {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", number+1)
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		isItem := content == "-" || strings.HasPrefix(content, "- ")
+		item := strings.TrimPrefix(content, "-")
+		// list items may be indented like their key, "key:" followed by "- 1"
+		for len(sections) > 0 && (sections[len(sections)-1].indent > indent || sections[len(sections)-1].indent == indent && !isItem) {
+			sections = sections[:len(sections)-1]
+		}
+		var path []string
+		if len(sections) > 0 {
+			path = sections[len(sections)-1].path
+		}
+
+		// list item of the enclosing key
+		if isItem {
+			if len(path) == 0 {
+				return nil, fmt.Errorf("line %d: list item without key", number+1)
+			}
+			if sections[len(sections)-1].hasKeys {
+				return nil, fmt.Errorf("line %d: list item in section %s", number+1, strings.Join(path, "."))
+			}
+			parsed, err := parseYAMLItem(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number+1, err)
+			}
+			name := variableName(path)
+			if values[name] != "" {
+				values[name] += ","
+			}
+			values[name] += parsed
+			continue
+		}
+
+		key, value, err := cutKey(content, ":")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		keyPath := append(append([]string(nil), path...), key)
+		if len(sections) > 0 {
+			sections[len(sections)-1].hasKeys = true
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// a section or a list follows
+			sections = append(sections, yamlSection{indent: indent, path: keyPath})
+			continue
+		}
+		parsed, err := parseYAMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		values[variableName(keyPath)] = parsed
+	}
+	return values, nil
+}
+
+// parseYAMLValue parses a value after "key:", rejecting the YAML features
+// which don't fit on the line.
+func parseYAMLValue(value string) (string, error) {
+	if strings.ContainsAny(value[:1], "|>&*!") {
+		return "", fmt.Errorf("value %s: block scalars, anchors, aliases and tags aren't supported", value)
+	}
+	return parseValue(value)
+}
+
+// parseYAMLItem parses the value of a list item, lists of sections aren't
+// supported.
+func parseYAMLItem(item string) (string, error) {
+	if item == "" {
+		return "", fmt.Errorf("empty list item")
+	}
+	// like in YAML, a colon only starts a value if a space or the line end follows
+	if !strings.ContainsAny(item[:1], `"'`) && (strings.Contains(item, ": ") || strings.HasSuffix(item, ":")) {
+		return "", fmt.Errorf("list item %s: lists of sections aren't supported", item)
+	}
+	if strings.HasPrefix(item, "[") {
+		return "", fmt.Errorf("list item %s: nested lists aren't supported", item)
+	}
+	return parseYAMLValue(item)
+}
+
+// cutKey splits "key: value" or "key = value" at the separator. The key may
+// be quoted and must be made of letters, digits, - and _.
+func cutKey(content, separator string) (key, value string, err error) {
+	if content[0] == '"' || content[0] == '\'' {
+		end := strings.IndexByte(content[1:], content[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("key %s isn't closed", content)
+		}
+		key, value = content[1:end+1], strings.TrimSpace(content[end+2:])
+		if value, found := strings.CutPrefix(value, separator); found {
+			return key, strings.TrimSpace(value), checkKey(key)
+		}
+		return "", "", fmt.Errorf("missing %q after key %s", separator, content[:end+2])
+	}
+	key, value, found := strings.Cut(content, separator)
+	if !found {
+		if separator == "=" {
+			return "", "", fmt.Errorf("expected 'key = value'")
+		}
+		return "", "", fmt.Errorf("expected 'key: value'")
+	}
+	key = strings.TrimSpace(key)
+	return key, strings.TrimSpace(value), checkKey(key)
+}
+
+func checkKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("key %q isn't supported, use letters, digits, - and _", key)
+	}
+	return nil
+}
+
+func parseTOML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+	var section []string
+	for number, line := range lines {
+		content := strings.TrimSpace(stripComment(line))
+		if content == "" {
+			continue
+		}
+		if strings.HasPrefix(content, "[[") {
+			return nil, fmt.Errorf("line %d: arrays of tables aren't supported", number+1)
+		}
+		if strings.HasPrefix(content, "[") && strings.HasSuffix(content, "]") {
+			section = nil
+			for _, part := range strings.Split(strings.Trim(content, "[]"), ".") {
+				part = unquote(strings.TrimSpace(part))
+				if err := checkKey(part); err != nil {
+					return nil, fmt.Errorf("line %d: %w", number+1, err)
+				}
+				section = append(section, part)
+			}
+			continue
+		}
+
+		key, value, err := cutKey(content, "=")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		keyPath := append(append([]string(nil), section...), key)
+		parsed, err := parseValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+		values[variableName(keyPath)] = parsed
+	}
+	return values, nil
+}
+
+// parseValue returns a scalar unquoted and a list like [1, 2] joined with commas.
+func parseValue(value string) (string, error) {
+	if strings.HasPrefix(value, "{") {
+		return "", fmt.Errorf("value %s: inline tables and flow maps aren't supported", value)
+	}
+	if !strings.HasPrefix(value, "[") {
+		return parseScalar(value)
+	}
+	if !strings.HasSuffix(value, "]") {
+		return "", fmt.Errorf("list %s isn't closed", value)
+	}
+	var items []string
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.ContainsAny(item[:1], "[{") {
+			return "", fmt.Errorf("list %s: nested lists and maps aren't supported", value)
+		}
+		parsed, err := parseScalar(item)
+		if err != nil {
+			return "", fmt.Errorf("list %s: %w", value, err)
+		}
+		items = append(items, parsed)
+	}
+	return strings.Join(items, ","), nil
+}
+
+// parseScalar unquotes a value, which has to end on its line.
+func parseScalar(value string) (string, error) {
+	if strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''") {
+		return "", fmt.Errorf("value %s: multi-line strings aren't supported", value)
+	}
+	if (value[0] == '"' || value[0] == '\'') && (len(value) < 2 || value[len(value)-1] != value[0]) {
+		return "", fmt.Errorf("string %s isn't closed, multi-line strings and commas in quoted list items aren't supported", value)
+	}
+	return unquote(value), nil
+}
+
+// stripComment removes a # comment which isn't part of a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// variableName returns the environment variable of a key path, e.g.
+// [radarr, 4k, port] is RBOT_RADARR_4K_PORT.
+func variableName(path []string) string {
+	return "RBOT_" + envName(strings.Join(path, "_"))
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "sections and values",
+			input: `---
+telegram:
+  bot_token: "1460:AAH" # token
+bot:
+  max_items: 10
+radarr:
+  4k:
+    port: 7879
+  hostname: 192.168.2.2`,
+			want: map[string]string{
+				"RBOT_TELEGRAM_BOT_TOKEN": "1460:AAH",
+				"RBOT_BOT_MAX_ITEMS":      "10",
+				"RBOT_RADARR_4K_PORT":     "7879",
+				"RBOT_RADARR_HOSTNAME":    "192.168.2.2",
+			},
+		},
+		{
+			name:  "flow list",
+			input: "bot:\n  allowed_userids: [123, 987, -567]",
+			want:  map[string]string{"RBOT_BOT_ALLOWED_USERIDS": "123,987,-567"},
+		},
+		{
+			name:  "indented list",
+			input: "bot:\n  allowed_userids:\n    - 123\n    - '987'\n  max_items: 5",
+			want:  map[string]string{"RBOT_BOT_ALLOWED_USERIDS": "123,987", "RBOT_BOT_MAX_ITEMS": "5"},
+		},
+		{
+			name:  "list at the indent of its key",
+			input: "bot:\n  allowed_userids:\n  - 123\n  - -567\n  max_items: 5\nradarr:\n  port: 7878",
+			want: map[string]string{
+				"RBOT_BOT_ALLOWED_USERIDS": "123,-567",
+				"RBOT_BOT_MAX_ITEMS":       "5",
+				"RBOT_RADARR_PORT":         "7878",
+			},
+		},
+		{
+			name:    "list item without key",
+			input:   "- 123",
+			wantErr: "line 1: list item without key",
+		},
+		{
+			name:    "list item in a section",
+			input:   "bot:\n  max_items: 5\n- 123",
+			wantErr: "line 3: list item in section bot",
+		},
+		{
+			name:    "no colon",
+			input:   "bot:\n  max_items 5",
+			wantErr: "line 2: expected 'key: value'",
+		},
+		{
+			name:    "unclosed list",
+			input:   "bot:\n  allowed_userids: [1, 2",
+			wantErr: "line 2: list [1, 2 isn't closed",
+		},
+		{
+			name:  "quoted keys and colons in values",
+			input: "\"telegram\":\n  'bot_token': 1460:AAH\nbot:\n  allowed_userids:\n    - 12:34",
+			want:  map[string]string{"RBOT_TELEGRAM_BOT_TOKEN": "1460:AAH", "RBOT_BOT_ALLOWED_USERIDS": "12:34"},
+		},
+		{
+			name:    "quoted key with a colon",
+			input:   "bot:\n  \"max:items\": 5",
+			wantErr: `line 2: key "max:items" isn't supported, use letters, digits, - and _`,
+		},
+		{
+			name:    "block scalar",
+			input:   "telegram:\n  bot_token: |\n    1460:AAH",
+			wantErr: "line 2: value |: block scalars, anchors, aliases and tags aren't supported",
+		},
+		{
+			name:    "alias",
+			input:   "bot:\n  max_items: *items",
+			wantErr: "line 2: value *items: block scalars, anchors, aliases and tags aren't supported",
+		},
+		{
+			name:    "multi-line string",
+			input:   "telegram:\n  bot_token: \"1460\n    AAH\"",
+			wantErr: `line 2: string "1460 isn't closed, multi-line strings and commas in quoted list items aren't supported`,
+		},
+		{
+			name:    "flow map",
+			input:   "radarr: {hostname: localhost}",
+			wantErr: "line 1: value {hostname: localhost}: inline tables and flow maps aren't supported",
+		},
+		{
+			name:    "nested flow list",
+			input:   "bot:\n  allowed_userids: [[1, 2]]",
+			wantErr: "line 2: list [[1, 2]]: nested lists and maps aren't supported",
+		},
+		{
+			name:    "list of sections",
+			input:   "radarr:\n  instances:\n    - name: hd",
+			wantErr: "line 3: list item name: hd: lists of sections aren't supported",
+		},
+		{
+			name:    "tab indent",
+			input:   "bot:\n\tmax_items: 5",
+			wantErr: "line 2: indent with spaces, not tabs",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseYAML(strings.Split(test.input, "\n"))
+			checkParsed(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "sections and values",
+			input: `[telegram]
+bot_token = "1460:AAH#1" # the hash in quotes stays
+
+[bot]
+allowed_userids = [123, 987, -567]
+max_items = 10
+
+[radarr."4k"]
+port = 7879`,
+			want: map[string]string{
+				"RBOT_TELEGRAM_BOT_TOKEN":  "1460:AAH#1",
+				"RBOT_BOT_ALLOWED_USERIDS": "123,987,-567",
+				"RBOT_BOT_MAX_ITEMS":       "10",
+				"RBOT_RADARR_4K_PORT":      "7879",
+			},
+		},
+		{
+			name:    "no equals sign",
+			input:   "[bot]\nmax_items 10",
+			wantErr: "line 2: expected 'key = value'",
+		},
+		{
+			name:    "array of tables",
+			input:   "[[radarr]]\nport = 7878",
+			wantErr: "line 1: arrays of tables aren't supported",
+		},
+		{
+			name:    "dotted key",
+			input:   "[bot]\nlog.level = \"debug\"",
+			wantErr: `line 2: key "log.level" isn't supported, use letters, digits, - and _`,
+		},
+		{
+			name:    "inline table",
+			input:   "radarr = { port = 7878 }",
+			wantErr: "line 1: value { port = 7878 }: inline tables and flow maps aren't supported",
+		},
+		{
+			name:    "multi-line string",
+			input:   "[telegram]\nbot_token = \"\"\"\n1460:AAH\"\"\"",
+			wantErr: `line 2: value """: multi-line strings aren't supported`,
+		},
+		{
+			name:    "comma in a quoted list item",
+			input:   "[bot]\nignore_tags = [\"a,b\"]",
+			wantErr: `line 2: list ["a,b"]: string "a isn't closed, multi-line strings and commas in quoted list items aren't supported`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTOML(strings.Split(test.input, "\n"))
+			checkParsed(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func checkParsed(t *testing.T, got map[string]string, err error, want map[string]string, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "10", want: "10"},
+		{input: `"quoted, value"`, want: "quoted, value"},
+		{input: "'single'", want: "single"},
+		{input: "[]", want: ""},
+		{input: "[1, 2 ,3,]", want: "1,2,3"},
+		{input: `["a", 'b']`, want: "a,b"},
+		{input: "[1, 2", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseValue(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseValue(%q) error = %v, want error %v", test.input, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseValue(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "key: value", want: "key: value"},
+		{input: "key: value # comment", want: "key: value "},
+		{input: "# comment", want: ""},
+		{input: `key: "a # b" # comment`, want: `key: "a # b" `},
+		{input: `key: 'it"s # here'`, want: `key: 'it"s # here'`},
+		{input: `key: "unclosed # still quoted`, want: `key: "unclosed # still quoted`},
+	}
+	for _, test := range tests {
+		if got := stripComment(test.input); got != test.want {
+			t.Errorf("stripComment(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package ics writes iCalendar (RFC 5545) feeds of all-day events, which
+// calendar apps can subscribe to.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLength is the length in octets a content line is folded at.
+const maxLineLength = 75
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event.
+type Event struct {
+	UID         string // unique and stable, so updates replace the event
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+}
+
+// Write writes the calendar in the iCalendar format.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//woiza//telegram-bot-radarr//EN")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	for _, event := range c.Events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+escape(event.UID))
+		writeLine(bw, "DTSTAMP:"+stamp)
+		writeLine(bw, "DTSTART;VALUE=DATE:"+event.Date.Format("20060102"))
+		writeLine(bw, "DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"))
+		writeLine(bw, "SUMMARY:"+escape(event.Summary))
+		if event.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+escape(event.Description))
+		}
+		if event.URL != "" {
+			writeLine(bw, "URL:"+event.URL)
+		}
+		writeLine(bw, "TRANSP:TRANSPARENT")
+		writeLine(bw, "END:VEVENT")
+	}
+	writeLine(bw, "END:VCALENDAR")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing calendar: %w", err)
+	}
+	return nil
+}
+
+// escape escapes a text value.
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// writeLine writes a content line, folded after 75 octets without
+// splitting UTF-8 characters.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts
+		limit = maxLineLength - 1
+	}
+	w.WriteString(line + "\r\n")
+}
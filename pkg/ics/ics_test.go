@@ -0,0 +1,109 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{input: "The Matrix", want: "The Matrix"},
+		{input: "Dune; Part Two, 2024", want: `Dune\; Part Two\, 2024`},
+		{input: `C:\movies`, want: `C:\\movies`},
+		{input: "line\nbreak\r\nand more", want: `line\nbreak\nand more`},
+	}
+	for _, test := range tests {
+		if got := escape(test.input); got != test.want {
+			t.Errorf("escape(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{name: "short", input: "SUMMARY:Dune", want: "SUMMARY:Dune\r\n"},
+		{name: "exactly 75 octets", input: strings.Repeat("a", 75), want: strings.Repeat("a", 75) + "\r\n"},
+		{
+			name:  "folded twice",
+			input: strings.Repeat("a", 75+74+3),
+			want:  strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n " + "aaa\r\n",
+		},
+		{
+			// é is two octets, it must not be split at the 75th octet
+			name:  "multi-byte character at the limit",
+			input: strings.Repeat("a", 74) + "éb",
+			want:  strings.Repeat("a", 74) + "\r\n éb\r\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := bufio.NewWriter(&out)
+			writeLine(w, test.input)
+			w.Flush()
+			if out.String() != test.want {
+				t.Errorf("got %q, want %q", out.String(), test.want)
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+				if len(line) > maxLineLength || !utf8.ValidString(line) {
+					t.Errorf("invalid folded line %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarWrite(t *testing.T) {
+	calendar := &Calendar{
+		Name: "Radarr, upcoming",
+		Events: []Event{{
+			UID:         "radarr-603-digital@example",
+			Date:        time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			Summary:     "The Matrix (digital release)",
+			Description: "Sci-fi; action",
+			URL:         "https://www.themoviedb.org/movie/603",
+		}},
+	}
+	var out bytes.Buffer
+	if err := calendar.Write(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(out.String(), "\r\n")
+	// DTSTAMP is the current time
+	for i, line := range lines {
+		if strings.HasPrefix(line, "DTSTAMP:") {
+			lines[i] = "DTSTAMP:<now>"
+		}
+	}
+	want := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//woiza//telegram-bot-radarr//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Radarr\, upcoming`,
+		"BEGIN:VEVENT",
+		"UID:radarr-603-digital@example",
+		"DTSTAMP:<now>",
+		"DTSTART;VALUE=DATE:20241231",
+		"DTEND;VALUE=DATE:20250101",
+		"SUMMARY:The Matrix (digital release)",
+		`DESCRIPTION:Sci-fi\; action`,
+		"URL:https://www.themoviedb.org/movie/603",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
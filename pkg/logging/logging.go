@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const redacted = "REDACTED"
+
+var (
+	level = new(slog.LevelVar)
+
+	// Secrets which end up in URLs, even if they aren't known, like the bot
+	// token in the Telegram API URLs of errors.
+	botTokenPattern = regexp.MustCompile(`bot\d+:[A-Za-z0-9_-]+`)
+	apiKeyPattern   = regexp.MustCompile(`(?i)(api_?key=)[^&\s"]+`)
+)
+
+// Setup makes a logger with the level and format ("text" or "json") the
+// default logger, it's also used by the log package and the Telegram API.
+// The secrets are redacted from every log line.
+func Setup(logLevel slog.Level, format string, secrets ...string) {
+	level.Set(logLevel)
+	redactor := newRedactor(secrets)
+	options := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			return redactor.attr(attr)
+		},
+	}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+	slog.SetDefault(slog.New(handler))
+	if err := tgbotapi.SetLogger(botLogger{}); err != nil {
+		slog.Warn("Cannot set the logger of the Telegram API", "error", err)
+	}
+}
+
+// SetLevel changes the level of the default logger, e.g. on a reload.
+func SetLevel(logLevel slog.Level) {
+	level.Set(logLevel)
+}
+
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+func newRedactor(secrets []string) redactor {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	return redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+func (r redactor) redact(text string) string {
+	text = r.replacer.Replace(text)
+	text = botTokenPattern.ReplaceAllString(text, "bot"+redacted)
+	return apiKeyPattern.ReplaceAllString(text, "${1}"+redacted)
+}
+
+// attr redacts strings and values logged as strings, like errors.
+func (r redactor) attr(attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(r.redact(attr.Value.String()))
+	case slog.KindAny:
+		attr.Value = slog.StringValue(r.redact(fmt.Sprint(attr.Value.Any())))
+	}
+	return attr
+}
+
+// botLogger logs the messages of the Telegram API, e.g. failed update requests.
+type botLogger struct{}
+
+func (botLogger) Println(v ...interface{}) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintln(v...)), "component", "telegram")
+}
+
+func (botLogger) Printf(format string, v ...interface{}) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", "telegram")
+}
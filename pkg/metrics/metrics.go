@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets in seconds for request latencies.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// registry holds all metrics, they are written in the order they were created.
+var registry struct {
+	mu       sync.Mutex
+	families []writer
+}
+
+type writer interface {
+	write(w io.Writer)
+}
+
+func register(family writer) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.families = append(registry.families, family)
+}
+
+// Handler serves all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		registry.mu.Lock()
+		families := append([]writer(nil), registry.families...)
+		registry.mu.Unlock()
+		for _, family := range families {
+			family.write(w)
+		}
+	})
+}
+
+// series is a single set of label values of a metric.
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64 // per bucket, histograms only
+	count       uint64   // histograms only
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newFamily(name, help, kind string, labels []string) family {
+	return family{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*series)}
+}
+
+// get returns the series of the label values, the lock must be held.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series sorted by label values, the lock must be held.
+func (f *family) sorted() []*series {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sorted := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, f.series[key])
+	}
+	return sorted
+}
+
+func (f *family) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+}
+
+// writeValues writes the counter or gauge values, the lock must be held.
+func (f *family) writeValues(w io.Writer) {
+	f.writeHeader(w)
+	for _, s := range f.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues), formatValue(s.value))
+	}
+}
+
+// Counter is a value which only goes up, like the number of handled updates.
+type Counter struct {
+	family
+}
+
+// NewCounter creates and registers a counter with the label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{newFamily(name, help, "counter", labels)}
+	register(counter)
+	return counter
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(value float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues).value += value
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeValues(w)
+}
+
+// Gauge is a value which goes up and down, like the free space of a root folder.
+type Gauge struct {
+	family
+}
+
+// NewGauge creates and registers a gauge with the label names.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	gauge := &Gauge{newFamily(name, help, "gauge", labels)}
+	register(gauge)
+	return gauge
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.get(labelValues).value = value
+}
+
+// Delete removes the series of the label values, e.g. of a removed root folder.
+func (g *Gauge) Delete(labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.series, strings.Join(labelValues, "\xff"))
+}
+
+// Reset removes the series whose first label values are the given ones, all
+// series without values. Collections set them again, so series which are
+// gone, like a removed root folder, aren't exported anymore.
+func (g *Gauge) Reset(labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	prefix := strings.Join(labelValues, "\xff")
+	for key := range g.series {
+		if len(labelValues) == 0 || key == prefix || strings.HasPrefix(key, prefix+"\xff") {
+			delete(g.series, key)
+		}
+	}
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeValues(w)
+}
+
+// GaugeFunc is a gauge whose values are collected when the metrics are scraped.
+type GaugeFunc struct {
+	family
+	collect func(set func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc creates and registers a gauge, collect sets its values on
+// every scrape and must not block.
+func NewGaugeFunc(name, help string, labels []string, collect func(set func(value float64, labelValues ...string))) *GaugeFunc {
+	gauge := &GaugeFunc{family: newFamily(name, help, "gauge", labels), collect: collect}
+	register(gauge)
+	return gauge
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.series = make(map[string]*series)
+	g.collect(func(value float64, labelValues ...string) {
+		g.get(labelValues).value = value
+	})
+	g.writeValues(w)
+}
+
+// Histogram counts observations, like request latencies, in buckets.
+type Histogram struct {
+	family
+	buckets []float64
+}
+
+// NewHistogram creates and registers a histogram with the upper bounds of
+// the buckets and the label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{family: newFamily(name, help, "histogram", labels), buckets: buckets}
+	register(histogram)
+	return histogram
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	bucketLabels := append(append([]string(nil), h.labels...), "le")
+	for _, s := range h.sorted() {
+		for i, bucket := range h.buckets {
+			labels := formatLabels(bucketLabels, append(append([]string(nil), s.labelValues...), formatValue(bucket)))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, s.counts[i])
+		}
+		labels := formatLabels(bucketLabels, append(append([]string(nil), s.labelValues...), "+Inf"))
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labelValues), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labelValues), s.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCounterExposition(t *testing.T) {
+	counter := NewCounter("test_commands_total", "Commands handled.", "command")
+	counter.Inc("q")
+	counter.Add(2, `say "hi"\now`)
+	counter.Inc("line\nbreak")
+	counter.Inc("q")
+
+	var out bytes.Buffer
+	counter.write(&out)
+	want := `# HELP test_commands_total Commands handled.
+# TYPE test_commands_total counter
+test_commands_total{command="line\nbreak"} 1
+test_commands_total{command="q"} 2
+test_commands_total{command="say \"hi\"\\now"} 2
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestGaugeExposition(t *testing.T) {
+	gauge := NewGauge("test_free_bytes", "Free space.", "instance", "path")
+	gauge.Set(1.5e12, "main", "/movies")
+	gauge.Set(42, "main", "/old")
+	gauge.Delete("main", "/old")
+	unlabeled := NewGauge("test_up", "Whether it's up.")
+	unlabeled.Set(1)
+
+	var out bytes.Buffer
+	gauge.write(&out)
+	unlabeled.write(&out)
+	want := `# HELP test_free_bytes Free space.
+# TYPE test_free_bytes gauge
+test_free_bytes{instance="main",path="/movies"} 1.5e+12
+# HELP test_up Whether it's up.
+# TYPE test_up gauge
+test_up 1
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestGaugeReset(t *testing.T) {
+	gauge := NewGauge("test_reset_bytes", "Free space.", "instance", "path")
+	gauge.Set(1, "hd", "/movies")
+	gauge.Set(2, "hd", "/old")
+	gauge.Set(3, "uhd", "/movies")
+	gauge.Reset("hd")
+	gauge.Set(4, "hd", "/movies")
+
+	var out bytes.Buffer
+	gauge.write(&out)
+	want := `# HELP test_reset_bytes Free space.
+# TYPE test_reset_bytes gauge
+test_reset_bytes{instance="hd",path="/movies"} 4
+test_reset_bytes{instance="uhd",path="/movies"} 3
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	gauge.Reset()
+	out.Reset()
+	gauge.write(&out)
+	if want := "# HELP test_reset_bytes Free space.\n# TYPE test_reset_bytes gauge\n"; out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	histogram := NewHistogram("test_duration_seconds", "Latency.", []float64{0.1, 1}, "method")
+	histogram.Observe(0.25, "GET")
+	histogram.Observe(0.5, "GET")
+	histogram.Observe(2, "GET")
+	histogram.Observe(0.1, "POST")
+
+	var out bytes.Buffer
+	histogram.write(&out)
+	want := `# HELP test_duration_seconds Latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="GET",le="0.1"} 0
+test_duration_seconds_bucket{method="GET",le="1"} 2
+test_duration_seconds_bucket{method="GET",le="+Inf"} 3
+test_duration_seconds_sum{method="GET"} 2.75
+test_duration_seconds_count{method="GET"} 3
+test_duration_seconds_bucket{method="POST",le="0.1"} 1
+test_duration_seconds_bucket{method="POST",le="1"} 1
+test_duration_seconds_bucket{method="POST",le="+Inf"} 1
+test_duration_seconds_sum{method="POST"} 0.1
+test_duration_seconds_count{method="POST"} 1
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{value: 0, want: "0"},
+		{value: 3, want: "3"},
+		{value: 0.05, want: "0.05"},
+		{value: -2.5, want: "-2.5"},
+		{value: 1e21, want: "1e+21"},
+		{value: math.Inf(1), want: "+Inf"},
+	}
+	for _, test := range tests {
+		if got := formatValue(test.value); got != test.want {
+			t.Errorf("formatValue(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package radarrapi implements Radarr API endpoints which are not (yet)
+// covered by golift.io/starr. The functions mirror the starr style and use
+// the APIer of a radarr.Radarr to talk to the server.
+package radarrapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const bpCollection = radarr.APIver + "/collection"
+
+// Collection is the /api/v3/collection endpoint.
+type Collection struct {
+	ID                  int64               `json:"id"`
+	Title               string              `json:"title"`
+	SortTitle           string              `json:"sortTitle"`
+	TmdbID              int64               `json:"tmdbId"`
+	Overview            string              `json:"overview"`
+	Monitored           bool                `json:"monitored"`
+	RootFolderPath      string              `json:"rootFolderPath"`
+	QualityProfileID    int64               `json:"qualityProfileId"`
+	SearchOnAdd         bool                `json:"searchOnAdd"`
+	MinimumAvailability radarr.Availability `json:"minimumAvailability"`
+	Movies              []*CollectionMovie  `json:"movies"`
+	MissingMovies       int                 `json:"missingMovies"`
+	Tags                []int               `json:"tags"`
+	Images              []*starr.Image      `json:"images,omitempty"`
+}
+
+// CollectionMovie is part of a Collection.
+type CollectionMovie struct {
+	TmdbID     int64    `json:"tmdbId"`
+	ImdbID     string   `json:"imdbId"`
+	Title      string   `json:"title"`
+	Year       int      `json:"year"`
+	Status     string   `json:"status"`
+	Runtime    int      `json:"runtime"`
+	Genres     []string `json:"genres"`
+	IsExisting bool     `json:"isExisting"`
+	IsExcluded bool     `json:"isExcluded"`
+}
+
+// CollectionUpdate is the input for the bulk collection editor.
+// Only non-nil members are changed.
+type CollectionUpdate struct {
+	CollectionIDs       []int64              `json:"collectionIds"`
+	Monitored           *bool                `json:"monitored,omitempty"`
+	MonitorMovies       *bool                `json:"monitorMovies,omitempty"`
+	SearchOnAdd         *bool                `json:"searchOnAdd,omitempty"`
+	QualityProfileID    *int64               `json:"qualityProfileId,omitempty"`
+	RootFolderPath      *string              `json:"rootFolderPath,omitempty"`
+	MinimumAvailability *radarr.Availability `json:"minimumAvailability,omitempty"`
+}
+
+// GetCollections returns all collections, or the collection of a movie if tmdbID is not 0.
+func GetCollections(r *radarr.Radarr, tmdbID int64) ([]*Collection, error) {
+	params := make(url.Values)
+	if tmdbID != 0 {
+		params.Set("tmdbId", fmt.Sprint(tmdbID))
+	}
+
+	var output []*Collection
+
+	req := starr.Request{URI: bpCollection, Query: params}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
+
+// GetCollection returns a single collection by its Radarr ID.
+func GetCollection(r *radarr.Radarr, collectionID int64) (*Collection, error) {
+	var output Collection
+
+	req := starr.Request{URI: path.Join(bpCollection, fmt.Sprint(collectionID))}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return &output, nil
+}
+
+// UpdateCollections changes the settings of one or more collections.
+func UpdateCollections(r *radarr.Radarr, update *CollectionUpdate) ([]*Collection, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(update); err != nil {
+		return nil, fmt.Errorf("json.Marshal(%s): %w", bpCollection, err)
+	}
+
+	var output []*Collection
+
+	req := starr.Request{URI: bpCollection, Body: &body}
+	if err := r.PutInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Put(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,25 @@
+package radarrapi
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const bpCommand = radarr.APIver + "/command"
+
+// GetCommand returns the current state of a command sent with SendCommand.
+func GetCommand(r *radarr.Radarr, commandID int64) (*radarr.CommandResponse, error) {
+	var output radarr.CommandResponse
+
+	req := starr.Request{URI: path.Join(bpCommand, strconv.FormatInt(commandID, 10))}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return &output, nil
+}
@@ -0,0 +1,31 @@
+package radarrapi
+
+import (
+	"context"
+	"fmt"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const bpDiskSpace = radarr.APIver + "/diskspace"
+
+// DiskSpace is a disk of the /api/v3/diskspace endpoint.
+type DiskSpace struct {
+	Path       string `json:"path"`
+	Label      string `json:"label"`
+	FreeSpace  int64  `json:"freeSpace"`
+	TotalSpace int64  `json:"totalSpace"`
+}
+
+// GetDiskSpace returns the free and total space of the disks Radarr sees.
+func GetDiskSpace(r *radarr.Radarr) ([]*DiskSpace, error) {
+	var output []*DiskSpace
+
+	req := starr.Request{URI: bpDiskSpace}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,51 @@
+package radarrapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const (
+	bpHealth       = radarr.APIver + "/health"
+	bpHistorySince = radarr.APIver + "/history/since"
+)
+
+// Health is an issue of the /api/v3/health endpoint, e.g. an unavailable indexer.
+type Health struct {
+	Source  string `json:"source"`
+	Type    string `json:"type"` // ok, notice, warning or error
+	Message string `json:"message"`
+	WikiURL string `json:"wikiUrl"`
+}
+
+// GetHealth returns the current health issues.
+func GetHealth(r *radarr.Radarr) ([]*Health, error) {
+	var output []*Health
+
+	req := starr.Request{URI: bpHealth}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
+
+// GetHistorySince returns the history records since the date, oldest first.
+func GetHistorySince(r *radarr.Radarr, since time.Time) ([]*radarr.HistoryRecord, error) {
+	params := make(url.Values)
+	params.Set("date", since.UTC().Format(time.RFC3339))
+
+	var output []*radarr.HistoryRecord
+
+	req := starr.Request{URI: bpHistorySince, Query: params}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
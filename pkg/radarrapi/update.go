@@ -0,0 +1,48 @@
+package radarrapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golift.io/starr"
+	"golift.io/starr/radarr"
+)
+
+const bpUpdate = radarr.APIver + "/update"
+
+// Update is a release of the /api/v3/update endpoint, newest first.
+type Update struct {
+	Version     string    `json:"version"`
+	Branch      string    `json:"branch"`
+	ReleaseDate time.Time `json:"releaseDate"`
+	Installed   bool      `json:"installed"`
+	Installable bool      `json:"installable"`
+	Latest      bool      `json:"latest"`
+}
+
+// GetUpdates returns the recent releases of the branch Radarr runs on.
+func GetUpdates(r *radarr.Radarr) ([]*Update, error) {
+	var output []*Update
+
+	req := starr.Request{URI: bpUpdate}
+	if err := r.GetInto(context.Background(), req, &output); err != nil {
+		return nil, fmt.Errorf("api.Get(%s): %w", &req, err)
+	}
+
+	return output, nil
+}
+
+// AvailableUpdate returns the latest release if it's newer than the
+// installed one and can be installed, nil otherwise.
+func AvailableUpdate(updates []*Update) *Update {
+	for _, update := range updates {
+		if update.Latest {
+			if !update.Installed && update.Installable {
+				return update
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Package store keeps the data which has to survive a restart, like digest
+// subscriptions, in a JSON file. Without a file the data is only kept in
+// memory.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data map[string]json.RawMessage
+}
+
+// Open reads the data file, it's created on the first save if it doesn't
+// exist. An empty path keeps the data in memory.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: make(map[string]json.RawMessage)}
+	if path == "" {
+		return s, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("data file: %w", err)
+	}
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, fmt.Errorf("data file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Load decodes the value saved under key into value, which is left
+// untouched if nothing was saved yet.
+func (s *Store) Load(key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+	if err := json.Unmarshal(raw, value); err != nil {
+		return fmt.Errorf("data file %s: %w", key, err)
+	}
+	return nil
+}
+
+// Save stores the value under key and writes the data file.
+func (s *Store) Save(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("data file %s: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = raw
+	if s.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("data file: %w", err)
+	}
+	// write a temporary file first, so a crash can't leave a truncated file
+	tmp := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("data file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("data file: %w", err)
+	}
+	return nil
+}
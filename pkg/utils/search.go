@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
+)
+
+var (
+	imdbIDPattern    = regexp.MustCompile(`^(?i)(?:imdb:)?(tt\d+)$`)
+	tmdbIDPattern    = regexp.MustCompile(`^(?i)tmdb:(\d+)$`)
+	trailingYear     = regexp.MustCompile(`^(.+?)[\s(\[]+((?:18|19|20)\d{2})[)\]]?$`)
+	yearOnly         = regexp.MustCompile(`^(?:18|19|20)\d{2}$`)
+	minFuzzyWordSize = 4
+	accents          = strings.NewReplacer(
+		"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+		"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+		"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+		"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+		"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+	)
+)
+
+// Match scores, higher is better.
+const (
+	scoreExact     = 90
+	scorePrefix    = 80
+	scoreContains  = 70
+	scoreAllWords  = 60
+	scoreFuzzy     = 50
+	scoreFuzzyWord = 40
+)
+
+// SearchMovies searches the movies locally by title, original title, alternate
+// titles, year, IMDb ID ("tt0133093") and TMDB ID ("tmdb:603"). Matching is case,
+// accent and article insensitive and tolerates small typos. The best matches
+// are returned first.
+func SearchMovies(movies []*radarr.Movie, query string) []*radarr.Movie {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	if matches := imdbIDPattern.FindStringSubmatch(query); matches != nil {
+		return filter(movies, func(movie *radarr.Movie) bool {
+			return strings.EqualFold(movie.ImdbID, matches[1])
+		})
+	}
+	if matches := tmdbIDPattern.FindStringSubmatch(query); matches != nil {
+		tmdbID, _ := strconv.ParseInt(matches[1], 10, 64)
+		return filter(movies, func(movie *radarr.Movie) bool {
+			return movie.TmdbID == tmdbID
+		})
+	}
+
+	// "The Matrix 1999" or "The Matrix (1999)", but the year might be part of the title
+	if matches := trailingYear.FindStringSubmatch(query); matches != nil {
+		year, _ := strconv.Atoi(matches[2])
+		byYear := filter(movies, func(movie *radarr.Movie) bool {
+			return movie.Year == year
+		})
+		if results := searchTitles(byYear, matches[1]); len(results) > 0 {
+			return results
+		}
+	}
+
+	results := searchTitles(movies, query)
+
+	// a bare year lists the movies of that year after titles like "1917"
+	if yearOnly.MatchString(query) {
+		year, _ := strconv.Atoi(query)
+		for _, movie := range movies {
+			if movie.Year == year && !containsMovie(results, movie) {
+				results = append(results, movie)
+			}
+		}
+	}
+	return results
+}
+
+// SearchSeries searches the series locally by title and alternate titles, like
+// SearchMovies does for movies.
+func SearchSeries(series []*sonarr.Series, query string) []*sonarr.Series {
+	return rankByTitle(series, strings.TrimSpace(query), seriesTitles, func(series *sonarr.Series) string {
+		return series.Title
+	})
+}
+
+func seriesTitles(series *sonarr.Series) []string {
+	titles := []string{series.Title}
+	for _, alternateTitle := range series.AlternateTitles {
+		titles = append(titles, alternateTitle.Title)
+	}
+	return titles
+}
+
+func containsMovie(movies []*radarr.Movie, movie *radarr.Movie) bool {
+	for _, m := range movies {
+		if m == movie {
+			return true
+		}
+	}
+	return false
+}
+
+func searchTitles(movies []*radarr.Movie, query string) []*radarr.Movie {
+	return rankByTitle(movies, query, movieTitles, func(movie *radarr.Movie) string {
+		return movie.Title
+	})
+}
+
+// rankByTitle returns the items with a title matching the query, the best
+// matches first and equal matches sorted by name.
+func rankByTitle[T comparable](items []T, query string, titles func(T) []string, name func(T) string) []T {
+	normalizedQuery := NormalizeTitle(query)
+	if normalizedQuery == "" {
+		return nil
+	}
+
+	scores := make(map[T]int)
+	bestScore := 0
+	for _, item := range items {
+		score := 0
+		for _, title := range titles(item) {
+			if titleScore := matchTitle(normalizedQuery, NormalizeTitle(title)); titleScore > score {
+				score = titleScore
+			}
+		}
+		if score > 0 {
+			scores[item] = score
+		}
+		if score > bestScore {
+			bestScore = score
+		}
+	}
+
+	var results []T
+	for item, score := range scores {
+		// drop typo matches if there are real matches
+		if bestScore >= scoreAllWords && score < scoreAllWords {
+			continue
+		}
+		results = append(results, item)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if scores[results[i]] != scores[results[j]] {
+			return scores[results[i]] > scores[results[j]]
+		}
+		return IgnoreArticles(strings.ToLower(name(results[i]))) < IgnoreArticles(strings.ToLower(name(results[j])))
+	})
+	return results
+}
+
+func movieTitles(movie *radarr.Movie) []string {
+	titles := []string{movie.Title}
+	if movie.OriginalTitle != "" {
+		titles = append(titles, movie.OriginalTitle)
+	}
+	for _, alternateTitle := range movie.AlternateTitles {
+		titles = append(titles, alternateTitle.Title)
+	}
+	return titles
+}
+
+func matchTitle(query, title string) int {
+	switch {
+	case title == "":
+		return 0
+	case query == title:
+		return scoreExact
+	case strings.HasPrefix(title, query):
+		return scorePrefix
+	case strings.Contains(title, query):
+		return scoreContains
+	}
+
+	queryWords := strings.Fields(query)
+	titleWords := strings.Fields(title)
+	if containsAllWords(titleWords, queryWords, 0) {
+		return scoreAllWords
+	}
+	if levenshtein(query, title) <= typoTolerance(utf8.RuneCountInString(query)) {
+		return scoreFuzzy
+	}
+	if containsAllWords(titleWords, queryWords, 1) {
+		return scoreFuzzyWord
+	}
+	return 0
+}
+
+// containsAllWords reports whether every query word is a prefix of a title
+// word. Words of at least minFuzzyWordSize runes may differ by typos.
+func containsAllWords(titleWords, queryWords []string, typos int) bool {
+	for _, queryWord := range queryWords {
+		found := false
+		for _, titleWord := range titleWords {
+			if strings.HasPrefix(titleWord, queryWord) {
+				found = true
+				break
+			}
+			if typos > 0 && utf8.RuneCountInString(queryWord) >= minFuzzyWordSize &&
+				levenshtein(queryWord, titleWord) <= typos*typoTolerance(utf8.RuneCountInString(queryWord)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// typoTolerance returns how many typos a word of length runes may have.
+func typoTolerance(length int) int {
+	switch {
+	case length < minFuzzyWordSize:
+		return 0
+	case length < 8:
+		return 1
+	case length < 16:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// NormalizeTitle lower cases the title, removes accents, punctuation and a
+// leading article, so titles can be compared regardless of their notation.
+func NormalizeTitle(title string) string {
+	var normalized strings.Builder
+	for _, r := range accents.Replace(strings.ToLower(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			normalized.WriteRune(r)
+		case r == '&':
+			normalized.WriteString(" and ")
+		default:
+			normalized.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(normalized.String())
+	if len(words) > 1 && isArticle(words[0]) {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func isArticle(word string) bool {
+	for _, article := range articles {
+		if word == article {
+			return true
+		}
+	}
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func filter(movies []*radarr.Movie, condition func(movie *radarr.Movie) bool) []*radarr.Movie {
+	var filtered []*radarr.Movie
+	for _, movie := range movies {
+		if condition(movie) {
+			filtered = append(filtered, movie)
+		}
+	}
+	return filtered
+}
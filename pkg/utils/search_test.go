@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"golift.io/starr/radarr"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "", b: "matrix", want: 6},
+		{a: "matrix", b: "matrix", want: 0},
+		{a: "matirx", b: "matrix", want: 2},
+		{a: "matrx", b: "matrix", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "мир", b: "мор", want: 1},
+		{a: "amélie", b: "amelie", want: 1},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := levenshtein(test.b, test.a); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{input: "The Matrix", want: "matrix"},
+		{input: "Mission: Impossible", want: "mission impossible"},
+		{input: "Amélie", want: "amelie"},
+		{input: "Fast & Furious", want: "fast and furious"},
+		{input: "The", want: "the"},
+	}
+	for _, test := range tests {
+		if got := NormalizeTitle(test.input); got != test.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSearchMovies(t *testing.T) {
+	movies := []*radarr.Movie{
+		{ID: 1, Title: "The Matrix", Year: 1999, ImdbID: "tt0133093", TmdbID: 603},
+		{ID: 2, Title: "The Matrix Reloaded", Year: 2003},
+		{ID: 3, Title: "Matrix of Leadership", Year: 2020},
+		{ID: 4, Title: "Amélie", Year: 2001, OriginalTitle: "Le Fabuleux Destin d'Amélie Poulain"},
+		{ID: 5, Title: "1917", Year: 2019},
+		{ID: 6, Title: "Parasite", Year: 2019},
+		{ID: 7, Title: "Мир", Year: 2015},
+		{ID: 8, Title: "The Lord of the Rings", Year: 2001},
+	}
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{query: "", want: nil},
+		{query: "the matrix", want: []int64{1, 3, 2}},
+		{query: "Matrix Reloaded", want: []int64{2}},
+		{query: "matrix 1999", want: []int64{1}},
+		{query: "The Matrix (2003)", want: []int64{2}},
+		{query: "tt0133093", want: []int64{1}},
+		{query: "tmdb:603", want: []int64{1}},
+		{query: "amelie", want: []int64{4}},
+		{query: "destin amelie", want: []int64{4}},
+		{query: "lord rings", want: []int64{8}},
+		// typos only match if nothing matches better
+		{query: "parasit", want: []int64{6}},
+		{query: "parsite", want: []int64{6}},
+		// a typo in the whole title ranks above a typo in a word
+		{query: "matrx", want: []int64{1, 3, 2}},
+		{query: "lord rimgs", want: []int64{8}},
+		// the tolerance counts runes, a three letter word has no typos
+		{query: "Мор", want: nil},
+		{query: "мир", want: []int64{7}},
+		// a bare year lists the title first, then the movies of that year
+		{query: "1917", want: []int64{5}},
+		{query: "2019", want: []int64{5, 6}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			var got []int64
+			for _, movie := range SearchMovies(movies, test.query) {
+				got = append(got, movie.ID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SearchMovies(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golift.io/starr/radarr"
+)
+
+const (
+	statsTopItems       = 10
+	statsMaxLabelLength = 24
+	unknown             = "unknown"
+)
+
+// statsGroup counts movies and their size per label.
+type statsGroup struct {
+	label string
+	count int
+	size  int64
+}
+
+type statsGroups map[string]*statsGroup
+
+func (g statsGroups) add(label string, size int64) {
+	if label == "" {
+		label = unknown
+	}
+	group, exists := g[label]
+	if !exists {
+		group = &statsGroup{label: label}
+		g[label] = group
+	}
+	group.count++
+	group.size += size
+}
+
+// sorted returns the groups with the most movies first.
+func (g statsGroups) sorted() []*statsGroup {
+	groups := make([]*statsGroup, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].label < groups[j].label
+	})
+	return groups
+}
+
+// PrepareLibraryStats summarizes the library as MarkdownV2 formatted sections,
+// each section is a monospace table.
+func PrepareLibraryStats(movies []*radarr.Movie, rootFolders []*radarr.RootFolder) []string {
+	var monitored, onDisk, missing int
+	var totalSize int64
+	folders := make(statsGroups)
+	qualities := make(statsGroups)
+	resolutions := make(statsGroups)
+	videoCodecs := make(statsGroups)
+	dynamicRanges := make(statsGroups)
+	audioCodecs := make(statsGroups)
+	genres := make(statsGroups)
+	decades := make(statsGroups)
+
+	for _, movie := range movies {
+		if movie.Monitored {
+			monitored++
+		}
+		if movie.HasFile {
+			onDisk++
+		} else if movie.Monitored {
+			missing++
+		}
+		totalSize += movie.SizeOnDisk
+
+		for _, genre := range movie.Genres {
+			genres.add(genre, movie.SizeOnDisk)
+		}
+		if movie.Year > 0 {
+			decades.add(fmt.Sprintf("%ds", movie.Year/10*10), movie.SizeOnDisk)
+		} else {
+			decades.add(unknown, movie.SizeOnDisk)
+		}
+
+		if !movie.HasFile {
+			continue
+		}
+		folders.add(rootFolderOf(movie, rootFolders), movie.SizeOnDisk)
+
+		file := movie.MovieFile
+		if file == nil {
+			continue
+		}
+		if file.Quality != nil && file.Quality.Quality != nil {
+			qualities.add(file.Quality.Quality.Name, movie.SizeOnDisk)
+			if file.Quality.Quality.Resolution > 0 {
+				resolutions.add(fmt.Sprintf("%dp", file.Quality.Quality.Resolution), movie.SizeOnDisk)
+			} else {
+				resolutions.add(unknown, movie.SizeOnDisk)
+			}
+		}
+		if file.MediaInfo != nil {
+			videoCodecs.add(file.MediaInfo.VideoCodec, movie.SizeOnDisk)
+			dynamicRange := file.MediaInfo.VideoDynamicRangeType
+			if dynamicRange == "" {
+				dynamicRange = "SDR"
+			}
+			dynamicRanges.add(dynamicRange, movie.SizeOnDisk)
+			audioCodecs.add(file.MediaInfo.AudioCodec, movie.SizeOnDisk)
+		}
+	}
+
+	averageSize := int64(0)
+	if onDisk > 0 {
+		averageSize = totalSize / int64(onDisk)
+	}
+
+	sections := []string{
+		formatStatsTable("Library", [][]string{
+			{"Movies", strconv.Itoa(len(movies))},
+			{"Monitored", strconv.Itoa(monitored)},
+			{"Unmonitored", strconv.Itoa(len(movies) - monitored)},
+			{"On disk", strconv.Itoa(onDisk)},
+			{"Missing", strconv.Itoa(missing)},
+			{"Total size", ByteCountSI(totalSize)},
+			{"Average size", ByteCountSI(averageSize)},
+		}),
+		formatStatsGroups("Size per root folder", folders.sorted(), 0),
+		formatStatsGroups("Quality", qualities.sorted(), 0),
+		formatStatsGroups("Resolution", resolutions.sorted(), 0),
+		formatStatsGroups("Video codec", videoCodecs.sorted(), 0),
+		formatStatsGroups("HDR", dynamicRanges.sorted(), 0),
+		formatStatsGroups("Audio codec", audioCodecs.sorted(), 0),
+		formatStatsGroups("Top genres", genres.sorted(), statsTopItems),
+		formatStatsGroups("Decades", sortedByLabel(decades), 0),
+		formatLargestMovies(movies),
+	}
+
+	var nonEmpty []string
+	for _, section := range sections {
+		if section != "" {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return nonEmpty
+}
+
+// rootFolderOf returns the root folder path the movie is stored in.
+func rootFolderOf(movie *radarr.Movie, rootFolders []*radarr.RootFolder) string {
+	for _, rootFolder := range rootFolders {
+		if strings.HasPrefix(movie.Path, strings.TrimSuffix(rootFolder.Path, "/")+"/") {
+			return rootFolder.Path
+		}
+	}
+	return unknown
+}
+
+func sortedByLabel(groups statsGroups) []*statsGroup {
+	sorted := groups.sorted()
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].label < sorted[j].label
+	})
+	return sorted
+}
+
+func formatStatsGroups(title string, groups []*statsGroup, limit int) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	rows := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		rows = append(rows, []string{group.label, strconv.Itoa(group.count), ByteCountSI(group.size)})
+	}
+	return formatStatsTable(title, rows)
+}
+
+func formatLargestMovies(movies []*radarr.Movie) string {
+	var onDisk []*radarr.Movie
+	for _, movie := range movies {
+		if movie.SizeOnDisk > 0 {
+			onDisk = append(onDisk, movie)
+		}
+	}
+	if len(onDisk) == 0 {
+		return ""
+	}
+	sort.Slice(onDisk, func(i, j int) bool {
+		return onDisk[i].SizeOnDisk > onDisk[j].SizeOnDisk
+	})
+	if len(onDisk) > statsTopItems {
+		onDisk = onDisk[:statsTopItems]
+	}
+	rows := make([][]string, 0, len(onDisk))
+	for _, movie := range onDisk {
+		rows = append(rows, []string{fmt.Sprintf("%s (%d)", movie.Title, movie.Year), ByteCountSI(movie.SizeOnDisk)})
+	}
+	return formatStatsTable("Largest movies", rows)
+}
+
+// formatStatsTable aligns the rows in a monospace block, the first column is
+// left aligned and truncated, all other columns are right aligned.
+func formatStatsTable(title string, rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		row[0] = truncate(row[0], statsMaxLabelLength)
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if length := utf8.RuneCountInString(cell); length > widths[i] {
+				widths[i] = length
+			}
+		}
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*\n```\n", Escape(title))
+	for _, row := range rows {
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == 0 {
+				text.WriteString(escapeCode(cell) + padding)
+			} else {
+				text.WriteString("  " + padding + escapeCode(cell))
+			}
+		}
+		text.WriteString("\n")
+	}
+	text.WriteString("```")
+	return text.String()
+}
+
+// escapeCode escapes text for MarkdownV2 code blocks, only ` and \ need escaping there.
+func escapeCode(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
@@ -2,9 +2,12 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"golift.io/starr/radarr"
+	"golift.io/starr/sonarr"
 )
 
 func Escape(text string) string {
@@ -32,14 +35,59 @@ func ByteCountSI(b int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "kMGTPE"[exp])
 }
 
+const gigabyte = 1024 * 1024 * 1024
+
+var sizeUnits = map[string]int64{
+	"":   gigabyte,
+	"b":  1,
+	"kb": 1024,
+	"mb": 1024 * 1024,
+	"gb": gigabyte,
+	"tb": 1024 * gigabyte,
+}
+
+// ParseSize parses sizes like "20GB" or "700mb" into bytes, without a unit
+// the size is in GB.
+func ParseSize(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	index := strings.IndexFunc(lower, unicode.IsLetter)
+	if index == -1 {
+		index = len(lower)
+	}
+	unit, exists := sizeUnits[lower[index:]]
+	if !exists {
+		return 0, fmt.Errorf("unknown size unit in %q, use B, KB, MB, GB or TB", value)
+	}
+	number, err := strconv.ParseFloat(lower[:index], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a size", value)
+	}
+	return int64(number * float64(unit)), nil
+}
+
 func PrepareRootFolders(rootFolders []*radarr.RootFolder) (msgtext string) {
+	freeSpace := make(map[string]int64, len(rootFolders))
+	for _, disk := range rootFolders {
+		freeSpace[disk.Path] = disk.FreeSpace
+	}
+	return prepareFreeSpace(freeSpace)
+}
+
+func PrepareSonarrRootFolders(rootFolders []*sonarr.RootFolder) (msgtext string) {
+	freeSpace := make(map[string]int64, len(rootFolders))
+	for _, disk := range rootFolders {
+		freeSpace[disk.Path] = disk.FreeSpace
+	}
+	return prepareFreeSpace(freeSpace)
+}
+
+// prepareFreeSpace lists the free space per path as monospace table.
+func prepareFreeSpace(freeSpace map[string]int64) string {
 	maxLength := 0
 	var text strings.Builder
-	disks := make(map[string]string, len(rootFolders))
-	for _, disk := range rootFolders {
-		path := disk.Path
-		freeSpace := disk.FreeSpace
-		disks[fmt.Sprintf("%v:", path)] = Escape(ByteCountSI(freeSpace))
+	disks := make(map[string]string, len(freeSpace))
+	for path, free := range freeSpace {
+		disks[fmt.Sprintf("%v:", path)] = Escape(ByteCountSI(free))
 
 		length := len(path)
 		if maxLength < length {
@@ -54,8 +102,9 @@ func PrepareRootFolders(rootFolders []*radarr.RootFolder) (msgtext string) {
 	return text.String()
 }
 
+var articles = []string{"a", "an", "the", "and", "or", "of"}
+
 func IgnoreArticles(s string) string {
-	articles := []string{"a", "an", "the", "and", "or", "of"}
 
 	for _, article := range articles {
 		if strings.HasPrefix(strings.ToLower(s), article+" ") {